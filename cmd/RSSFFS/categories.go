@@ -0,0 +1,229 @@
+// Package cmd provides the categories command for managing RSS reader categories.
+//
+// This file implements the "categories" subcommand and its list, create,
+// rename, delete and show subcommands, which manage the RSS reader's
+// categories through its API and print them as a table or as JSON.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// CategoriesCommand holds configuration options for the categories command
+type CategoriesCommand struct {
+	JSON bool
+	Yes  bool
+}
+
+// categoryFeeds is a category along with its feeds, as printed by categories show
+type categoryFeeds struct {
+	RSSFFS.Category
+	Feeds []RSSFFS.Feed `json:"feeds"`
+}
+
+// NewCategoriesCommand creates and returns the categories command and its subcommands
+func NewCategoriesCommand() *cobra.Command {
+	categoriesCmd := &CategoriesCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "categories",
+		Short: "List, create, rename, delete and show RSS reader categories",
+		Long: `Manage the RSS reader's categories without leaving the command line.
+Categories are given by title, ignoring case, and a title that doesn't match
+any category suggests similar ones.
+
+Examples:
+  # List categories with their feed and unread counts
+  RSSFFS categories list
+
+  # Create a category to subscribe to feeds in
+  RSSFFS categories create "Link Blogs"
+
+  # Show the feeds in a category and whether they are being fetched
+  RSSFFS categories show "Link Blogs" --json`,
+	}
+	cmd.PersistentFlags().BoolVar(&categoriesCmd.JSON, "json", false, "Print JSON instead of a table")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List the RSS reader's categories",
+			Args:  cobra.NoArgs,
+			RunE:  categoriesCmd.runList,
+		},
+		&cobra.Command{
+			Use:   "create <title>",
+			Short: "Create a category",
+			Args:  cobra.ExactArgs(1),
+			RunE:  categoriesCmd.runCreate,
+		},
+		&cobra.Command{
+			Use:   "rename <title> <new-title>",
+			Short: "Rename a category",
+			Args:  cobra.ExactArgs(2),
+			RunE:  categoriesCmd.runRename,
+		},
+		&cobra.Command{
+			Use:   "show <title>",
+			Short: "List the feeds in a category with their status",
+			Args:  cobra.ExactArgs(1),
+			RunE:  categoriesCmd.runShow,
+		},
+	)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <title>",
+		Short: "Delete a category and unsubscribe from its feeds",
+		Long: `Delete a category. The RSS reader unsubscribes from every feed in the
+category along with it, so the feeds are listed first and nothing is deleted
+until you confirm. With --json, the list and the confirmation go to stderr,
+leaving stdout to the JSON.`,
+		Args: cobra.ExactArgs(1),
+		RunE: categoriesCmd.runDelete,
+	}
+	deleteCmd.Flags().BoolVarP(&categoriesCmd.Yes, "yes", "y", false, "Delete without asking for confirmation")
+	cmd.AddCommand(deleteCmd)
+
+	return cmd
+}
+
+// readerClient returns a client for the configured RSS reader
+func readerClient() *RSSFFS.ReaderClient {
+	conf := config.GetEnvVars()
+	return RSSFFS.NewReaderClient(conf.RSSReaderEndpoint, conf.RSSReaderAPIKey)
+}
+
+// runList executes the categories list command
+func (c *CategoriesCommand) runList(cmd *cobra.Command, args []string) error {
+	categories, err := readerClient().Categories()
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		return writeJSON(os.Stdout, categories)
+	}
+	printCategories(os.Stdout, categories)
+	return nil
+}
+
+// runCreate executes the categories create command
+func (c *CategoriesCommand) runCreate(cmd *cobra.Command, args []string) error {
+	category, err := readerClient().CreateCategory(args[0])
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		return writeJSON(os.Stdout, category)
+	}
+	printCategories(os.Stdout, []RSSFFS.Category{category})
+	return nil
+}
+
+// runRename executes the categories rename command
+func (c *CategoriesCommand) runRename(cmd *cobra.Command, args []string) error {
+	client := readerClient()
+	category, err := client.Category(args[0])
+	if err != nil {
+		return err
+	}
+	renamed, err := client.RenameCategory(category.ID, args[1])
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		return writeJSON(os.Stdout, renamed)
+	}
+	printCategories(os.Stdout, []RSSFFS.Category{renamed})
+	return nil
+}
+
+// runShow executes the categories show command
+func (c *CategoriesCommand) runShow(cmd *cobra.Command, args []string) error {
+	client := readerClient()
+	category, err := client.Category(args[0])
+	if err != nil {
+		return err
+	}
+	feeds, err := client.CategoryFeeds(category.ID)
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		return writeJSON(os.Stdout, categoryFeeds{Category: category, Feeds: feeds})
+	}
+	printCategoryFeeds(os.Stdout, category, feeds)
+	return nil
+}
+
+// runDelete executes the categories delete command
+func (c *CategoriesCommand) runDelete(cmd *cobra.Command, args []string) error {
+	client := readerClient()
+	category, err := client.Category(args[0])
+	if err != nil {
+		return err
+	}
+	feeds, err := client.CategoryFeeds(category.ID)
+	if err != nil {
+		return err
+	}
+	if !c.Yes {
+		// With --json, stdout only carries the JSON, so that it can be piped
+		var out io.Writer = os.Stdout
+		if c.JSON {
+			out = os.Stderr
+		}
+		if len(feeds) > 0 {
+			printCategoryFeeds(out, category, feeds)
+		}
+		question := fmt.Sprintf("Delete category %s and unsubscribe from its %d feeds?", category.Title, len(feeds))
+		if !confirm(os.Stdin, out, question) {
+			_, _ = fmt.Fprintln(out, "Nothing deleted.")
+			return nil
+		}
+	}
+
+	if err := client.DeleteCategory(category.ID); err != nil {
+		return err
+	}
+	if c.JSON {
+		return writeJSON(os.Stdout, categoryFeeds{Category: category, Feeds: feeds})
+	}
+	log.Infof("Deleted category %s and unsubscribed from its %d feeds.", category.Title, len(feeds))
+	return nil
+}
+
+// writeJSON writes v to w as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printCategories writes a table of categories to w, with their feed and
+// unread counts when the RSS reader gave them
+func printCategories(w io.Writer, categories []RSSFFS.Category) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tTITLE\tFEEDS\tUNREAD")
+	for _, category := range categories {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", category.ID, category.Title, strconv.Itoa(category.FeedCount), strconv.Itoa(category.TotalUnread))
+	}
+	_ = tw.Flush()
+}
+
+// printCategoryFeeds writes a table of the feeds in category to w, with
+// whether the RSS reader is fetching each and when it last did
+func printCategoryFeeds(w io.Writer, category RSSFFS.Category, feeds []RSSFFS.Feed) {
+	_, _ = fmt.Fprintf(w, "Category %s (ID %d) has %d feeds:\n", category.Title, category.ID, len(feeds))
+	printFeeds(w, feeds)
+}
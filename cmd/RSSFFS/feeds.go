@@ -0,0 +1,368 @@
+// Package cmd provides the feeds command for managing subscribed feeds.
+//
+// This file implements the "feeds" subcommand and its list, move, retitle,
+// refresh and remove subcommands, which manage the feeds subscribed to in the
+// RSS reader through its API. Feeds are picked out by ID, URL or glob, given
+// as arguments or, with "-", read a line at a time from stdin.
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// FeedsCommand holds configuration options for the feeds command
+type FeedsCommand struct {
+	JSON     bool
+	Quiet    bool
+	Errors   bool
+	Disabled bool
+	Yes      bool
+}
+
+// NewFeedsCommand creates and returns the feeds command and its subcommands
+func NewFeedsCommand() *cobra.Command {
+	feedsCmd := &FeedsCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "feeds",
+		Short: "List, move, retitle, refresh and remove subscribed feeds",
+		Long: `Manage the feeds subscribed to in the RSS reader without leaving the
+command line. Feeds are given by ID, by feed URL, or by a glob matched against
+feed URLs such as "*.example.com/*". Giving "-" instead reads them from stdin,
+one per line, so the IDs printed by "feeds list -q" can be piped to the other
+subcommands.
+
+Examples:
+  # List the feeds in a category that fail to parse
+  RSSFFS feeds list -c "Link Blogs" --errors
+
+  # Refresh every feed that fails to parse
+  RSSFFS feeds list --errors -q | RSSFFS feeds refresh -
+
+  # Move every feed from a site to another category
+  RSSFFS feeds move "Archive" "https://example.com/*"
+
+  # Retitle feeds in bulk, one "<feed> <title>" per line
+  RSSFFS feeds retitle - < titles.txt
+
+  # Unsubscribe from a feed by URL
+  RSSFFS feeds remove https://example.com/feed.xml`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list [feed...]",
+		Short: "List subscribed feeds, optionally only those in a category, failing or disabled",
+		RunE:  feedsCmd.runList,
+	}
+	listCmd.Flags().BoolVar(&feedsCmd.JSON, "json", false, "Print JSON instead of a table")
+	listCmd.Flags().BoolVarP(&feedsCmd.Quiet, "quiet", "q", false, "Only print feed IDs, one per line")
+	listCmd.Flags().BoolVar(&feedsCmd.Errors, "errors", false, "Only list feeds the RSS reader fails to parse")
+	listCmd.Flags().BoolVar(&feedsCmd.Disabled, "disabled", false, "Only list disabled feeds")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <feed>...",
+		Short: "Unsubscribe from feeds",
+		Long: `Unsubscribe from feeds given by ID, URL or glob. The feeds are listed first,
+and nothing is unsubscribed from until you confirm, unless --yes is given.
+--yes is required when the feeds are read from stdin.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: feedsCmd.runRemove,
+	}
+	removeCmd.Flags().BoolVarP(&feedsCmd.Yes, "yes", "y", false, "Unsubscribe without asking for confirmation")
+
+	cmd.AddCommand(
+		listCmd,
+		&cobra.Command{
+			Use:   "move <category> <feed>...",
+			Short: "Move feeds to a category",
+			Args:  cobra.MinimumNArgs(2),
+			RunE:  feedsCmd.runMove,
+		},
+		&cobra.Command{
+			Use:   "retitle <feed> <title> | retitle -",
+			Short: `Set a feed's title, or many from "<feed> <title>" lines on stdin`,
+			Args:  cobra.RangeArgs(1, 2),
+			RunE:  feedsCmd.runRetitle,
+		},
+		&cobra.Command{
+			Use:   "refresh <feed>...",
+			Short: "Have the RSS reader fetch feeds now",
+			Args:  cobra.MinimumNArgs(1),
+			RunE:  feedsCmd.runRefresh,
+		},
+		removeCmd,
+	)
+
+	return cmd
+}
+
+// runList executes the feeds list command
+func (f *FeedsCommand) runList(cmd *cobra.Command, args []string) error {
+	client := readerClient()
+	feeds, err := client.Feeds()
+	if err != nil {
+		return err
+	}
+
+	query := RSSFFS.FeedQuery{Errors: f.Errors, Disabled: f.Disabled}
+	if category != "" {
+		if query.CategoryID, err = client.CategoryID(category); err != nil {
+			return err
+		}
+	}
+	var selectors []string
+	if len(args) > 0 {
+		if selectors, _, err = expandStdin(args, os.Stdin); err != nil {
+			return err
+		}
+	}
+	if feeds, err = listFeeds(feeds, query, selectors); err != nil {
+		return err
+	}
+
+	switch {
+	case f.JSON:
+		if feeds == nil {
+			feeds = []RSSFFS.Feed{}
+		}
+		return writeJSON(os.Stdout, feeds)
+	case f.Quiet:
+		for _, feed := range feeds {
+			_, _ = fmt.Fprintln(os.Stdout, feed.ID)
+		}
+	case len(feeds) == 0:
+		_, _ = fmt.Fprintln(os.Stdout, "No feeds found.")
+	default:
+		printFeeds(os.Stdout, feeds)
+	}
+	return nil
+}
+
+// runMove executes the feeds move command
+func (f *FeedsCommand) runMove(cmd *cobra.Command, args []string) error {
+	conf := config.GetEnvVars()
+	applyFlags(cmd, &conf)
+	client := RSSFFS.NewReaderClient(conf.RSSReaderEndpoint, conf.RSSReaderAPIKey)
+
+	feeds, _, err := pickFeeds(client, args[1:], os.Stdin)
+	if err != nil {
+		return err
+	}
+	categoryId, err := client.ResolveCategoryID(args[0], conf.CreateCategory)
+	if err != nil {
+		return withCategoryHint(err)
+	}
+	return eachFeed(feeds, "move", func(feed RSSFFS.Feed) error {
+		if err := client.MoveFeed(feed.ID, categoryId); err != nil {
+			return err
+		}
+		log.Infof("Moved RSS feed %s to category %s", feed.FeedURL, args[0])
+		return nil
+	})
+}
+
+// runRetitle executes the feeds retitle command
+func (f *FeedsCommand) runRetitle(cmd *cobra.Command, args []string) error {
+	var retitles [][2]string
+	switch {
+	case len(args) == 2:
+		retitles = append(retitles, [2]string{args[0], args[1]})
+	case args[0] == "-":
+		var err error
+		if retitles, err = readRetitles(os.Stdin); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf(`retitle needs a feed and a title, or "-" to read them from stdin`)
+	}
+
+	client := readerClient()
+	subscribed, err := client.Feeds()
+	if err != nil {
+		return err
+	}
+	var failed []error
+	for _, retitle := range retitles {
+		feeds, err := RSSFFS.SelectFeeds(subscribed, []string{retitle[0]})
+		if err == nil && len(feeds) > 1 {
+			err = fmt.Errorf("%d feeds match %s, but only one can be given a title", len(feeds), retitle[0])
+		}
+		if err == nil {
+			err = client.RenameFeed(feeds[0].ID, retitle[1])
+		}
+		if err != nil {
+			log.Errorf("Error retitling RSS feed %s: %v", retitle[0], err)
+			failed = append(failed, fmt.Errorf("%s: %w", retitle[0], err))
+			continue
+		}
+		log.Infof("Retitled RSS feed %s to %s", feeds[0].FeedURL, retitle[1])
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not retitle %d feeds: %w", len(failed), errors.Join(failed...))
+	}
+	return nil
+}
+
+// runRefresh executes the feeds refresh command
+func (f *FeedsCommand) runRefresh(cmd *cobra.Command, args []string) error {
+	client := readerClient()
+	feeds, _, err := pickFeeds(client, args, os.Stdin)
+	if err != nil {
+		return err
+	}
+	return eachFeed(feeds, "refresh", func(feed RSSFFS.Feed) error {
+		if err := client.RefreshFeed(feed.ID); err != nil {
+			return err
+		}
+		log.Infof("Refreshing RSS feed %s", feed.FeedURL)
+		return nil
+	})
+}
+
+// runRemove executes the feeds remove command
+func (f *FeedsCommand) runRemove(cmd *cobra.Command, args []string) error {
+	client := readerClient()
+	feeds, fromStdin, err := pickFeeds(client, args, os.Stdin)
+	if err != nil {
+		return err
+	}
+	if !f.Yes {
+		if fromStdin {
+			return errors.New("pass --yes to remove feeds read from stdin, since stdin can't also answer the confirmation")
+		}
+		printFeeds(os.Stdout, feeds)
+		if !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Unsubscribe from these %d feeds?", len(feeds))) {
+			_, _ = fmt.Fprintln(os.Stdout, "Nothing unsubscribed from.")
+			return nil
+		}
+	}
+	return eachFeed(feeds, "unsubscribe from", func(feed RSSFFS.Feed) error {
+		if err := client.DeleteFeed(feed.ID); err != nil {
+			return err
+		}
+		log.Infof("Unsubscribed from RSS feed %s", feed.FeedURL)
+		return nil
+	})
+}
+
+// listFeeds returns the feeds picked out by selectors, or all of them
+// without any, that match query. Selectors are matched against every
+// subscribed feed before the query narrows them down, so that a selector
+// matching only feeds the query leaves out lists nothing rather than failing.
+func listFeeds(feeds []RSSFFS.Feed, query RSSFFS.FeedQuery, selectors []string) ([]RSSFFS.Feed, error) {
+	if len(selectors) > 0 {
+		var err error
+		if feeds, err = RSSFFS.SelectFeeds(feeds, selectors); err != nil {
+			return nil, err
+		}
+	}
+	return query.Filter(feeds), nil
+}
+
+// pickFeeds returns the subscribed feeds picked out by selectors, reading
+// them from in in place of "-", and whether any were
+func pickFeeds(client *RSSFFS.ReaderClient, selectors []string, in io.Reader) ([]RSSFFS.Feed, bool, error) {
+	selectors, fromStdin, err := expandStdin(selectors, in)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(selectors) == 0 {
+		return nil, fromStdin, errors.New("no feeds given")
+	}
+	subscribed, err := client.Feeds()
+	if err != nil {
+		return nil, fromStdin, err
+	}
+	feeds, err := RSSFFS.SelectFeeds(subscribed, selectors)
+	return feeds, fromStdin, err
+}
+
+// expandStdin replaces a "-" in args with the lines read from in, skipping
+// blank lines and "#" comments, and reports whether it did
+func expandStdin(args []string, in io.Reader) ([]string, bool, error) {
+	var expanded []string
+	fromStdin := false
+	for _, arg := range args {
+		if arg != "-" {
+			expanded = append(expanded, arg)
+			continue
+		}
+		if fromStdin {
+			continue
+		}
+		fromStdin = true
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				expanded = append(expanded, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fromStdin, fmt.Errorf("error reading feeds from stdin: %w", err)
+		}
+	}
+	return expanded, fromStdin, nil
+}
+
+// readRetitles reads "<feed> <title>" lines from in, where the title is the
+// rest of the line after the feed's ID, URL or glob
+func readRetitles(in io.Reader) ([][2]string, error) {
+	lines, _, err := expandStdin([]string{"-"}, in)
+	if err != nil {
+		return nil, err
+	}
+	retitles := make([][2]string, 0, len(lines))
+	for _, line := range lines {
+		end := strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			return nil, fmt.Errorf("line %q should be a feed followed by its title", line)
+		}
+		retitles = append(retitles, [2]string{line[:end], strings.TrimSpace(line[end:])})
+	}
+	return retitles, nil
+}
+
+// eachFeed applies action, described by verb, to every feed, carrying on past
+// failures and returning them together
+func eachFeed(feeds []RSSFFS.Feed, verb string, action func(RSSFFS.Feed) error) error {
+	var failed []error
+	for _, feed := range feeds {
+		if err := action(feed); err != nil {
+			log.Errorf("Could not %s RSS feed %s: %v", verb, feed.FeedURL, err)
+			failed = append(failed, fmt.Errorf("%s: %w", feed.FeedURL, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not %s %d of %d feeds: %w", verb, len(failed), len(feeds), errors.Join(failed...))
+	}
+	return nil
+}
+
+// printFeeds writes a table of feeds to w, with their category, when the RSS
+// reader last checked each and whether it is fetching them
+func printFeeds(w io.Writer, feeds []RSSFFS.Feed) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tTITLE\tFEED\tCATEGORY\tLAST CHECKED\tSTATUS")
+	for _, feed := range feeds {
+		checked := ""
+		if !feed.CheckedAt.IsZero() {
+			checked = feed.CheckedAt.Local().Format("2006-01-02 15:04")
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", feed.ID, orDash(feed.Title), feed.FeedURL, orDash(feed.Category.Title), orDash(checked), feed.Status())
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,101 @@
+// Package cmd provides the harvest command for finding new sources in the RSS reader.
+//
+// This file implements the "harvest" subcommand, which reads entries already
+// in the RSS reader (starred ones, or those in a category or date range),
+// counts the sites they link to and subscribes to the feeds of the sites
+// linked most often that aren't already subscribed to.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// HarvestCommand holds configuration options for the harvest command
+type HarvestCommand struct {
+	Starred      bool
+	FromCategory string
+	After        string
+	Before       string
+	Limit        int
+}
+
+// NewHarvestCommand creates and returns a new harvest command
+func NewHarvestCommand() *cobra.Command {
+	harvestCmd := &HarvestCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "harvest",
+		Short: "Subscribe to sites often linked from entries in the RSS reader",
+		Long: `Read entries from the RSS reader, count the sites linked from each entry's
+link and content, and subscribe to the feeds of sites linked from at least
+--min-mentions entries (2 by default) that aren't already subscribed to.
+
+Examples:
+  # Follow the authors of things you starred this year
+  RSSFFS harvest --starred --after 2024-01-01 -c "Found"
+
+  # Look through the last 30 days of a link blog category, without subscribing
+  RSSFFS harvest --from-category "Link Blogs" --after 30d --min-mentions 3 -d -c "Found"`,
+		Args: cobra.NoArgs,
+		RunE: harvestCmd.runHarvest,
+	}
+
+	cmd.Flags().BoolVar(&harvestCmd.Starred, "starred", false, "Only read starred entries")
+	cmd.Flags().StringVar(&harvestCmd.FromCategory, "from-category", "", "Only read entries in this RSS reader category")
+	cmd.Flags().StringVar(&harvestCmd.After, "after", "", "Only read entries published after this date (2006-01-02) or this long ago (e.g. 30d)")
+	cmd.Flags().StringVar(&harvestCmd.Before, "before", "", "Only read entries published before this date (2006-01-02) or this long ago (e.g. 7d)")
+	cmd.Flags().IntVar(&harvestCmd.Limit, "limit", 500, "Read at most this many entries, newest first")
+
+	return cmd
+}
+
+// runHarvest executes the harvest command
+func (h *HarvestCommand) runHarvest(cmd *cobra.Command, args []string) error {
+	conf := config.GetEnvVars()
+	applyFlags(cmd, &conf)
+
+	now := time.Now()
+	after, err := parseSince(h.After, now)
+	if err != nil {
+		return fmt.Errorf("invalid --after: %w", err)
+	}
+	before, err := parseSince(h.Before, now)
+	if err != nil {
+		return fmt.Errorf("invalid --before: %w", err)
+	}
+
+	options := RSSFFS.HarvestOptions{Starred: h.Starred, Category: h.FromCategory, After: after, Before: before, Limit: h.Limit}
+	report, err := RSSFFS.Harvest(options, category, debug, conf)
+	if err != nil {
+		return withCategoryHint(err)
+	}
+	printReport(os.Stdout, report)
+	log.Infof("Successfully subscribed to %d new RSS feed(s), %d already subscribed, skipped %d.", report.SubscribedCount(), report.AlreadySubscribedCount(), report.SkippedCount())
+	return nil
+}
+
+// parseSince parses a date such as "2024-01-31", or a duration such as "30d"
+// counted back from now. An empty string gives the zero time.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return date, nil
+	}
+	age, err := RSSFFS.ParseMaxAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2006-01-02) nor a duration (e.g. 30d)", s)
+	}
+	return now.Add(-age), nil
+}
@@ -0,0 +1,110 @@
+// Package cmd provides the recommend command for friends-of-friends discovery.
+//
+// This file implements the "recommend" subcommand, which reads the sites of
+// every feed already subscribed to in the RSS reader and ranks the feeds
+// their blogrolls and home pages recommend by how many subscriptions
+// recommend each, printing them for review or subscribing to the top ones.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// RecommendCommand holds configuration options for the recommend command
+type RecommendCommand struct {
+	Subscribe int
+}
+
+// NewRecommendCommand creates and returns a new recommend command
+func NewRecommendCommand() *cobra.Command {
+	recommendCmd := &RecommendCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "recommend",
+		Short: "Recommend feeds that your subscriptions' sites recommend",
+		Long: `Read the site of every feed subscribed to in the RSS reader, collect the
+feeds listed in their blogrolls and the sites linked from their home pages,
+and rank those recommended by at least --min-mentions subscriptions (2 by
+default) by how many subscriptions recommend them. Feeds and sites already
+subscribed to are left out.
+
+The recommendations are printed for review, or with --subscribe N the top N
+are subscribed to in the --category category.
+
+Examples:
+  # Review what your subscriptions recommend
+  RSSFFS recommend
+
+  # Subscribe to the ten most recommended feeds
+  RSSFFS recommend --subscribe 10 -c "Recommended"`,
+		Args: cobra.NoArgs,
+		RunE: recommendCmd.runRecommend,
+	}
+
+	cmd.Flags().IntVar(&recommendCmd.Subscribe, "subscribe", 0, "Subscribe to this many of the top recommendations instead of only listing them")
+
+	return cmd
+}
+
+// runRecommend executes the recommend command
+func (r *RecommendCommand) runRecommend(cmd *cobra.Command, args []string) error {
+	conf := config.GetEnvVars()
+	applyFlags(cmd, &conf)
+
+	report, err := RSSFFS.Recommend(r.Subscribe, category, debug, conf)
+	if err != nil {
+		return withCategoryHint(err)
+	}
+	if r.Subscribe > 0 {
+		printReport(os.Stdout, report)
+		log.Infof("Successfully subscribed to %d new RSS feed(s), %d already subscribed, skipped %d.", report.SubscribedCount(), report.AlreadySubscribedCount(), report.SkippedCount())
+		return nil
+	}
+	if len(report.Results) == 0 {
+		_, _ = fmt.Fprintln(os.Stdout, "No recommendations found.")
+		return nil
+	}
+	printRecommendations(os.Stdout, report)
+	return nil
+}
+
+// printRecommendations writes a ranked table of recommended feeds to w.
+//
+// Each row shows how many subscriptions recommend the feed, its URL and
+// title, how it was recommended (for example "in 3 blogrolls"), when it last
+// posted, and whether the rules and feed filter would skip it.
+//
+// Parameters:
+//   - w: Destination for the table, usually os.Stdout
+//   - report: The report returned by RSSFFS.Recommend
+func printRecommendations(w io.Writer, report *RSSFFS.Report) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "RECOMMENDED BY\tFEED\tTITLE\tSOURCE\tLAST POST\tSTATUS")
+	for _, result := range report.Results {
+		title, lastPost := result.Title, ""
+		if info := result.Info; info != nil {
+			if title == "" {
+				title = info.Title
+			}
+			if !info.Newest.IsZero() {
+				lastPost = info.Newest.Format("2006-01-02")
+			}
+		}
+		status := "recommended"
+		if result.Skipped != "" {
+			status = "skipped: " + result.Skipped
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", strconv.Itoa(result.Mentions), result.URL, orDash(title), orDash(result.Source), orDash(lastPost), status)
+	}
+	_ = tw.Flush()
+}
@@ -21,9 +21,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -57,6 +62,64 @@ var (
 	// instead of traversing all domains found on the page.
 	// Set via the --single-url/-s flag.
 	singleURLMode bool
+
+	// forgeFeed selects which feed to subscribe to for code forge repository
+	// URLs: "releases", "tags" or "commits". Overrides RSSFFS_FORGE_FEED.
+	// Set via the --forge-feed flag.
+	forgeFeed string
+
+	// selectFeeds prompts for which feeds to subscribe to when single URL
+	// mode finds several, such as a blog's category and author feeds.
+	// Set via the --select flag.
+	selectFeeds bool
+
+	// podcastCategory is the RSS reader category podcast feeds are subscribed
+	// to instead of the --category one. Overrides RSSFFS_PODCAST_CATEGORY.
+	// Set via the --podcast-category flag.
+	podcastCategory string
+
+	// maxAge skips feeds whose newest item is older than this duration, e.g.
+	// "365d". Overrides RSSFFS_MAX_AGE.
+	// Set via the --max-age flag.
+	maxAge string
+
+	// minItems skips feeds with fewer items than this. Overrides RSSFFS_MIN_ITEMS.
+	// Set via the --min-items flag.
+	minItems int
+
+	// languages only subscribes to feeds in these languages, preferring a
+	// site's matching hreflang alternate. Overrides RSSFFS_LANGUAGES.
+	// Set via the --language flag, which may be repeated or comma-separated.
+	languages []string
+
+	// minMentions only checks the sites linked from at least this many items
+	// when the input URL is a feed, or this many entries when harvesting.
+	// Overrides RSSFFS_MIN_MENTIONS.
+	// Set via the --min-mentions flag.
+	minMentions int
+
+	// rulesFile is a YAML file of rules that accept, reject, categorise and
+	// rename discovered feeds. Overrides RSSFFS_RULES_FILE.
+	// Set via the --rules flag.
+	rulesFile string
+
+	// createCategory creates the categories feeds are subscribed to when they
+	// don't exist yet. Overrides RSSFFS_CREATE_CATEGORY.
+	// Set via the --create-category flag.
+	createCategory bool
+
+	// replaceCategoryFeeds unsubscribes from the feeds in the category that
+	// aren't found again, once subscribing to the feeds found succeeded.
+	// Set via the --replace flag.
+	replaceCategoryFeeds bool
+
+	// assumeYes skips asking for confirmation before clearing a category.
+	// Set via the --yes/-y flag.
+	assumeYes bool
+
+	// dryRun reports what would be subscribed to and unsubscribed from
+	// without changing anything. Set via the --dry-run flag.
+	dryRun bool
 )
 
 // rootCmd defines the base command for the RSSFFS CLI application.
@@ -71,56 +134,208 @@ var (
 //   - Validates URL format before processing
 //   - Integrates with RSS reader API for feed subscription
 var rootCmd = &cobra.Command{
-	Use:   "RSSFFS [pageURL]",
+	Use:   "RSSFFS [pageURL|@user@host]",
 	Short: "RSS Feed Finder [and] Subscriber",
 	Long: `Automatically find and subscribe to RSS feeds found on inputted URL, and on URLs mentioned on the inputted URL.
 
 RSSFFS operates in two modes:
 
-1. Traversal Mode (default): Discovers RSS feeds on the provided URL and follows links to find feeds on other domains mentioned on the page.
+1. Traversal Mode (default): Discovers RSS feeds on the provided URL and follows links to find feeds on other domains mentioned on the page. If the site publishes a blogroll as OPML (via <link rel="blogroll"> or /.well-known/recommendations.opml), or the URL is itself an OPML file, the feeds listed in it are used directly. If the URL is a feed, such as a link blog's or newsletter's, the sites its items link to are checked instead.
 
 2. Single URL Mode: Only searches for RSS feeds on the specific domain of the provided URL, without following links to other domains.
 
+Fediverse handles such as @user@host are also accepted; they are resolved to a profile page via WebFinger and checked in single URL mode.
+
 Examples:
   # Basic usage (traversal mode)
   RSSFFS https://example.com
 
+  # Subscribe to every feed in a remote OPML file
+  RSSFFS -c "Blogroll" https://example.com/blogroll.opml
+
+  # Follow a fediverse account, resolved via WebFinger
+  RSSFFS -c "People" @alice@mastodon.example
+
   # Single URL mode - only check example.com domain
   RSSFFS --single-url https://example.com/blog/post
 
   # Single URL mode with category
   RSSFFS -s -c "Tech Blogs" https://blog.example.com
 
+  # Choose between a blog's site-wide, category and author feeds
+  RSSFFS -s --select https://blog.example.com/category/go/
+
+  # Track a dependency's tags rather than its releases
+  RSSFFS -s -c "Releases" --forge-feed tags https://github.com/spf13/cobra
+
+  # Subscribe to a podcast shared as an Apple Podcasts link
+  RSSFFS -s --podcast-category "Podcasts" https://podcasts.apple.com/us/podcast/some-show/id123456789
+
+  # Skip blogroll feeds that haven't posted in a year or are in other languages
+  RSSFFS --max-age 365d --min-items 3 --language en,de https://example.com/blogroll
+
+  # Follow the sites a link blog links to at least three times
+  RSSFFS -c "Found" --min-mentions 3 https://linkblog.example.com/feed.xml
+
+  # Sort feeds into categories with a rules file, then check which rule matches
+  RSSFFS --rules rules.yaml https://example.com/blogroll
+  RSSFFS rules test --rules rules.yaml https://example.com/blogroll
+
   # Clear existing feeds and use single URL mode
-  RSSFFS -r -s -c "News" https://news.example.com`,
+  RSSFFS -r -s -c "News" https://news.example.com
+
+  # Preview replacing a category's feeds with those of a blogroll, then do it
+  RSSFFS --replace --dry-run -c "Blogroll" https://example.com/blogroll
+  RSSFFS --replace -c "Blogroll" https://example.com/blogroll`,
 	Args:             cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	PersistentPreRun: rootCmdPreRun,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Load configuration
 		conf := config.GetEnvVars()
 
-		inputURL := args[0]
-		pageURL, err := url.ParseRequestURI(inputURL)
-		if err != nil {
-			fmt.Println("Invalid URL input:", err)
-			os.Exit(1)
+		// Fediverse handles (@user@host) are resolved via WebFinger by RSSFFS.RunReport
+		input := args[0]
+		if !RSSFFS.IsHandle(input) {
+			pageURL, err := url.ParseRequestURI(input)
+			if err != nil {
+				fmt.Println("Invalid URL input:", err)
+				os.Exit(1)
+			}
+			input = pageURL.String()
+		}
+
+		effectiveSingleURLMode := applyFlags(cmd, &conf)
+
+		if selectFeeds {
+			RSSFFS.SelectCandidates = promptForCandidates(os.Stdin, os.Stdout)
 		}
 
-		// Determine single URL mode with CLI flag precedence over environment variable
-		effectiveSingleURLMode := singleURLMode || conf.SingleURLMode
-		// If CLI flag was explicitly set, it takes precedence
-		if cmd.Flags().Changed("single-url") {
-			effectiveSingleURLMode = singleURLMode
+		mode := clearMode()
+		if mode != RSSFFS.ClearNone && !dryRun && !assumeYes && category != "" {
+			client := RSSFFS.NewReaderClient(conf.RSSReaderEndpoint, conf.RSSReaderAPIKey)
+			existing, err := client.Category(category)
+			if err != nil && !conf.CreateCategory {
+				log.Fatalf("An error occurred during execution: %v", withCategoryHint(err))
+			}
+			if err == nil {
+				feeds, err := client.CategoryFeeds(existing.ID)
+				if err != nil {
+					log.Fatalf("An error occurred during execution: %v", err)
+				}
+				if !confirmClear(os.Stdin, os.Stdout, mode, existing, feeds) {
+					_, _ = fmt.Fprintln(os.Stdout, "Nothing changed.")
+					return
+				}
+			}
 		}
 
-		count, err := RSSFFS.Run(pageURL.String(), category, debug, clearCategoryFeeds, effectiveSingleURLMode, conf)
+		report, err := RSSFFS.RunReport(input, category, debug || dryRun, mode, effectiveSingleURLMode, conf)
 		if err != nil {
-			log.Fatalf("An error occurred during execution: %v", err)
+			log.Fatalf("An error occurred during execution: %v", withCategoryHint(err))
 		}
-		log.Infof("Successfully subscribed to %d new RSS feed(s).", count)
+		printReport(os.Stdout, report)
+		printCleared(os.Stdout, report, dryRun)
+		log.Infof("Successfully subscribed to %d new RSS feed(s), %d already subscribed, skipped %d.", report.SubscribedCount(), report.AlreadySubscribedCount(), report.SkippedCount())
 	},
 }
 
+// applyFlags overrides configuration loaded from the environment with the
+// flags explicitly set on the command line.
+//
+// It returns whether single URL mode is in effect, with an explicitly set
+// --single-url flag taking precedence over RSSFFS_SINGLE_URL_MODE.
+//
+// Parameters:
+//   - cmd: The cobra command being executed
+//   - conf: The configuration to override
+func applyFlags(cmd *cobra.Command, conf *config.Config) bool {
+	if cmd.Flags().Changed("forge-feed") {
+		conf.ForgeFeed = forgeFeed
+	}
+	if cmd.Flags().Changed("podcast-category") {
+		conf.PodcastCategory = podcastCategory
+	}
+	if cmd.Flags().Changed("max-age") {
+		conf.MaxAge = maxAge
+	}
+	if cmd.Flags().Changed("min-items") {
+		conf.MinItems = minItems
+	}
+	if cmd.Flags().Changed("language") {
+		conf.Languages = languages
+	}
+	if cmd.Flags().Changed("min-mentions") {
+		conf.MinMentions = minMentions
+	}
+	if cmd.Flags().Changed("rules") {
+		conf.RulesFile = rulesFile
+	}
+	if cmd.Flags().Changed("create-category") {
+		conf.CreateCategory = createCategory
+	}
+
+	// Determine single URL mode with CLI flag precedence over environment variable
+	effectiveSingleURLMode := singleURLMode || conf.SingleURLMode
+	// If CLI flag was explicitly set, it takes precedence
+	if cmd.Flags().Changed("single-url") {
+		effectiveSingleURLMode = singleURLMode
+	}
+	return effectiveSingleURLMode
+}
+
+// clearMode returns what the run does with the feeds already in its
+// category, --replace taking precedence over -r
+func clearMode() RSSFFS.ClearMode {
+	switch {
+	case replaceCategoryFeeds:
+		return RSSFFS.ClearReplace
+	case clearCategoryFeeds:
+		return RSSFFS.ClearBefore
+	}
+	return RSSFFS.ClearNone
+}
+
+// confirmClear lists the feeds in category and asks on out whether clearing
+// them as mode does should go ahead, reading the answer from in. A category
+// without feeds needs no confirmation.
+func confirmClear(in io.Reader, out io.Writer, mode RSSFFS.ClearMode, category RSSFFS.Category, feeds []RSSFFS.Feed) bool {
+	if len(feeds) == 0 {
+		return true
+	}
+	printCategoryFeeds(out, category, feeds)
+	question := fmt.Sprintf("Unsubscribe from all %d feeds in category %s before looking for new ones? They are backed up to an OPML file first.", len(feeds), category.Title)
+	if mode == RSSFFS.ClearReplace {
+		question = fmt.Sprintf("Unsubscribe from the feeds of these %d that aren't found again, once subscribing succeeds? They are backed up to an OPML file first.", len(feeds))
+	}
+	return confirm(in, out, question)
+}
+
+// printCleared writes the feeds a run unsubscribed from in its category to
+// w, or would have with dryRun, along with where they were backed up
+func printCleared(w io.Writer, report *RSSFFS.Report, dryRun bool) {
+	if report == nil || len(report.Removed) == 0 {
+		return
+	}
+	if dryRun {
+		_, _ = fmt.Fprintf(w, "Would unsubscribe from %d feeds:\n", len(report.Removed))
+		printFeeds(w, report.Removed)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Unsubscribed from %d feeds:\n", len(report.Removed))
+	printFeeds(w, report.Removed)
+	_, _ = fmt.Fprintf(w, "The category's feeds were backed up to %s; import it into the RSS reader to restore them.\n", report.Backup)
+}
+
+// withCategoryHint adds how to create a missing category to err, when err
+// is because the category doesn't exist
+func withCategoryHint(err error) error {
+	var notFound *RSSFFS.CategoryNotFoundError
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("%w; pass --create-category to create it", err)
+	}
+	return err
+}
+
 // rootCmdPreRun performs setup operations before executing the root command.
 //
 // This function is called before both the root command and any subcommands
@@ -139,6 +354,112 @@ func rootCmdPreRun(cmd *cobra.Command, args []string) {
 	}
 }
 
+// printReport writes a table of every feed handled during a run to w.
+//
+// Each row shows the feed URL and title, how it was found (for example
+// "recommended by example.com" for feeds read from a blogroll), the publishing
+// platform detected behind it, whether the feed is native to the site or
+// generated by a bridge such as RSSHub (and whether it is a podcast or carries
+// full articles), its language, when it last posted and how often it posts,
+// and whether subscribing succeeded or why the feed was skipped, along with
+// the rule that decided so and the category it chose. The ID to undo the run
+// with follows the table when it subscribed to any feeds.
+// Nothing is printed when the run found no feeds.
+//
+// Parameters:
+//   - w: Destination for the table, usually os.Stdout
+//   - report: The report returned by RSSFFS.RunReport
+func printReport(w io.Writer, report *RSSFFS.Report) {
+	if report == nil || len(report.Results) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "FEED\tTITLE\tSOURCE\tPLATFORM\tTYPE\tLANG\tLAST POST\tEVERY\tSTATUS")
+	for _, result := range report.Results {
+		status := "subscribed"
+		if result.Category != "" {
+			status += " to " + result.Category
+		}
+		switch result.Subscription {
+		case RSSFFS.SubscriptionExisting:
+			status = "already subscribed"
+		case RSSFFS.SubscriptionOtherURL:
+			status += ", site already subscribed as " + result.SubscribedAs
+		}
+		if result.AlreadySubscribed() && result.Category != "" {
+			status += " in " + result.Category
+		}
+		if result.Skipped != "" {
+			status = "skipped: " + result.Skipped
+		}
+		if result.Error != "" {
+			status = "error: " + result.Error
+		}
+		if result.Rule != "" {
+			status += " (rule " + result.Rule + ")"
+		}
+		feedType := []string{"native"}
+		if result.Bridge != "" {
+			feedType = []string{"bridged (" + result.Bridge + ")"}
+		}
+
+		title, language, lastPost, every := "", "", "", ""
+		if info := result.Info; info != nil {
+			title = info.Title
+			language = info.Language
+			if !info.Newest.IsZero() {
+				lastPost = info.Newest.Format("2006-01-02")
+			}
+			every = formatInterval(info.AverageInterval)
+			if info.Podcast {
+				feedType = append(feedType, "podcast")
+			}
+			if info.FullText {
+				feedType = append(feedType, "full text")
+			}
+		}
+		if result.Title != "" {
+			title = result.Title
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", result.URL, orDash(title), orDash(result.Source), orDash(result.Platform),
+			strings.Join(feedType, ", "), orDash(language), orDash(lastPost), orDash(every), status)
+	}
+	_ = tw.Flush()
+	if report.RunID != "" {
+		_, _ = fmt.Fprintf(w, "\nRecorded as run %s; undo it with: RSSFFS undo %s\n", report.RunID, report.RunID)
+	}
+}
+
+// orDash returns s, or "-" for an empty table cell
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatInterval describes a posting interval in the largest sensible unit,
+// e.g. "~3d" or "~2w", or returns an empty string when it is unknown
+func formatInterval(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d <= 0:
+		return ""
+	case d < time.Hour:
+		return "<1h"
+	case d < day:
+		return fmt.Sprintf("~%dh", int((d+time.Hour/2)/time.Hour))
+	case d < 14*day:
+		return fmt.Sprintf("~%dd", int((d+day/2)/day))
+	case d < 60*day:
+		return fmt.Sprintf("~%dw", int((d+7*day/2)/(7*day)))
+	default:
+		return fmt.Sprintf("~%dmo", int((d+15*day)/(30*day)))
+	}
+}
+
 // Execute starts the command-line interface execution.
 //
 // This is the main entry point called from main.go to begin command processing.
@@ -172,19 +493,51 @@ func Execute() {
 //   - clearCategoryFeeds (-r, --clearCategoryFeeds): Clears existing feeds before adding new ones
 //   - category (-c, --category): Specifies RSS reader category for new feeds
 //   - singleURLMode (-s, --single-url): Only check the provided URL for RSS feeds
+//   - forgeFeed (--forge-feed): Which code forge repository feed to subscribe to
+//   - selectFeeds (--select): Choose between the feeds found in single URL mode
+//   - podcastCategory (--podcast-category): Category to subscribe podcast feeds to
+//   - maxAge (--max-age): Skip feeds that have not posted within this duration
+//   - minItems (--min-items): Skip feeds with fewer items than this
+//   - languages (--language): Only subscribe to feeds in these languages
+//   - minMentions (--min-mentions): Sites a feed's items (or harvested entries) must link to this often to be checked
+//   - rulesFile (--rules): Rules file to accept, reject and categorise feeds with
 //
 // The flags are persistent, meaning they're inherited by all subcommands.
+//
+// Root command flags defined:
+//   - replaceCategoryFeeds (--replace): Replace the category's feeds with those found, once subscribing succeeded
+//   - assumeYes (-y, --yes): Clear the category without asking for confirmation
+//   - dryRun (--dry-run): Only report what would be subscribed to and unsubscribed from
 func init() {
 	// create rootCmd-level flags
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable debug-level logging")
-	rootCmd.PersistentFlags().BoolVarP(&clearCategoryFeeds, "clearCategoryFeeds", "r", false, "Delete all feeds within category before subscribing to new feeds")
+	rootCmd.PersistentFlags().BoolVarP(&clearCategoryFeeds, "clearCategoryFeeds", "r", false, "Delete all feeds within category before subscribing to new feeds, after backing them up and confirming")
 	rootCmd.PersistentFlags().StringVarP(&category, "category", "c", "", "RSS reader category name to assign new feeds to")
 	rootCmd.PersistentFlags().BoolVarP(&singleURLMode, "single-url", "s", false, "Enable single URL mode: only check the provided URL's domain for RSS feeds, without traversing to other domains found on the page")
+	rootCmd.PersistentFlags().StringVar(&forgeFeed, "forge-feed", RSSFFS.ForgeFeedReleases, "Feed to subscribe to for code forge repositories: releases, tags or commits")
+	rootCmd.PersistentFlags().StringVar(&podcastCategory, "podcast-category", "", "RSS reader category name to assign podcast feeds to, instead of --category")
+	rootCmd.PersistentFlags().StringVar(&maxAge, "max-age", "", "Skip feeds whose newest item is older than this, e.g. 720h, 90d or 2y")
+	rootCmd.PersistentFlags().IntVar(&minItems, "min-items", 0, "Skip feeds with fewer items than this")
+	rootCmd.PersistentFlags().StringSliceVar(&languages, "language", nil, "Only subscribe to feeds in these languages (e.g. en,de), preferring a site's matching hreflang alternate")
+	rootCmd.PersistentFlags().IntVar(&minMentions, "min-mentions", 0, "When the URL is a feed, only check sites linked from at least this many of its items")
+	rootCmd.PersistentFlags().BoolVar(&createCategory, "create-category", false, "Create the category (and rule and podcast categories) when it doesn't exist yet, instead of failing")
+	rootCmd.PersistentFlags().StringVar(&rulesFile, "rules", "", "YAML rules file to accept, reject, categorise and rename discovered feeds with")
+	rootCmd.PersistentFlags().BoolVar(&selectFeeds, "select", false, "In single URL mode, list every feed found (e.g. category and author feeds) and prompt for which to subscribe to")
+	rootCmd.Flags().BoolVar(&replaceCategoryFeeds, "replace", false, "Delete the feeds within category that aren't found again (feeds found again are kept even if filters or rules skip them), only once subscribing to the feeds found succeeded")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Clear the category without asking for confirmation")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show which feeds would be subscribed to and unsubscribed from")
 
 	// add sub-commands
 	rootCmd.AddCommand(
 		man.NewManCmd(),
 		version.Command(),
 		NewServeCommand(),
+		NewRulesCommand(),
+		NewHarvestCommand(),
+		NewRecommendCommand(),
+		NewUndoCommand(),
+		NewCategoriesCommand(),
+		NewFeedsCommand(),
+		NewSyncCommand(),
 	)
 }
@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
 )
 
 func TestRootCommandFlags(t *testing.T) {
@@ -512,3 +520,490 @@ func TestEnvironmentVariablePrecedence(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintReport(t *testing.T) {
+	var buf bytes.Buffer
+	report := &RSSFFS.Report{Results: []RSSFFS.Result{
+		{Candidate: RSSFFS.Candidate{URL: "https://alice.example.com/feed.xml", Source: "recommended by example.com", Platform: "wordpress", Info: &RSSFFS.FeedInfo{
+			Title:           "Alice's Notes",
+			Language:        "en",
+			Newest:          time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC),
+			AverageInterval: 7 * 24 * time.Hour,
+			FullText:        true,
+		}}, Subscribed: true},
+		{Candidate: RSSFFS.Candidate{URL: "https://bob.example.org/rss"}, Error: "failed to subscribe, status code: 400"},
+		{Candidate: RSSFFS.Candidate{URL: "https://carol.example.org/atom.xml"}, Skipped: "newest item from 2016-03-04 is older than 365d"},
+		{Candidate: RSSFFS.Candidate{URL: "https://rsshub.example.net/github/issue/alice/widget", Bridge: "rsshub"}, Subscribed: true},
+		{Candidate: RSSFFS.Candidate{URL: "https://github.com/spf13/cobra/releases.atom"}, Subscribed: true, Rule: "releases", Category: "Releases", Title: "Releases: cobra"},
+		{Candidate: RSSFFS.Candidate{URL: "https://dave.example.org/feed"}, Subscription: RSSFFS.SubscriptionExisting, SubscribedAs: "https://dave.example.org/feed", Category: "Blogs"},
+		{Candidate: RSSFFS.Candidate{URL: "https://erin.example.org/comments.xml"}, Subscription: RSSFFS.SubscriptionOtherURL, SubscribedAs: "https://erin.example.org/feed.xml", Subscribed: true},
+	}}
+
+	printReport(&buf, report)
+	output := buf.String()
+
+	for _, expected := range []string{"FEED", "TITLE", "SOURCE", "PLATFORM", "TYPE", "LANG", "LAST POST", "EVERY", "STATUS", "Alice's Notes", "recommended by example.com", "wordpress", "native, full text", "2024-05-15", "~7d", "bridged (rsshub)", "subscribed", "error: failed to subscribe", "skipped: newest item from 2016-03-04", "Releases: cobra", "subscribed to Releases (rule releases)", "already subscribed in Blogs", "subscribed, site already subscribed as https://erin.example.org/feed.xml"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected report output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	buf.Reset()
+	printReport(&buf, &RSSFFS.Report{})
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for empty report, got %q", buf.String())
+	}
+}
+
+func TestPrintRuleReport(t *testing.T) {
+	var buf bytes.Buffer
+	report := &RSSFFS.Report{Results: []RSSFFS.Result{
+		{Candidate: RSSFFS.Candidate{URL: "https://alice.substack.com/feed", Info: &RSSFFS.FeedInfo{Title: "Alice"}}, Rule: "newsletters", Category: "Newsletters"},
+		{Candidate: RSSFFS.Candidate{URL: "https://example.com/jobs.xml"}, Rule: "no-jobs", Skipped: "rejected by rule no-jobs"},
+		{Candidate: RSSFFS.Candidate{URL: "https://blog.example.com/feed"}, Category: "Blogs"},
+	}}
+
+	printRuleReport(&buf, report)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header and 3 rows, got:\n%s", buf.String())
+	}
+	for i, expected := range [][]string{
+		{"FEED", "TITLE", "RULE", "DECISION"},
+		{"https://alice.substack.com/feed", "Alice", "newsletters", "subscribe to Newsletters"},
+		{"https://example.com/jobs.xml", "no-jobs", "skip: rejected by rule no-jobs"},
+		{"https://blog.example.com/feed", "-", "subscribe to Blogs"},
+	} {
+		for _, field := range expected {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("Expected line %d to contain %q, got %q", i, field, lines[i])
+			}
+		}
+	}
+}
+
+func TestPrintRecommendations(t *testing.T) {
+	var buf bytes.Buffer
+	report := &RSSFFS.Report{Results: []RSSFFS.Result{
+		{Candidate: RSSFFS.Candidate{URL: "https://alice.example.org/feed.xml", Source: "in 4 blogrolls", Mentions: 4, Info: &RSSFFS.FeedInfo{Title: "Alice", Newest: time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)}}},
+		{Candidate: RSSFFS.Candidate{URL: "https://bob.example.net/rss", Source: "linked from 2 subscriptions", Mentions: 2}, Skipped: "language fr is not one of en"},
+	}}
+
+	printRecommendations(&buf, report)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got:\n%s", buf.String())
+	}
+	for i, expected := range [][]string{
+		{"RECOMMENDED BY", "FEED", "TITLE", "SOURCE", "LAST POST", "STATUS"},
+		{"4", "https://alice.example.org/feed.xml", "Alice", "in 4 blogrolls", "2024-05-15", "recommended"},
+		{"2", "https://bob.example.net/rss", "linked from 2 subscriptions", "skipped: language fr is not one of en"},
+	} {
+		for _, field := range expected {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("Expected line %d to contain %q, got %q", i, field, lines[i])
+			}
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input     string
+		expected  time.Time
+		expectErr bool
+	}{
+		{input: "", expected: time.Time{}},
+		{input: "2024-01-31", expected: time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)},
+		{input: "30d", expected: now.Add(-30 * 24 * time.Hour)},
+		{input: "12h", expected: now.Add(-12 * time.Hour)},
+		{input: "last week", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSince(tt.input, now)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		expected string
+	}{
+		{0, ""},
+		{20 * time.Minute, "<1h"},
+		{5*time.Hour + 40*time.Minute, "~6h"},
+		{36 * time.Hour, "~2d"},
+		{21 * 24 * time.Hour, "~3w"},
+		{95 * 24 * time.Hour, "~3mo"},
+	}
+
+	for _, tt := range tests {
+		if got := formatInterval(tt.interval); got != tt.expected {
+			t.Errorf("Expected %q for %v, got %q", tt.expected, tt.interval, got)
+		}
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		answer    string
+		expected  []int
+		expectErr bool
+	}{
+		{name: "Empty selects first", answer: "\n", expected: []int{0}},
+		{name: "Single", answer: "2", expected: []int{1}},
+		{name: "List", answer: "3, 1", expected: []int{0, 2}},
+		{name: "Range", answer: "2-4", expected: []int{1, 2, 3}},
+		{name: "All", answer: "all", expected: []int{0, 1, 2, 3}},
+		{name: "None", answer: "none", expected: nil},
+		{name: "Out of range", answer: "5", expectErr: true},
+		{name: "Reversed range", answer: "3-2", expectErr: true},
+		{name: "Not a number", answer: "first", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSelection(tt.answer, 4)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.answer, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPromptForCandidates(t *testing.T) {
+	candidates := []RSSFFS.Candidate{
+		{URL: "https://blog.example.com/category/go/feed/", Platform: "wordpress"},
+		{URL: "https://blog.example.com/feed/", Platform: "wordpress", Info: &RSSFFS.FeedInfo{Title: "Example Blog"}},
+		{URL: "https://blog.example.com/author/alice/feed/", Platform: "wordpress"},
+	}
+
+	var out bytes.Buffer
+	selected, err := promptForCandidates(strings.NewReader("2,3\n"), &out)(candidates)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(selected, candidates[1:]) {
+		t.Errorf("Expected %v, got %v", candidates[1:], selected)
+	}
+	if !strings.Contains(out.String(), "1) https://blog.example.com/category/go/feed/ (wordpress)") {
+		t.Errorf("Expected numbered candidate list, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "2) Example Blog - https://blog.example.com/feed/ (wordpress)") {
+		t.Errorf("Expected feed title in candidate list, got:\n%s", out.String())
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for answer, expected := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		if got := confirm(strings.NewReader(answer), &out, "Unsubscribe?"); got != expected {
+			t.Errorf("Expected %t for answer %q, got %t", expected, answer, got)
+		}
+		if out.String() != "Unsubscribe? [y/N]: " {
+			t.Errorf("Expected the question asked, got %q", out.String())
+		}
+	}
+}
+
+func TestPrintUndo(t *testing.T) {
+	run := RSSFFS.JournalRun{
+		ID:   "20240131-154500-3fa2",
+		Time: time.Date(2024, 1, 31, 15, 45, 0, 0, time.Local),
+		Mode: "Traversal mode",
+		Feeds: []RSSFFS.JournalFeed{
+			{ID: 42, URL: "https://alice.example.org/feed.xml", CategoryID: 3, Category: "Blogs"},
+			{URL: "https://bob.example.net/rss", CategoryID: 3, Removed: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	printUndoPreview(&buf, &run)
+	for _, expected := range []string{"Run 20240131-154500-3fa2 (Traversal mode, 2024-01-31 15:45)", "FEED ID", "42", "https://alice.example.org/feed.xml", "Blogs", "to unsubscribe", "#3", "already unsubscribed"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected undo preview to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	run.UndoneAt = time.Date(2024, 2, 1, 9, 0, 0, 0, time.Local)
+	printRuns(&buf, []RSSFFS.JournalRun{run})
+	for _, expected := range []string{"RUN", "20240131-154500-3fa2", "2024-01-31 15:45", "Traversal mode", "undone 2024-02-01 09:00"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected run list to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	printReport(&buf, &RSSFFS.Report{Results: []RSSFFS.Result{{Candidate: RSSFFS.Candidate{URL: "https://alice.example.org/feed.xml"}, Subscribed: true}}, RunID: run.ID})
+	if !strings.Contains(buf.String(), "undo it with: RSSFFS undo 20240131-154500-3fa2") {
+		t.Errorf("Expected the run ID after the report, got:\n%s", buf.String())
+	}
+}
+
+func TestWithCategoryHint(t *testing.T) {
+	notFound := &RSSFFS.CategoryNotFoundError{Name: "Link Blog", Matches: []string{"Link Blogs"}}
+	err := withCategoryHint(fmt.Errorf("error getting category ID: %w", notFound))
+	expected := `error getting category ID: category "Link Blog" does not exist in the RSS reader (did you mean "Link Blogs"?); pass --create-category to create it`
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+	if !errors.As(err, &notFound) {
+		t.Error("Expected the hinted error to still wrap the CategoryNotFoundError")
+	}
+
+	other := errors.New("connection refused")
+	if got := withCategoryHint(other); got != other {
+		t.Errorf("Expected other errors unchanged, got %v", got)
+	}
+	if withCategoryHint(nil) != nil {
+		t.Error("Expected nil to stay nil")
+	}
+}
+
+func TestPrintCategories(t *testing.T) {
+	var buf bytes.Buffer
+	printCategories(&buf, []RSSFFS.Category{{ID: 7, Title: "Link Blogs", FeedCount: 2, TotalUnread: 5}})
+	for _, expected := range []string{"ID", "TITLE", "UNREAD", "7", "Link Blogs", "2", "5"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected category list to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	category := RSSFFS.Category{ID: 7, Title: "Link Blogs"}
+	feeds := []RSSFFS.Feed{
+		{ID: 3, Title: "Alice", FeedURL: "https://alice.example.org/feed.xml", CheckedAt: time.Date(2024, 1, 31, 15, 45, 0, 0, time.Local)},
+		{ID: 4, FeedURL: "https://bob.example.net/rss", ParsingErrorCount: 3, ParsingErrorMessage: "unable to parse feed"},
+	}
+	printCategoryFeeds(&buf, category, feeds)
+	for _, expected := range []string{"Category Link Blogs (ID 7) has 2 feeds:", "CATEGORY", "LAST CHECKED", "Alice", "2024-01-31 15:45", "ok", "https://bob.example.net/rss", "error: unable to parse feed"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected category feeds to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := writeJSON(&buf, categoryFeeds{Category: category, Feeds: feeds[:1]}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{`"id": 7`, `"title": "Link Blogs"`, `"feeds": [`, `"feed_url": "https://alice.example.org/feed.xml"`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected JSON to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestExpandStdin(t *testing.T) {
+	in := strings.NewReader("12\n\n# stale feeds\n  https://example.com/feed.xml  \n")
+	got, fromStdin, err := expandStdin([]string{"3", "-", "*.example.org/*", "-"}, in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"3", "12", "https://example.com/feed.xml", "*.example.org/*"}
+	if !reflect.DeepEqual(got, expected) || !fromStdin {
+		t.Errorf("Expected %v read from stdin, got %v (%t)", expected, got, fromStdin)
+	}
+
+	got, fromStdin, _ = expandStdin([]string{"3"}, strings.NewReader("12\n"))
+	if !reflect.DeepEqual(got, []string{"3"}) || fromStdin {
+		t.Errorf("Expected stdin to be left unread, got %v (%t)", got, fromStdin)
+	}
+}
+
+func TestReadRetitles(t *testing.T) {
+	got, err := readRetitles(strings.NewReader("12 Alice's Blog\nhttps://example.com/feed.xml\tExample  News\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][2]string{{"12", "Alice's Blog"}, {"https://example.com/feed.xml", "Example  News"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if _, err := readRetitles(strings.NewReader("12\n")); err == nil {
+		t.Error("Expected an error for a line without a title")
+	}
+}
+
+func TestEachFeed(t *testing.T) {
+	feeds := []RSSFFS.Feed{{ID: 1, FeedURL: "https://alice.example.org/feed.xml"}, {ID: 2, FeedURL: "https://bob.example.net/rss"}}
+	var done []int
+	err := eachFeed(feeds, "refresh", func(feed RSSFFS.Feed) error {
+		if feed.ID == 1 {
+			return errors.New("not found")
+		}
+		done = append(done, feed.ID)
+		return nil
+	})
+	if !reflect.DeepEqual(done, []int{2}) {
+		t.Errorf("Expected to carry on past failures, got %v", done)
+	}
+	if err == nil || !strings.Contains(err.Error(), "could not refresh 1 of 2 feeds") || !strings.Contains(err.Error(), "alice.example.org") {
+		t.Errorf("Expected the failure to be reported, got %v", err)
+	}
+	if err := eachFeed(feeds, "refresh", func(RSSFFS.Feed) error { return nil }); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestClearMode(t *testing.T) {
+	savedClear, savedReplace := clearCategoryFeeds, replaceCategoryFeeds
+	defer func() { clearCategoryFeeds, replaceCategoryFeeds = savedClear, savedReplace }()
+
+	tests := []struct {
+		clear, replace bool
+		expected       RSSFFS.ClearMode
+	}{
+		{false, false, RSSFFS.ClearNone},
+		{true, false, RSSFFS.ClearBefore},
+		{false, true, RSSFFS.ClearReplace},
+		{true, true, RSSFFS.ClearReplace},
+	}
+	for _, tt := range tests {
+		clearCategoryFeeds, replaceCategoryFeeds = tt.clear, tt.replace
+		if got := clearMode(); got != tt.expected {
+			t.Errorf("-r %t, --replace %t: expected %q, got %q", tt.clear, tt.replace, tt.expected, got)
+		}
+	}
+}
+
+func TestConfirmClear(t *testing.T) {
+	category := RSSFFS.Category{ID: 7, Title: "Link Blogs"}
+	feeds := []RSSFFS.Feed{{ID: 3, FeedURL: "https://alice.example.org/feed.xml"}}
+
+	var out bytes.Buffer
+	if confirmClear(strings.NewReader("n\n"), &out, RSSFFS.ClearBefore, category, feeds) {
+		t.Error("Expected clearing to be declined")
+	}
+	for _, expected := range []string{"Category Link Blogs (ID 7) has 1 feeds", "https://alice.example.org/feed.xml", "Unsubscribe from all 1 feeds in category Link Blogs", "[y/N]"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected confirmation to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if !confirmClear(strings.NewReader("y\n"), &out, RSSFFS.ClearReplace, category, feeds) || !strings.Contains(out.String(), "that aren't found again") {
+		t.Errorf("Expected replacing to be confirmed, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if !confirmClear(strings.NewReader(""), &out, RSSFFS.ClearBefore, category, nil) || out.Len() != 0 {
+		t.Errorf("Expected an empty category to need no confirmation, got %q", out.String())
+	}
+}
+
+func TestPrintCleared(t *testing.T) {
+	report := &RSSFFS.Report{Removed: []RSSFFS.Feed{{ID: 3, FeedURL: "https://alice.example.org/feed.xml"}}, Backup: "/backups/link-blogs-20240131-154500.opml"}
+
+	var buf bytes.Buffer
+	printCleared(&buf, report, false)
+	for _, expected := range []string{"Unsubscribed from 1 feeds", "https://alice.example.org/feed.xml", "backed up to /backups/link-blogs-20240131-154500.opml"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	printCleared(&buf, &RSSFFS.Report{Removed: report.Removed}, true)
+	if !strings.Contains(buf.String(), "Would unsubscribe from 1 feeds") || strings.Contains(buf.String(), "backed up") {
+		t.Errorf("Expected a dry run to list the feeds without a backup, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printCleared(&buf, &RSSFFS.Report{}, false)
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing printed without removed feeds, got %q", buf.String())
+	}
+}
+
+func TestPrintSyncPlan(t *testing.T) {
+	plan := &RSSFFS.SyncPlan{
+		Changes: []RSSFFS.SyncChange{
+			{Action: RSSFFS.SyncCreateCategory, Category: "Podcasts"},
+			{Action: RSSFFS.SyncAdd, URL: "https://carol.example.com/atom.xml", Category: "Podcasts", Source: "listed in feeds file"},
+			{Action: RSSFFS.SyncMove, URL: "https://bob.example.net/rss", Category: "Link Blogs", From: "Other", FeedID: 4},
+			{Action: RSSFFS.SyncRemove, URL: "https://old.example.org/feed", Category: "Link Blogs", From: "Link Blogs", FeedID: 3},
+		},
+		Unchanged: 12,
+		Warnings:  []string{"News: not pruning the category, since discovering feeds on https://news.example.com failed: no feeds found"},
+	}
+
+	var buf bytes.Buffer
+	printSyncPlan(&buf, plan)
+	for _, expected := range []string{"Warning: News: not pruning", "ACTION", "create category", "add", "https://carol.example.com/atom.xml", "listed in feeds file", "move", "Other", "remove", "Plan: create 1 categories, add 1 feeds, move 1, remove 1; 12 unchanged."} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected plan to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	printSyncApplied(&buf, &RSSFFS.SyncPlan{Backups: []string{"/backups/link-blogs-20240131-154500.opml"}, RunID: "20240131-154500-3fa2"})
+	for _, expected := range []string{"Backed up a pruned category to /backups/link-blogs-20240131-154500.opml", "RSSFFS undo 20240131-154500-3fa2"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestListFeeds(t *testing.T) {
+	feeds := []RSSFFS.Feed{
+		{ID: 1, FeedURL: "https://x.example.org/feed.xml", Category: RSSFFS.Category{ID: 7}},
+		{ID: 2, FeedURL: "https://x.example.org/comments.xml", Category: RSSFFS.Category{ID: 8}},
+		{ID: 3, FeedURL: "https://bob.example.net/rss", Category: RSSFFS.Category{ID: 8}},
+	}
+
+	tests := []struct {
+		name      string
+		query     RSSFFS.FeedQuery
+		selectors []string
+		expected  []int
+		wantErr   bool
+	}{
+		{"query only", RSSFFS.FeedQuery{CategoryID: 8}, nil, []int{2, 3}, false},
+		{"selectors within the category", RSSFFS.FeedQuery{CategoryID: 8}, []string{"https://x.example.org/*"}, []int{2}, false},
+		{"selectors matching only outside the category", RSSFFS.FeedQuery{CategoryID: 9}, []string{"https://x.example.org/*"}, nil, false},
+		{"selector matching no subscribed feed", RSSFFS.FeedQuery{}, []string{"https://carol.example.com/*"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listFeeds(feeds, tt.query, tt.selectors)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %t, got %v", tt.wantErr, err)
+			}
+			var ids []int
+			for _, feed := range got {
+				ids = append(ids, feed.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected feeds %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
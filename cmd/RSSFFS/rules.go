@@ -0,0 +1,98 @@
+// Package cmd provides the rules command for checking feed rules files.
+//
+// This file implements the "rules" subcommand and its "test" subcommand,
+// which discovers the feeds on a URL as the root command would and shows
+// which rule matched each and what it decided, without subscribing.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// NewRulesCommand creates and returns the rules command and its subcommands
+func NewRulesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Work with feed rules files",
+		Long: `Work with the YAML rules files given with --rules or RSSFFS_RULES_FILE.
+
+Rules are evaluated in order against every discovered feed, and the first one
+whose "when" expression matches decides whether the feed is accepted (past the
+--max-age, --min-items and --language filters) or rejected, which category it
+is subscribed to and what it is renamed to. Feeds no rule gives a category go
+to the --category one.`,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "test [pageURL|@user@host]",
+		Short: "Show which rule matches each feed found on a URL",
+		Long: `Discover the feeds on a URL as RSSFFS would, and show which rule matched each
+feed and what it decided, without subscribing to any of them.
+
+Example:
+  RSSFFS rules test --rules rules.yaml -c "Blogs" https://example.com/blogroll`,
+		Args: cobra.ExactArgs(1),
+		RunE: runRulesTest,
+	})
+	return cmd
+}
+
+// runRulesTest executes the rules test command
+func runRulesTest(cmd *cobra.Command, args []string) error {
+	conf := config.GetEnvVars()
+	effectiveSingleURLMode := applyFlags(cmd, &conf)
+
+	input := args[0]
+	if !RSSFFS.IsHandle(input) {
+		pageURL, err := url.ParseRequestURI(input)
+		if err != nil {
+			return fmt.Errorf("invalid URL input: %w", err)
+		}
+		input = pageURL.String()
+	}
+
+	report, err := RSSFFS.EvaluateRules(input, category, effectiveSingleURLMode, conf)
+	if err != nil {
+		return err
+	}
+	if len(report.Results) == 0 {
+		_, _ = fmt.Fprintln(os.Stdout, "No feeds found.")
+		return nil
+	}
+	printRuleReport(os.Stdout, report)
+	return nil
+}
+
+// printRuleReport writes a table of what the rules decided for each feed to w.
+//
+// Each row shows the feed URL, the title it would be subscribed under, the
+// rule that matched it ("-" when none did), and whether it would be
+// subscribed, and to which category, or why it would be skipped.
+//
+// Parameters:
+//   - w: Destination for the table, usually os.Stdout
+//   - report: The report returned by RSSFFS.EvaluateRules
+func printRuleReport(w io.Writer, report *RSSFFS.Report) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "FEED\tTITLE\tRULE\tDECISION")
+	for _, result := range report.Results {
+		title := result.Title
+		if title == "" && result.Info != nil {
+			title = result.Info.Title
+		}
+		decision := "subscribe to " + orDash(result.Category)
+		if result.Skipped != "" {
+			decision = "skip: " + result.Skipped
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.URL, orDash(title), orDash(result.Rule), decision)
+	}
+	_ = tw.Flush()
+}
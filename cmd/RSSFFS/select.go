@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+)
+
+// promptForCandidates returns an RSSFFS.CandidateSelector that lists the feeds
+// found for a URL on out and reads the user's choice from in.
+//
+// The user may enter feed numbers and ranges separated by commas or spaces
+// (for example "1,3" or "2-4"), "all" or "none". An empty answer selects the
+// first, most specific, feed.
+//
+// Parameters:
+//   - in: Source of the user's answer, usually os.Stdin
+//   - out: Destination for the list and prompt, usually os.Stdout
+func promptForCandidates(in io.Reader, out io.Writer) RSSFFS.CandidateSelector {
+	reader := bufio.NewReader(in)
+	return func(candidates []RSSFFS.Candidate) ([]RSSFFS.Candidate, error) {
+		_, _ = fmt.Fprintln(out, "Found multiple feeds:")
+		for i, candidate := range candidates {
+			line := candidate.URL
+			if candidate.Info != nil && candidate.Info.Title != "" {
+				line = candidate.Info.Title + " - " + line
+			}
+			if candidate.Platform != "" {
+				line += " (" + candidate.Platform + ")"
+			}
+			_, _ = fmt.Fprintf(out, "  %d) %s\n", i+1, line)
+		}
+		_, _ = fmt.Fprint(out, "Select feeds to subscribe to (e.g. 1,3 or 2-4, \"all\" or \"none\") [1]: ")
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		indexes, err := parseSelection(answer, len(candidates))
+		if err != nil {
+			return nil, err
+		}
+
+		selected := make([]RSSFFS.Candidate, 0, len(indexes))
+		for _, i := range indexes {
+			selected = append(selected, candidates[i])
+		}
+		return selected, nil
+	}
+}
+
+// parseSelection converts a selection such as "1,3" or "2-4" into zero-based
+// indexes into a list of count items, without duplicates and in list order
+func parseSelection(answer string, count int) ([]int, error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "":
+		return []int{0}, nil
+	case "none":
+		return nil, nil
+	}
+
+	chosen := make([]bool, count)
+	if answer == "all" {
+		for i := range chosen {
+			chosen[i] = true
+		}
+	} else {
+		for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+			first, last, isRange := strings.Cut(field, "-")
+			if !isRange {
+				last = first
+			}
+			start, err := strconv.Atoi(first)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selection %q", field)
+			}
+			end, err := strconv.Atoi(last)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selection %q", field)
+			}
+			if start < 1 || end > count || start > end {
+				return nil, fmt.Errorf("selection %q is out of range 1-%d", field, count)
+			}
+			for i := start; i <= end; i++ {
+				chosen[i-1] = true
+			}
+		}
+	}
+
+	var indexes []int
+	for i, ok := range chosen {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,161 @@
+// Package cmd provides the sync command for reconciling subscriptions with a feeds file.
+//
+// This file implements the "sync" subcommand, which reads the categories and
+// feeds declared in a YAML feeds file, discovers the feeds on their pages,
+// shows the plan of subscriptions to add, move and remove, and once
+// confirmed applies it to the RSS reader.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// SyncCommand holds configuration options for the sync command
+type SyncCommand struct {
+	Prune  bool
+	DryRun bool
+	Yes    bool
+	JSON   bool
+}
+
+// NewSyncCommand creates and returns a new sync command
+func NewSyncCommand() *cobra.Command {
+	syncCmd := &SyncCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "sync <feeds.yaml>",
+		Short: "Bring the RSS reader's subscriptions in line with a feeds file",
+		Long: `Keep subscriptions in a YAML feeds file, e.g. in git, and reconcile the RSS
+reader with it. Each category lists pages to discover feeds on in traversal
+mode (sources), sites or fediverse handles to check in single URL mode (sites),
+and feed URLs to subscribe to as they are (feeds):
+
+  categories:
+    - name: Link Blogs
+      sources:
+        - https://example.com/blogroll
+      sites:
+        - https://alice.example.org
+      feeds:
+        - https://carol.example.net/feed.xml
+
+Sync discovers the feeds, compares them with the RSS reader, and shows a plan
+of the categories to create, the feeds to subscribe to and the feeds to move
+from other categories. With --prune, feeds in the declared categories that are
+no longer declared are removed too, after backing the category up to an OPML
+file. Categories with a source that can't be read or has no feeds are never
+pruned. Nothing changes until you confirm; pass --yes for scheduled runs.
+
+Examples:
+  # Show what syncing would change
+  RSSFFS sync feeds.yaml --dry-run
+
+  # Sync, removing feeds no longer declared, without asking
+  RSSFFS sync feeds.yaml --prune --yes`,
+		Args: cobra.ExactArgs(1),
+		RunE: syncCmd.runSync,
+	}
+
+	cmd.Flags().BoolVar(&syncCmd.Prune, "prune", false, "Remove feeds in the declared categories that are no longer declared")
+	cmd.Flags().BoolVar(&syncCmd.DryRun, "dry-run", false, "Only show the plan")
+	cmd.Flags().BoolVarP(&syncCmd.Yes, "yes", "y", false, "Apply the plan without asking for confirmation")
+	cmd.Flags().BoolVar(&syncCmd.JSON, "json", false, "Print the plan as JSON instead of a table")
+
+	return cmd
+}
+
+// runSync executes the sync command
+func (s *SyncCommand) runSync(cmd *cobra.Command, args []string) error {
+	conf := config.GetEnvVars()
+	applyFlags(cmd, &conf)
+
+	feeds, err := RSSFFS.LoadSyncFile(args[0])
+	if err != nil {
+		return err
+	}
+	plan, err := RSSFFS.PlanSync(feeds, args[0], s.Prune, conf)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Changes) == 0 {
+		if s.JSON {
+			return writeJSON(os.Stdout, plan)
+		}
+		printSyncWarnings(os.Stdout, plan)
+		_, _ = fmt.Fprintf(os.Stdout, "Already in sync (%d feeds).\n", plan.Unchanged)
+		return nil
+	}
+	if s.DryRun || !s.Yes {
+		if s.JSON {
+			if err := writeJSON(os.Stdout, plan); err != nil {
+				return err
+			}
+		} else {
+			printSyncPlan(os.Stdout, plan)
+		}
+	}
+	if s.DryRun {
+		return nil
+	}
+	if !s.Yes && !confirm(os.Stdin, os.Stdout, "Apply these changes?") {
+		_, _ = fmt.Fprintln(os.Stdout, "Nothing changed.")
+		return nil
+	}
+
+	err = RSSFFS.ApplySync(plan, conf)
+	if s.JSON && s.Yes {
+		// Without a plan printed before, print the applied plan, which records which changes failed
+		if jsonErr := writeJSON(os.Stdout, plan); jsonErr != nil {
+			return jsonErr
+		}
+	} else if !s.JSON {
+		printSyncApplied(os.Stdout, plan)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("Synced %s: added %d feeds, moved %d and removed %d.", args[0], plan.Count(RSSFFS.SyncAdd), plan.Count(RSSFFS.SyncMove), plan.Count(RSSFFS.SyncRemove))
+	return nil
+}
+
+// printSyncApplied writes where pruned categories were backed up to w, and
+// how to undo the subscriptions an applied plan made
+func printSyncApplied(w io.Writer, plan *RSSFFS.SyncPlan) {
+	for _, backup := range plan.Backups {
+		_, _ = fmt.Fprintf(w, "Backed up a pruned category to %s; import it into the RSS reader to restore its feeds.\n", backup)
+	}
+	if plan.RunID != "" {
+		_, _ = fmt.Fprintf(w, "Recorded as run %s; undo the subscriptions with: RSSFFS undo %s\n", plan.RunID, plan.RunID)
+	}
+}
+
+// printSyncPlan writes the plan's warnings and a table of its changes to w,
+// followed by a summary of how many feeds each kind of change affects
+func printSyncPlan(w io.Writer, plan *RSSFFS.SyncPlan) {
+	printSyncWarnings(w, plan)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ACTION\tFEED\tCATEGORY\tFROM\tSOURCE")
+	for _, change := range plan.Changes {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", change.Action, orDash(change.URL), change.Category, orDash(change.From), orDash(change.Source))
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "Plan: create %d categories, add %d feeds, move %d, remove %d; %d unchanged.\n",
+		plan.Count(RSSFFS.SyncCreateCategory), plan.Count(RSSFFS.SyncAdd), plan.Count(RSSFFS.SyncMove), plan.Count(RSSFFS.SyncRemove), plan.Unchanged)
+}
+
+// printSyncWarnings writes the plan's warnings to w, one per line
+func printSyncWarnings(w io.Writer, plan *RSSFFS.SyncPlan) {
+	for _, warning := range plan.Warnings {
+		_, _ = fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,152 @@
+// Package cmd provides the undo command for reverting a run's subscriptions.
+//
+// This file implements the "undo" subcommand, which looks up a run in the
+// run journal, previews the feeds it subscribed to and, once confirmed,
+// unsubscribes from exactly those feeds.
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// UndoCommand holds configuration options for the undo command
+type UndoCommand struct {
+	DryRun bool
+	Yes    bool
+	List   bool
+}
+
+// NewUndoCommand creates and returns a new undo command
+func NewUndoCommand() *cobra.Command {
+	undoCmd := &UndoCommand{}
+
+	cmd := &cobra.Command{
+		Use:   "undo [run-id]",
+		Short: "Unsubscribe from the feeds a run subscribed to",
+		Long: `Every run records the feeds it subscribes to in the run journal
+(RSSFFS_JOURNAL_FILE), under a run ID printed at the end of the run. Undo
+unsubscribes from exactly those feeds, for the given run or the latest run not
+yet undone. The feeds are listed first, and nothing is unsubscribed from until
+you confirm.
+
+Examples:
+  # List the recorded runs
+  RSSFFS undo --list
+
+  # Preview, then undo, the latest run
+  RSSFFS undo
+
+  # Only preview what undoing a run would unsubscribe from
+  RSSFFS undo 20240131-154500-3fa2 --dry-run`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: undoCmd.runUndo,
+	}
+
+	cmd.Flags().BoolVar(&undoCmd.DryRun, "dry-run", false, "Only list the feeds that would be unsubscribed from")
+	cmd.Flags().BoolVarP(&undoCmd.Yes, "yes", "y", false, "Unsubscribe without asking for confirmation")
+	cmd.Flags().BoolVar(&undoCmd.List, "list", false, "List the runs recorded in the run journal")
+
+	return cmd
+}
+
+// runUndo executes the undo command
+func (u *UndoCommand) runUndo(cmd *cobra.Command, args []string) error {
+	conf := config.GetEnvVars()
+
+	if u.List {
+		runs, err := RSSFFS.ListRuns(conf)
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			_, _ = fmt.Fprintln(os.Stdout, "No runs recorded.")
+			return nil
+		}
+		printRuns(os.Stdout, runs)
+		return nil
+	}
+
+	runID := ""
+	if len(args) > 0 {
+		runID = args[0]
+	}
+	run, err := RSSFFS.Undo(runID, true, conf)
+	if err != nil {
+		return err
+	}
+	printUndoPreview(os.Stdout, run)
+	if u.DryRun {
+		return nil
+	}
+	if !u.Yes && !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Unsubscribe from these %d feeds?", len(run.Feeds))) {
+		_, _ = fmt.Fprintln(os.Stdout, "Nothing unsubscribed from.")
+		return nil
+	}
+
+	if _, err := RSSFFS.Undo(run.ID, false, conf); err != nil {
+		return err
+	}
+	log.Infof("Undid run %s.", run.ID)
+	return nil
+}
+
+// confirm asks question on out and reports whether the answer read from in is yes
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	_, _ = fmt.Fprintf(out, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// printRuns writes a table of recorded runs to w, newest first
+func printRuns(w io.Writer, runs []RSSFFS.JournalRun) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "RUN\tTIME\tMODE\tINPUT\tFEEDS\tSTATUS")
+	for _, run := range runs {
+		status := "active"
+		if run.Undone() {
+			status = "undone " + run.UndoneAt.Local().Format("2006-01-02 15:04")
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", run.ID, run.Time.Local().Format("2006-01-02 15:04"), run.Mode, orDash(run.Input), strconv.Itoa(len(run.Feeds)), status)
+	}
+	_ = tw.Flush()
+}
+
+// printUndoPreview writes the feeds undoing run would unsubscribe from to w
+func printUndoPreview(w io.Writer, run *RSSFFS.JournalRun) {
+	_, _ = fmt.Fprintf(w, "Run %s (%s, %s) subscribed to:\n", run.ID, run.Mode, run.Time.Local().Format("2006-01-02 15:04"))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "FEED ID\tFEED\tCATEGORY\tSTATUS")
+	for _, feed := range run.Feeds {
+		id, category, status := "-", feed.Category, "to unsubscribe"
+		if feed.ID != 0 {
+			id = strconv.Itoa(feed.ID)
+		}
+		if category == "" {
+			category = "#" + strconv.Itoa(feed.CategoryID)
+		}
+		if feed.Removed {
+			status = "already unsubscribed"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", id, feed.URL, category, status)
+	}
+	_ = tw.Flush()
+}
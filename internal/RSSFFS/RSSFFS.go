@@ -1,7 +1,11 @@
 package RSSFFS
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strings"
@@ -20,18 +24,27 @@ type Category struct {
 	Title  string `json:"title"`
 	UserID int    `json:"user_id"`
 	ID     int    `json:"id"`
-}
 
-var (
-	apiEndpoint string
-	apiKey      string
-)
+	// FeedCount and TotalUnread are only given when asked for, by Miniflux 2.0.46 and later
+	FeedCount   int `json:"feed_count,omitempty"`
+	TotalUnread int `json:"total_unread,omitempty"`
+}
 
 var commonPatterns = []string{"/index.xml", "/feed", "/feed.xml", "/rss", "/rss.xml", "/atom.xml", "/?format=rss"}
 
 const maxRedirects = 10
 const timeoutSeconds = 10
 
+// maxResolvedLinksPerDomain caps how many linked URLs per domain are passed to
+// site resolvers in traversal mode, since some resolvers fetch each page
+const maxResolvedLinksPerDomain = 5
+
+// maxConcurrentDomainChecks caps how many linked domains traversal mode checks for feeds at once
+const maxConcurrentDomainChecks = 8
+
+// maxPageBytes caps how much of a fetched page or OPML file is read into memory
+const maxPageBytes = 10 << 20
+
 // validateURL validates that a URL is safe to request and not targeting internal networks
 func validateURL(rawURL string) error {
 	if rawURL == "" {
@@ -150,8 +163,16 @@ func extractDomainFromURL(pageURL string) (string, error) {
 	return hostname, nil
 }
 
-// getAllDomainsFromPage retrieves all unique domain names from a webpage
-func getAllDomainsFromPage(pageURL string) (map[string]bool, error) {
+// fetchedPage is a downloaded document along with the metadata needed to decide how to treat it
+type fetchedPage struct {
+	URL         string
+	ContentType string
+	Header      http.Header
+	Body        []byte
+}
+
+// fetchPage downloads a document after validating its URL, capping the body at maxPageBytes
+func fetchPage(pageURL string) (*fetchedPage, error) {
 	// Validate the URL before making the request
 	if err := validateURL(pageURL); err != nil {
 		return nil, fmt.Errorf("invalid URL: %v", err)
@@ -171,15 +192,54 @@ func getAllDomainsFromPage(pageURL string) (map[string]bool, error) {
 		}
 	}()
 
-	tokenizer := html.NewTokenizer(resp.Body)
-	domains := make(map[string]bool)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d fetching %s", resp.StatusCode, pageURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	return &fetchedPage{
+		URL:         resp.Request.URL.String(),
+		ContentType: strings.ToLower(resp.Header.Get("Content-Type")),
+		Header:      resp.Header,
+		Body:        body,
+	}, nil
+}
+
+// fetchJSON downloads a JSON document after validating its URL and decodes it into v
+func fetchJSON(jsonURL string, v interface{}) error {
+	page, err := fetchPage(jsonURL)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(page.Body, v)
+}
+
+// getAllDomainsFromPage retrieves all unique domain names from a webpage, along with the URLs linked on each
+func getAllDomainsFromPage(pageURL string) (map[string][]string, error) {
+	page, err := fetchPage(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	return domainsFromHTML(page.Body), nil
+}
+
+// domainsFromHTML extracts all unique domain names linked from an HTML document,
+// mapping each domain to the distinct absolute URLs linked on it in document order
+func domainsFromHTML(body []byte) map[string][]string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	domains := make(map[string][]string)
+	seen := make(map[string]bool)
 
 	// Parse HTML and extract URLs
 	for {
 		tt := tokenizer.Next()
 		switch tt {
 		case html.ErrorToken:
-			return domains, nil
+			return domains
 		case html.StartTagToken:
 			t := tokenizer.Token()
 			if t.Data == "a" {
@@ -188,7 +248,12 @@ func getAllDomainsFromPage(pageURL string) (map[string]bool, error) {
 						u, err := url.Parse(attr.Val)
 						if err == nil && u.Host != "" {
 							domain := u.Hostname()
-							domains[domain] = true
+							u.Fragment = ""
+							link := u.String()
+							if !seen[link] {
+								seen[link] = true
+								domains[domain] = append(domains[domain], link)
+							}
 						}
 					}
 				}
@@ -197,27 +262,34 @@ func getAllDomainsFromPage(pageURL string) (map[string]bool, error) {
 	}
 }
 
-// checkDomainsForRSS checks for RSS feeds on the given domains with concurrency
-func checkDomainsForRSS(domains map[string]bool, pageURL string) []string {
+// checkDomainsForRSS checks for RSS feeds on the given domains with concurrency.
+// domains maps each domain to the URLs linked on it, which site resolvers use to
+// find platform feeds (e.g. one per linked subreddit) before falling back to
+// the common patterns on the domain itself. When mentions is given, each feed
+// records how many times its domain was mentioned.
+func (s *settings) checkDomainsForRSS(domains map[string][]string, pageURL string, mentions map[string]int) []Candidate {
 	var wg sync.WaitGroup
-	feedChan := make(chan string)
+	feedChan := make(chan Candidate)
 	feedMap := make(map[string]bool)
 	mu := sync.Mutex{}
+	sem := make(chan struct{}, maxConcurrentDomainChecks)
 
-	for domain := range domains {
+	for domain, links := range domains {
 		wg.Add(1)
-		go func(domain string) {
+		go func(domain string, links []string) {
 			defer wg.Done()
-			feed := findPreferredRSSFeed(domain, pageURL)
-			if feed != "" {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			for _, feed := range s.findLinkedRSSFeeds(domain, links, pageURL) {
+				feed.Mentions = mentions[domain]
 				mu.Lock()
-				if !feedMap[domain] {
-					feedMap[domain] = true
+				if !feedMap[feed.URL] {
+					feedMap[feed.URL] = true
 					feedChan <- feed
 				}
 				mu.Unlock()
 			}
-		}(domain)
+		}(domain, links)
 	}
 
 	// Close channel when all goroutines are done
@@ -226,7 +298,7 @@ func checkDomainsForRSS(domains map[string]bool, pageURL string) []string {
 		close(feedChan)
 	}()
 
-	var validFeeds []string
+	var validFeeds []Candidate
 	for feed := range feedChan {
 		validFeeds = append(validFeeds, feed)
 	}
@@ -234,9 +306,9 @@ func checkDomainsForRSS(domains map[string]bool, pageURL string) []string {
 	return validFeeds
 }
 
-// findPreferredRSSFeed checks RSS patterns for a domain and returns the first valid one based on preference
-func findPreferredRSSFeed(domain string, originalURL string) string {
-	client := &http.Client{
+// newFeedClient returns the HTTP client used to probe candidate feed URLs
+func newFeedClient() *http.Client {
+	return &http.Client{
 		Timeout: time.Second * timeoutSeconds,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= maxRedirects {
@@ -245,6 +317,74 @@ func findPreferredRSSFeed(domain string, originalURL string) string {
 			return nil
 		},
 	}
+}
+
+// findLinkedRSSFeeds returns the feeds site resolvers find for up to
+// maxResolvedLinksPerDomain of the links on a domain. Only the first link's
+// page is fetched, unless a resolver knows the host by name, so that resolvers
+// needing a page's HTML cost one request per domain. When no resolver finds
+// anything, it falls back to the domain's preferred feed.
+func (s *settings) findLinkedRSSFeeds(domain string, links []string, originalURL string) []Candidate {
+	client := newFeedClient()
+
+	var feeds []Candidate
+	seen := make(map[string]bool)
+	for i, link := range links {
+		if i >= maxResolvedLinksPerDomain {
+			log.Debugf("Only resolving the first %d links on domain %s", maxResolvedLinksPerDomain, domain)
+			break
+		}
+		target, err := newTarget(link)
+		if err != nil {
+			continue
+		}
+		target.run = s
+		if i > 0 && !matchesHost(target) {
+			target.urlOnly = true
+		}
+		for _, feed := range resolverFeeds(client, target, false) {
+			if !seen[feed] {
+				seen[feed] = true
+				feeds = append(feeds, Candidate{URL: feed, Platform: detectPlatform(target), Hreflang: feedHreflang(target, feed)})
+			}
+		}
+	}
+	if len(feeds) > 0 {
+		return feeds
+	}
+
+	if feeds := s.discoverFeeds(domain, originalURL, false); len(feeds) > 0 {
+		return feeds
+	}
+
+	// As a last resort, a bridge may be able to generate a feed for the first linked page
+	if len(links) > 0 {
+		if bridged := s.bridgedFeed(links[0]); bridged != nil {
+			return []Candidate{*bridged}
+		}
+	}
+	return nil
+}
+
+// discoverFeeds checks resolver candidates and RSS patterns for a domain. By
+// default only the first valid feed, in order of preference, is returned; with
+// all set, every valid resolver candidate is returned so that a site's section,
+// category and author feeds can be offered alongside its site-wide feed.
+func (s *settings) discoverFeeds(domain string, originalURL string, all bool) []Candidate {
+	client := newFeedClient()
+
+	// Site-specific resolvers know better than the common patterns, so try them first
+	if target := targetForDomain(domain, originalURL); target != nil {
+		target.run = s
+		if feeds := resolverFeeds(client, target, all); len(feeds) > 0 {
+			platform := detectPlatform(target)
+			candidates := make([]Candidate, 0, len(feeds))
+			for _, feed := range feeds {
+				candidates = append(candidates, Candidate{URL: feed, Platform: platform, Hreflang: feedHreflang(target, feed)})
+			}
+			return candidates
+		}
+	}
 
 	log.Debugf("Checking RSS patterns for domain: %s", domain)
 	for _, pattern := range commonPatterns {
@@ -252,26 +392,29 @@ func findPreferredRSSFeed(domain string, originalURL string) string {
 		log.Debugf("Checking RSS feed URL: %s", feedURL)
 		if checkRSSFeed(client, feedURL) {
 			log.Debugf("Valid RSS feed found at: %s", feedURL)
-			return feedURL
+			return []Candidate{{URL: feedURL}}
 		}
 	}
 
-	// Special case for medium.com: if original URL is https://medium.com/$USERNAME, try https://medium.com/feed/$USERNAME
-	if domain == "medium.com" && strings.HasPrefix(originalURL, "https://medium.com/") {
-		path := strings.TrimPrefix(originalURL, "https://medium.com/")
-		if path != "" && !strings.Contains(path, "/") {
-			// Assuming it's a username, no further slashes
-			specialURL := "https://medium.com/feed/" + path
-			log.Debugf("Checking special Medium RSS feed URL: %s", specialURL)
-			if checkRSSFeed(client, specialURL) {
-				log.Debugf("Valid RSS feed found at special Medium URL: %s", specialURL)
-				return specialURL
+	log.Debugf("No RSS feeds found for domain: %s", domain)
+	return nil
+}
+
+// resolverFeeds returns the valid feeds proposed by the site resolvers for
+// target: only the first one, or all of them when all is set
+func resolverFeeds(client *http.Client, target *Target, all bool) []string {
+	var feeds []string
+	for _, feedURL := range resolveCandidates(target) {
+		log.Debugf("Checking resolver candidate feed URL: %s", feedURL)
+		if checkRSSFeed(client, feedURL) {
+			log.Debugf("Valid RSS feed found at resolver candidate: %s", feedURL)
+			feeds = append(feeds, feedURL)
+			if !all {
+				break
 			}
 		}
 	}
-
-	log.Debugf("No RSS feeds found for domain: %s", domain)
-	return ""
+	return feeds
 }
 
 // checkRSSFeed checks if the given URL is a valid RSS feed
@@ -281,8 +424,13 @@ func checkRSSFeed(client *http.Client, feedURL string) bool {
 		log.Debugf("Skipping invalid RSS feed URL %s: %v", feedURL, err)
 		return false
 	}
+	return servesFeed(client, feedURL)
+}
 
-	resp, err := client.Get(feedURL)
+// servesFeed checks if the given URL answers with an RSS feed, without
+// validating it first, for URLs from configuration such as bridged feeds
+func servesFeed(client *http.Client, feedURL string) bool {
+	resp, err := client.Get(feedURL) // #nosec G704 -- callers validate feedURL or take it from config
 	if err != nil || resp.StatusCode != 200 {
 		return false
 	}
@@ -297,122 +445,406 @@ func checkRSSFeed(client *http.Client, feedURL string) bool {
 	return strings.Contains(contentType, "xml") || strings.Contains(contentType, "rss")
 }
 
-func Run(pageURL string, category string, debug bool, clearCategoryFeeds bool, singleURLMode bool, conf config.Config) (int, error) {
+// CandidateSelector chooses which of the feeds found for a single URL to
+// subscribe to, returning any subset of candidates
+type CandidateSelector func(candidates []Candidate) ([]Candidate, error)
+
+// SelectCandidates, when set, is offered every valid feed found in single URL
+// mode, such as a blog's category and author feeds alongside its site-wide
+// feed. When nil, only the preferred feed is subscribed to.
+var SelectCandidates CandidateSelector
+
+// Run discovers RSS feeds from pageURL and subscribes to them, returning the number of feeds subscribed
+func Run(pageURL string, category string, debug bool, clearMode ClearMode, singleURLMode bool, conf config.Config) (int, error) {
+	report, err := RunReport(pageURL, category, debug, clearMode, singleURLMode, conf)
+	return report.SubscribedCount(), err
+}
+
+// RunReport behaves like Run but returns a Report describing every feed that was handled.
+// clearMode says what happens to the feeds already in category, which are backed
+// up to an OPML file before any of them are unsubscribed from.
+func RunReport(pageURL string, category string, debug bool, clearMode ClearMode, singleURLMode bool, conf config.Config) (*Report, error) {
 	// Use configuration passed from caller
-	apiEndpoint, apiKey = conf.RSSReaderEndpoint, conf.RSSReaderAPIKey
+	s, err := newSettings(conf)
+	if err != nil {
+		return nil, err
+	}
+	if clearMode != ClearNone && category == "" {
+		return nil, errors.New("clearing a category's feeds needs a category")
+	}
+
+	// Mode selection logic based on CLI flag and environment variable precedence
+	// CLI flag takes precedence over environment variable
+	useSingleURLMode := singleURLMode || conf.SingleURLMode
+
+	// Fediverse handles name a single account, so resolve them to a profile page
+	// and only look for that account's feed. This and checking the URL happen
+	// before the category is cleared, so that bad input leaves it as it was.
+	if IsHandle(pageURL) {
+		profileURL, err := ResolveHandle(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		pageURL, useSingleURLMode = profileURL, true
+	}
+	if _, err := extractDomainFromURL(pageURL); err != nil {
+		return nil, err
+	}
 
 	// Get categoryId of user-input category if it exists
-	categoryId, err := getCategoryId(apiEndpoint, apiKey, category)
+	categoryId, err := s.reader.ResolveCategoryID(category, s.createCategories)
 	if err != nil {
-		return 0, fmt.Errorf("error getting categoryId from category %s: %w", category, err)
+		return nil, fmt.Errorf("error getting categoryId from category %s: %w", category, err)
 	}
 
-	// delete all feeds within categoryId if user requested it
-	if clearCategoryFeeds {
-		feedIds, err := getCategoryFeeds(apiEndpoint, apiKey, categoryId)
+	// Podcasts may be routed to their own category
+	if err := s.resolvePodcastCategory(conf.PodcastCategory); err != nil {
+		return nil, err
+	}
+
+	// delete all feeds within categoryId if user requested it, or once
+	// subscribing succeeded when replacing them
+	var categoryFeeds []Feed
+	cleared := &Report{}
+	if clearMode != ClearNone {
+		categoryFeeds, err = s.reader.CategoryFeeds(categoryId)
 		if err != nil {
-			return 0, fmt.Errorf("error getting feeds in categoryId %d: %w", categoryId, err)
+			return nil, fmt.Errorf("error getting feeds in categoryId %d: %w", categoryId, err)
 		}
+	}
+	if clearMode == ClearBefore {
 		log.Info("Deleting feeds from categoryId: ", categoryId)
-		for _, feedId := range feedIds {
-			log.Debug("Deleting feedId ", feedId)
-			err := deleteFeed(apiEndpoint, apiKey, feedId)
-			if err != nil {
-				log.Errorf("Error deleting feedId %d: %v\n ", feedId, err)
-			}
+		if err := s.clearFeeds(cleared, category, categoryFeeds, categoryFeeds, debug); err != nil {
+			return nil, err
 		}
 	}
 
-	// Mode selection logic based on CLI flag and environment variable precedence
-	// CLI flag takes precedence over environment variable
+	var report *Report
+	if useSingleURLMode {
+		report, err = s.runSingleURLMode(pageURL, categoryId, debug)
+	} else {
+		report, err = s.runTraversalMode(pageURL, categoryId, debug)
+	}
+	if err != nil {
+		if cleared.Backup != "" {
+			err = fmt.Errorf("%w (the feeds unsubscribed from are backed up in %s)", err, cleared.Backup)
+		}
+		return report, err
+	}
+	report.Backup, report.Removed = cleared.Backup, cleared.Removed
+
+	if clearMode == ClearReplace {
+		log.Info("Replacing feeds in categoryId: ", categoryId)
+		if err := s.clearFeeds(report, category, categoryFeeds, replacedFeeds(categoryFeeds, report), debug); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// EvaluateRules discovers the feeds on pageURL as RunReport would and returns
+// a Report of what the rules and feed filter decide for each, without
+// subscribing to any. Results not given a category by a rule are given the
+// podcast category or category, whichever would have been used.
+func EvaluateRules(pageURL string, category string, singleURLMode bool, conf config.Config) (*Report, error) {
+	s, err := newSettings(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	useSingleURLMode := singleURLMode || conf.SingleURLMode
+	if IsHandle(pageURL) {
+		profileURL, err := ResolveHandle(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		pageURL, useSingleURLMode = profileURL, true
+	}
 
+	var candidates []Candidate
 	if useSingleURLMode {
-		return runSingleURLMode(pageURL, categoryId, debug)
+		candidates, err = s.singleURLCandidates(pageURL, true)
+	} else {
+		candidates, err = s.traversalCandidates(pageURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inspectFeeds(candidates)
+	report := &Report{}
+	now := time.Now()
+	for _, candidate := range candidates {
+		result := s.evaluateCandidate(candidate, pageURL, now)
+		if result.Category == "" {
+			result.Category = category
+			if candidate.Info != nil && candidate.Info.Podcast && conf.PodcastCategory != "" {
+				result.Category = conf.PodcastCategory
+			}
+		}
+		report.Results = append(report.Results, result)
 	}
-	return runTraversalMode(pageURL, categoryId, debug)
+	return report, nil
+}
+
+// SubscribeFeed subscribes to a known feed URL in category without any
+// discovery, e.g. for feeds generated by RSSFFS serve from scraping rules. A
+// feed already subscribed to is left as it is.
+func SubscribeFeed(feedURL string, category string, conf config.Config) error {
+	client := NewReaderClient(conf.RSSReaderEndpoint, conf.RSSReaderAPIKey)
+	if feeds, err := client.Feeds(); err != nil {
+		log.Warnf("Could not fetch subscribed feeds to skip duplicates: %v", err)
+	} else if subscription, _ := newSubscriptions(feeds).match(Candidate{URL: feedURL}); subscription != SubscriptionNew {
+		log.Infof("Already subscribed to RSS feed %s", feedURL)
+		return nil
+	}
+	categoryId, err := client.ResolveCategoryID(category, conf.CreateCategory)
+	if err != nil {
+		return fmt.Errorf("error getting categoryId from category %s: %w", category, err)
+	}
+	feedId, err := client.Subscribe(categoryId, feedURL)
+	if IsReaderError(err, ReaderErrorDuplicateFeed) {
+		log.Infof("Already subscribed to RSS feed %s", feedURL)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recordRun(conf.JournalFile, "Subscribe", feedURL, []JournalFeed{{ID: feedId, URL: feedURL, CategoryID: categoryId, Category: category}})
+	return nil
 }
 
 // runSingleURLMode implements single URL mode that only checks the provided URL's domain
-func runSingleURLMode(pageURL string, categoryId int, debug bool) (int, error) {
+func (s *settings) runSingleURLMode(pageURL string, categoryId int, debug bool) (*Report, error) {
+	// When a selector is set, every valid feed is offered to it rather than only the preferred one
+	feeds, err := s.singleURLCandidates(pageURL, SelectCandidates != nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(feeds) == 0 {
+		return &Report{}, nil
+	}
+
+	if SelectCandidates != nil && len(feeds) > 1 {
+		// Read each feed's metadata up front so the selector can show titles
+		inspectFeeds(feeds)
+		selected, err := SelectCandidates(feeds)
+		if err != nil {
+			return nil, fmt.Errorf("single URL mode: Error selecting feeds: %w", err)
+		}
+		if len(selected) == 0 {
+			log.Infof("Single URL mode: No feeds selected")
+			return &Report{}, nil
+		}
+		feeds = selected
+	}
+
+	report := s.subscribeCandidates(feeds, pageURL, categoryId, debug, "Single URL mode")
+	if report.SubscribedCount() == 0 && report.Results[0].Error != "" {
+		log.Errorf("Single URL mode: Please check your RSS reader configuration and network connectivity")
+		return report, report.Results[0].err
+	}
+	return report, nil
+}
+
+// singleURLCandidates returns the feeds found on the provided URL's domain:
+// only the preferred one, or every valid one when all is set
+func (s *settings) singleURLCandidates(pageURL string, all bool) ([]Candidate, error) {
 	domain, err := extractDomainFromURL(pageURL)
 	if err != nil {
 		log.Errorf("Single URL mode: Failed to extract domain from URL '%s': %v", pageURL, err)
 		log.Errorf("Single URL mode: Please ensure the URL is properly formatted (e.g., https://example.com)")
-		return 0, err
+		return nil, err
 	}
 
 	log.Infof("Using single URL mode for domain: %s", domain)
 	log.Debugf("Single URL mode: checking common RSS patterns on %s", domain)
 
 	// Use existing RSS detection logic for the target domain
-	feed := findPreferredRSSFeed(domain, pageURL)
-	if feed != "" {
-		log.Infof("Single URL mode: Found RSS feed on %s: %s", domain, feed)
-		if debug {
-			log.Debugf("Single URL mode: Debug mode enabled - pretending to subscribe to feed: %s", feed)
-			return 1, nil
-		} else {
-			if err := subscribeToFeed(apiEndpoint, apiKey, categoryId, feed); err != nil {
-				log.Errorf("Single URL mode: Error subscribing to RSS feed %s: %v", feed, err)
-				log.Errorf("Single URL mode: Please check your RSS reader configuration and network connectivity")
-				return 0, err
-			} else {
-				log.Infof("Single URL mode: Successfully subscribed to RSS feed: %s", feed)
-				return 1, nil
-			}
+	feeds := s.discoverFeeds(domain, pageURL, all)
+	if len(feeds) == 0 {
+		if bridged := s.bridgedFeed(pageURL); bridged != nil {
+			log.Infof("Single URL mode: No native RSS feed on %s, using feed bridged by %s", domain, bridged.Bridge)
+			feeds = []Candidate{*bridged}
 		}
-	} else {
+	}
+	if len(feeds) == 0 {
 		log.Infof("Single URL mode: No RSS feeds found on domain %s", domain)
 		log.Infof("Single URL mode: Checked common RSS patterns: %v", commonPatterns)
 		log.Infof("Single URL mode: The website may not have RSS feeds, or they may be located at non-standard paths")
+		return nil, nil
+	}
+
+	for i := range feeds {
+		feeds[i].Source = "found on " + domain
+		log.Infof("Single URL mode: Found RSS feed on %s: %s", domain, feeds[i].URL)
 	}
-	return 0, nil
+	return feeds, nil
 }
 
 // runTraversalMode implements the existing traversal mode logic
-func runTraversalMode(pageURL string, categoryId int, debug bool) (int, error) {
+func (s *settings) runTraversalMode(pageURL string, categoryId int, debug bool) (*Report, error) {
+	candidates, err := s.traversalCandidates(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return &Report{}, nil
+	}
+	return s.subscribeCandidates(candidates, pageURL, categoryId, debug, "Traversal mode"), nil
+}
+
+// traversalCandidates returns the feeds listed in pageURL when it is an OPML
+// file or links to a blogroll, those of the sites its items link to when it
+// is a feed, and otherwise those found on every domain the page links to
+func (s *settings) traversalCandidates(pageURL string) ([]Candidate, error) {
 	log.Info("Using traversal mode, checking all domains found on page")
 
-	// Get all unique domains from the page
-	log.Infof("Traversal mode: Getting all unique domains from the URL: %s", pageURL)
-	domains, err := getAllDomainsFromPage(pageURL)
+	log.Infof("Traversal mode: Fetching the URL: %s", pageURL)
+	page, err := fetchPage(pageURL)
 	if err != nil {
-		return 0, fmt.Errorf("traversal mode: Error fetching page %s: %w", pageURL, err)
+		return nil, fmt.Errorf("traversal mode: Error fetching page %s: %w", pageURL, err)
+	}
+
+	// An OPML file given as input is read directly, without any HTML crawling
+	if isOPML(page.ContentType, page.Body) {
+		feeds, err := parseOPML(bytes.NewReader(page.Body))
+		if err != nil {
+			return nil, fmt.Errorf("traversal mode: Error reading OPML file %s: %w", pageURL, err)
+		}
+		log.Infof("Traversal mode: Found %d feeds listed in OPML file %s", len(feeds), pageURL)
+		return candidatesFromURLs(feeds, "listed in "+pageURL), nil
 	}
 
+	// A feed is traversed through the links in its items rather than as a page
+	if isFeedDocument(page.ContentType, page.Body) {
+		return s.feedItemCandidates(page)
+	}
+
+	// Likewise, a site publishing its blogroll as OPML is read from that file
+	if candidates := findBlogrollFeeds(page); len(candidates) > 0 {
+		log.Infof("Traversal mode: Using %d feeds from the site's blogroll", len(candidates))
+		return candidates, nil
+	}
+
+	// Get all unique domains from the page
+	log.Infof("Traversal mode: Getting all unique domains from the URL: %s", pageURL)
+	domains := domainsFromHTML(page.Body)
+
 	log.Infof("Traversal mode: Found %d unique domains to check for RSS feeds", len(domains))
 	if len(domains) == 0 {
 		log.Warnf("Traversal mode: No domains found on page %s", pageURL)
-		return 0, nil
+		return nil, nil
 	}
 
 	// Deduplicate valid RSS feeds
-	validFeeds := checkDomainsForRSS(domains, pageURL)
+	validFeeds := s.checkDomainsForRSS(domains, pageURL, nil)
 
 	if len(validFeeds) == 0 {
 		log.Infof("Traversal mode: No RSS feeds found across %d domains", len(domains))
-		return 0, nil
+		return nil, nil
 	}
 
 	log.Infof("Traversal mode: Found %d RSS feeds across %d domains", len(validFeeds), len(domains))
 
-	// Subscribe to valid RSS feeds
-	successCount := 0
-	for _, feed := range validFeeds {
+	source := "linked from " + pageURL
+	if u, err := url.Parse(pageURL); err == nil {
+		source = "linked from " + u.Hostname()
+	}
+	for i := range validFeeds {
+		validFeeds[i].Source = source
+	}
+	return validFeeds, nil
+}
+
+// subscribeCandidates reads each candidate's metadata, subscribes to it (or
+// pretends to, in debug mode) and records the outcome in a Report. Rules are
+// evaluated against each candidate and the page it came from, and may reject
+// it, accept it past the feed filter, or choose its category and title.
+// Otherwise feeds left out by the feed filter are recorded with the reason,
+// and podcast feeds are subscribed to the podcast category when one is
+// configured. mode prefixes log messages.
+func (s *settings) subscribeCandidates(candidates []Candidate, page string, categoryId int, debug bool, mode string) *Report {
+	report := &Report{}
+	now := time.Now()
+	categoryIds := make(map[string]int)
+	existing := s.loadSubscriptions(mode)
+	var journal []JournalFeed
+	// Inspect a copy, leaving the caller's candidates as they were
+	candidates = append([]Candidate(nil), candidates...)
+	inspectFeeds(candidates)
+	for _, candidate := range candidates {
+		result := s.evaluateCandidate(candidate, page, now)
+		if result.Rule != "" {
+			log.Debugf("%s: Rule %s matched RSS feed %s", mode, result.Rule, candidate.URL)
+		}
+		if result.Skipped != "" {
+			log.Infof("%s: Skipping RSS feed %s: %s", mode, candidate.URL, result.Skipped)
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		subscription, feed := existing.match(candidate)
+		result.Subscription = subscription
+		switch subscription {
+		case SubscriptionExisting:
+			result.SubscribedAs, result.Category = feed.FeedURL, feed.Category.Title
+			log.Infof("%s: Already subscribed to RSS feed %s", mode, candidate.URL)
+			report.Results = append(report.Results, result)
+			continue
+		case SubscriptionOtherURL:
+			// A site can publish several feeds, e.g. its posts, comments and a
+			// podcast, so feeds of a site already subscribed to are still subscribed to
+			result.SubscribedAs = feed.FeedURL
+			log.Infof("%s: Already subscribed to the site of RSS feed %s as %s, subscribing to this feed too", mode, candidate.URL, feed.FeedURL)
+		}
+
+		feedCategoryId := categoryId
+		switch {
+		case result.Category != "":
+			id, ok := categoryIds[result.Category]
+			if !ok {
+				var err error
+				if id, err = s.reader.ResolveCategoryID(result.Category, s.createCategories); err != nil {
+					log.Errorf("%s: Error getting categoryId from category %s: %v", mode, result.Category, err)
+					result.setError(err)
+					report.Results = append(report.Results, result)
+					continue
+				}
+				categoryIds[result.Category] = id
+			}
+			log.Debugf("%s: Routing RSS feed %s to category %s", mode, candidate.URL, result.Category)
+			feedCategoryId = id
+		case candidate.Info != nil && candidate.Info.Podcast && s.podcastCategoryID != 0:
+			log.Debugf("%s: Routing podcast feed %s to categoryId %d", mode, candidate.URL, s.podcastCategoryID)
+			feedCategoryId = s.podcastCategoryID
+		}
+
 		if debug {
-			log.Debugf("Traversal mode: Debug mode enabled - pretending to subscribe to feed: %s", feed)
-			successCount++
+			log.Debugf("%s: Debug mode enabled - pretending to subscribe to feed: %s", mode, candidate.URL)
+			result.Subscribed = true
+		} else if feedId, err := s.reader.Subscribe(feedCategoryId, candidate.URL); IsReaderError(err, ReaderErrorDuplicateFeed) {
+			// Feeds the comparison above missed, e.g. behind a redirect, are still duplicates rather than failures
+			log.Infof("%s: Already subscribed to RSS feed %s: %v", mode, candidate.URL, err)
+			result.Subscription = SubscriptionExisting
+		} else if err != nil {
+			log.Errorf("%s: Error subscribing to RSS feed %s: %v", mode, candidate.URL, err)
+			result.setError(err)
 		} else {
-			if err := subscribeToFeed(apiEndpoint, apiKey, categoryId, feed); err != nil {
-				log.Errorf("Traversal mode: Error subscribing to RSS feed %s: %v", feed, err)
-			} else {
-				log.Infof("Traversal mode: Successfully subscribed to RSS feed: %s", feed)
-				successCount++
+			log.Infof("%s: Successfully subscribed to RSS feed: %s", mode, candidate.URL)
+			result.Subscribed, result.FeedID = true, feedId
+			existing.add(candidate.URL)
+			journal = append(journal, JournalFeed{ID: feedId, URL: candidate.URL, CategoryID: feedCategoryId, Category: result.Category})
+			if result.Title != "" && feedId != 0 {
+				if err := s.reader.RenameFeed(feedId, result.Title); err != nil {
+					log.Errorf("%s: Error renaming RSS feed %s to %q: %v", mode, candidate.URL, result.Title, err)
+				}
 			}
 		}
+		report.Results = append(report.Results, result)
 	}
 
-	log.Infof("Traversal mode: Successfully processed %d out of %d RSS feeds", successCount, len(validFeeds))
-	return successCount, nil
+	log.Infof("%s: Successfully processed %d out of %d RSS feeds (%d already subscribed, %d skipped by rules and filters)", mode, report.SubscribedCount(), len(candidates), report.AlreadySubscribedCount(), report.SkippedCount())
+	report.RunID = recordRun(s.journalFile, mode, page, journal)
+	return report
 }
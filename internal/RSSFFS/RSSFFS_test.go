@@ -420,61 +420,60 @@ func TestRSSPatternChecking(t *testing.T) {
 	}
 }
 
-// TestMediumSpecialCase tests the special case handling for medium.com URLs
+// TestMediumSpecialCase tests that medium.com URLs are resolved through the resolver registry
 func TestMediumSpecialCase(t *testing.T) {
 	tests := []struct {
-		name         string
-		domain       string
-		originalURL  string
-		expectedPath string
-		shouldTry    bool
+		name        string
+		domain      string
+		originalURL string
+		expected    []string
 	}{
 		{
-			name:         "Medium.com with username",
-			domain:       "medium.com",
-			originalURL:  "https://medium.com/rokkorxblog",
-			expectedPath: "rokkorxblog",
-			shouldTry:    true,
+			name:        "Medium.com with username",
+			domain:      "medium.com",
+			originalURL: "https://medium.com/rokkorxblog",
+			expected:    []string{"https://medium.com/feed/rokkorxblog"},
 		},
 		{
-			name:         "Medium.com root",
-			domain:       "medium.com",
-			originalURL:  "https://medium.com",
-			expectedPath: "",
-			shouldTry:    false,
+			name:        "Medium.com root",
+			domain:      "medium.com",
+			originalURL: "https://medium.com",
+			expected:    nil,
 		},
 		{
-			name:         "Medium.com with path",
-			domain:       "medium.com",
-			originalURL:  "https://medium.com/tag/technology",
-			expectedPath: "",
-			shouldTry:    false, // has slash, not username
+			name:        "Medium.com with tag path",
+			domain:      "medium.com",
+			originalURL: "https://medium.com/tag/technology",
+			expected:    []string{"https://medium.com/feed/tag/technology"},
 		},
 		{
-			name:         "Other domain",
-			domain:       "example.com",
-			originalURL:  "https://example.com/user",
-			expectedPath: "",
-			shouldTry:    false,
+			name:        "Other domain",
+			domain:      "example.com",
+			originalURL: "https://medium.com/user",
+			expected:    nil,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Test the logic for constructing special URL
-			if tt.domain == "medium.com" && strings.HasPrefix(tt.originalURL, "https://medium.com/") {
-				path := strings.TrimPrefix(tt.originalURL, "https://medium.com/")
-				if path != "" && !strings.Contains(path, "/") {
-					specialURL := "https://medium.com/feed/" + path
-					expected := "https://medium.com/feed/" + tt.expectedPath
-					if specialURL != expected {
-						t.Errorf("Expected special URL %s, got %s", expected, specialURL)
-					}
-				} else if tt.shouldTry {
-					t.Errorf("Expected to try special case for %s", tt.originalURL)
+			target := targetForDomain(tt.domain, tt.originalURL)
+			if target == nil {
+				if tt.expected != nil {
+					t.Errorf("Expected candidates %v for %s, but no target was built", tt.expected, tt.originalURL)
+				}
+				return
+			}
+			// Avoid network access: medium.com is matched by host alone
+			target = newTargetWithHTML(target.URL, nil)
+
+			candidates := resolveCandidates(target)
+			if len(candidates) != len(tt.expected) {
+				t.Fatalf("Expected candidates %v, got %v", tt.expected, candidates)
+			}
+			for i := range candidates {
+				if candidates[i] != tt.expected[i] {
+					t.Errorf("Expected candidate %s, got %s", tt.expected[i], candidates[i])
 				}
-			} else if tt.shouldTry {
-				t.Errorf("Did not expect to try special case for %s", tt.originalURL)
 			}
 		})
 	}
@@ -0,0 +1,392 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Bridge generates feeds for sites that don't publish one, such as a
+// self-hosted RSS-Bridge or RSSHub instance. Bridges are only asked once every
+// other discovery strategy has failed.
+type Bridge interface {
+	// Name identifies the bridge instance in log output and results
+	Name() string
+	// FeedFor returns a feed URL the bridge generates for pageURL, or an empty
+	// string when it can't bridge the page
+	FeedFor(pageURL string) (string, error)
+}
+
+// BridgeFactory creates a Bridge for an instance at baseURL, authenticating with token if set
+type BridgeFactory func(baseURL string, token string) Bridge
+
+var (
+	bridgeKindsMu sync.RWMutex
+	bridgeKinds   = make(map[string]BridgeFactory)
+)
+
+// registerBridgeKind makes a kind of bridge available to ParseBridges
+func registerBridgeKind(kind string, factory BridgeFactory) {
+	bridgeKindsMu.Lock()
+	defer bridgeKindsMu.Unlock()
+	bridgeKinds[kind] = factory
+}
+
+// ParseBridges builds bridges from their configuration, one entry per
+// instance in order of preference. Each entry has the form
+// "kind=baseURL" with an optional ";token=secret", e.g.
+// "rsshub=https://rsshub.example.com;token=secret".
+func ParseBridges(entries []string) ([]Bridge, error) {
+	bridgeKindsMu.RLock()
+	defer bridgeKindsMu.RUnlock()
+
+	var parsed []Bridge
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, ";")
+		kind, baseURL, ok := strings.Cut(fields[0], "=")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		baseURL = strings.TrimSuffix(strings.TrimSpace(baseURL), "/")
+		if !ok || baseURL == "" {
+			return nil, fmt.Errorf("invalid bridge %q (expected kind=baseURL)", entry)
+		}
+		factory, known := bridgeKinds[kind]
+		if !known {
+			return nil, fmt.Errorf("unknown bridge kind %q (must be one of %s)", kind, strings.Join(bridgeKindNames(), ", "))
+		}
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid bridge base URL %q", baseURL)
+		}
+
+		token := ""
+		for _, option := range fields[1:] {
+			key, value, _ := strings.Cut(option, "=")
+			switch strings.TrimSpace(key) {
+			case "token":
+				token = strings.TrimSpace(value)
+			default:
+				return nil, fmt.Errorf("unknown option %q for bridge %q", key, entry)
+			}
+		}
+		parsed = append(parsed, factory(baseURL, token))
+	}
+	return parsed, nil
+}
+
+// bridgeKindNames returns the registered bridge kinds, sorted. Callers must hold bridgeKindsMu.
+func bridgeKindNames() []string {
+	names := make([]string, 0, len(bridgeKinds))
+	for name := range bridgeKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// maxBridgeLookups caps how many pages bridges are asked about during a run,
+// since traversal falls back to them for every linked domain without a feed
+const maxBridgeLookups = 20
+
+// bridgedFeed asks each configured bridge in turn for a feed for pageURL and
+// returns the first one offered that serves a feed, marked with the bridge
+// that generated it. Once maxBridgeLookups pages have been looked up during
+// the run, bridges aren't asked any more.
+func (s *settings) bridgedFeed(pageURL string) *Candidate {
+	if len(s.bridges) == 0 {
+		return nil
+	}
+	if s.bridgeLookups.Add(1) > maxBridgeLookups {
+		log.Debugf("Not asking bridges for a feed for %s, having asked about %d pages already", pageURL, maxBridgeLookups)
+		return nil
+	}
+
+	client := newBridgeClient()
+	for _, bridge := range s.bridges {
+		feed, err := bridge.FeedFor(pageURL)
+		if err != nil {
+			log.Debugf("Bridge %s could not check %s: %v", bridge.Name(), pageURL, err)
+			continue
+		}
+		if feed == "" {
+			continue
+		}
+		if !servesFeed(client, feed) {
+			log.Debugf("Bridge %s offered %s for %s, which doesn't serve a feed", bridge.Name(), feed, pageURL)
+			continue
+		}
+		log.Debugf("Bridge %s generates feed %s for %s", bridge.Name(), feed, pageURL)
+		return &Candidate{URL: feed, Bridge: bridge.Name()}
+	}
+	return nil
+}
+
+// newBridgeClient returns the HTTP client used to talk to bridge instances.
+// Bridge base URLs come from configuration, so unlike page fetches they may be
+// on the local network.
+func newBridgeClient() *http.Client {
+	return &http.Client{
+		Timeout: time.Second * timeoutSeconds,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// withToken adds a query parameter carrying an auth token to feedURL, unless token is empty
+func withToken(feedURL string, param string, token string) string {
+	if token == "" {
+		return feedURL
+	}
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return feedURL
+	}
+	query := u.Query()
+	if query.Get(param) == "" {
+		query.Set(param, token)
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// rssBridge asks an RSS-Bridge instance's detect action which of its bridges
+// can generate a feed for a page
+type rssBridge struct {
+	baseURL string
+	token   string
+}
+
+// rssHub matches pages against an RSSHub instance's radar rules and builds
+// the route of the first rule that matches
+type rssHub struct {
+	baseURL string
+	token   string
+
+	once  sync.Once
+	rules map[string]map[string]json.RawMessage
+	err   error
+}
+
+// radarRule is one RSSHub radar rule: the page paths it applies to and the route it maps them to
+type radarRule struct {
+	Source json.RawMessage `json:"source"`
+	Target json.RawMessage `json:"target"`
+}
+
+func init() {
+	registerBridgeKind("rss-bridge", func(baseURL string, token string) Bridge {
+		return &rssBridge{baseURL: baseURL, token: token}
+	})
+	registerBridgeKind("rsshub", func(baseURL string, token string) Bridge {
+		return &rssHub{baseURL: baseURL, token: token}
+	})
+}
+
+func (b *rssBridge) Name() string { return "rss-bridge" }
+
+// FeedFor relies on RSS-Bridge redirecting detect requests to the display
+// action of the matching bridge, and answering with an error when none match
+func (b *rssBridge) FeedFor(pageURL string) (string, error) {
+	query := url.Values{"action": {"detect"}, "format": {"Atom"}, "url": {pageURL}}
+	if b.token != "" {
+		query.Set("token", b.token)
+	}
+	detectURL := b.baseURL + "/?" + query.Encode()
+
+	resp, err := newBridgeClient().Get(detectURL) // #nosec G704 -- bridge base URL is from config
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		// No bridge matched the page
+		return "", nil
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return "", err
+	}
+	return withToken(location.String(), "token", b.token), nil
+}
+
+func (h *rssHub) Name() string { return "rsshub" }
+
+func (h *rssHub) FeedFor(pageURL string) (string, error) {
+	h.once.Do(h.loadRules)
+	if h.err != nil {
+		return "", h.err
+	}
+
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	route := matchRadarRules(h.rules, u)
+	if route == "" {
+		return "", nil
+	}
+	return withToken(h.baseURL+route, "key", h.token), nil
+}
+
+// loadRules fetches the instance's radar rules once
+func (h *rssHub) loadRules() {
+	resp, err := newBridgeClient().Get(withToken(h.baseURL+"/api/radar/rules", "key", h.token)) // #nosec G704 -- bridge base URL is from config
+	if err != nil {
+		h.err = err
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		h.err = fmt.Errorf("unexpected status code %d fetching RSSHub radar rules", resp.StatusCode)
+		return
+	}
+	h.err = json.NewDecoder(io.LimitReader(resp.Body, maxPageBytes)).Decode(&h.rules)
+}
+
+// matchRadarRules returns the RSSHub route for the first radar rule matching u,
+// or an empty string. Rules are keyed by domain and then by subdomain, with "."
+// standing for the domain itself.
+func matchRadarRules(rules map[string]map[string]json.RawMessage, u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+
+	// Check the most specific matching domain first, for a stable order
+	var domains []string
+	for domain := range rules {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return len(domains[i]) > len(domains[j]) })
+
+	for _, domain := range domains {
+		sub := "."
+		if host != domain {
+			sub = strings.TrimSuffix(host, "."+domain)
+		}
+		subdomains := rules[domain]
+
+		keys := []string{sub}
+		if sub == "www" {
+			keys = append(keys, ".")
+		}
+		for _, key := range keys {
+			var domainRules []radarRule
+			if err := json.Unmarshal(subdomains[key], &domainRules); err != nil {
+				continue
+			}
+			for _, rule := range domainRules {
+				if route := rule.route(u.Path); route != "" {
+					return route
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// route returns the rule's target route with the parameters captured from
+// path substituted, or an empty string when none of its sources match
+func (r radarRule) route(path string) string {
+	// Targets given as JavaScript functions in RSSHub don't survive JSON encoding
+	var target string
+	if err := json.Unmarshal(r.Target, &target); err != nil || target == "" {
+		return ""
+	}
+
+	var sources []string
+	if err := json.Unmarshal(r.Source, &sources); err != nil {
+		var source string
+		if err := json.Unmarshal(r.Source, &source); err != nil {
+			return ""
+		}
+		sources = []string{source}
+	}
+
+	for _, source := range sources {
+		if params, ok := matchRadarSource(source, path); ok {
+			return expandRadarTarget(target, params)
+		}
+	}
+	return ""
+}
+
+// matchRadarSource matches a path against a radar source pattern such as
+// "/:user/:repo/releases", where ":name" captures a segment, ":name?" is
+// optional and "*" matches the rest of the path
+func matchRadarSource(pattern string, path string) (map[string]string, bool) {
+	patternSegments := escapedSegments(pattern)
+	pathSegments := escapedSegments(path)
+	params := make(map[string]string)
+
+	i := 0
+	for _, segment := range patternSegments {
+		switch {
+		case segment == "*":
+			return params, true
+		case strings.HasPrefix(segment, ":"):
+			name := strings.TrimPrefix(segment, ":")
+			optional := strings.HasSuffix(name, "?")
+			name = strings.TrimSuffix(name, "?")
+			if i >= len(pathSegments) {
+				if optional {
+					continue
+				}
+				return nil, false
+			}
+			params[name] = pathSegments[i]
+			i++
+		default:
+			if i >= len(pathSegments) || segment != pathSegments[i] {
+				return nil, false
+			}
+			i++
+		}
+	}
+	if i != len(pathSegments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// expandRadarTarget substitutes captured parameters into a radar target route,
+// dropping optional parameters that weren't captured
+func expandRadarTarget(target string, params map[string]string) string {
+	var segments []string
+	for _, segment := range escapedSegments(target) {
+		if strings.HasPrefix(segment, ":") {
+			name := strings.TrimSuffix(strings.TrimPrefix(segment, ":"), "?")
+			value, ok := params[name]
+			if !ok {
+				if strings.HasSuffix(segment, "?") {
+					continue
+				}
+				return ""
+			}
+			segment = value
+		}
+		segments = append(segments, segment)
+	}
+	return "/" + strings.Join(segments, "/")
+}
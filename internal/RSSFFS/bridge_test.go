@@ -0,0 +1,238 @@
+package RSSFFS
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// radarRulesFixture is a trimmed copy of an RSSHub /api/radar/rules response
+const radarRulesFixture = `{
+	"github.com": {
+		"_name": "GitHub",
+		".": [
+			{"title": "Repo Issues", "source": ["/:user/:repo/issues", "/:user/:repo/issues/:id", "/:user/:repo"], "target": "/github/issue/:user/:repo"},
+			{"title": "User Repos", "source": "/:user", "target": "/github/repos/:user"}
+		],
+		"gist": [
+			{"title": "Gist Commits", "source": ["/:owner/:gistId/revisions"], "target": "/github/gist/:gistId"}
+		]
+	},
+	"example.com": {
+		"_name": "Example",
+		".": [
+			{"title": "Tagged", "source": ["/tags/:tag/:page?"], "target": "/example/tag/:tag/:page?"},
+			{"title": "Computed target", "source": ["/computed"]}
+		]
+	}
+}`
+
+// TestParseBridges tests bridge configuration parsing, ordering and validation
+func TestParseBridges(t *testing.T) {
+	parsed, err := ParseBridges([]string{
+		" rsshub=https://rsshub.example.com/;token=secret ",
+		"",
+		"rss-bridge=http://bridge.lan:8080",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(parsed) != 2 {
+		t.Fatalf("Expected 2 bridges, got %d", len(parsed))
+	}
+	hub, ok := parsed[0].(*rssHub)
+	if !ok || hub.baseURL != "https://rsshub.example.com" || hub.token != "secret" {
+		t.Errorf("Expected first bridge to be RSSHub with token, got %#v", parsed[0])
+	}
+	if bridge, ok := parsed[1].(*rssBridge); !ok || bridge.baseURL != "http://bridge.lan:8080" || bridge.token != "" {
+		t.Errorf("Expected second bridge to be RSS-Bridge without token, got %#v", parsed[1])
+	}
+
+	for _, invalid := range []string{
+		"rsshub",
+		"feedburner=https://example.com",
+		"rsshub=ftp://example.com",
+		"rsshub=https://example.com;priority=1",
+	} {
+		if _, err := ParseBridges([]string{invalid}); err == nil {
+			t.Errorf("Expected error for bridge %q, got none", invalid)
+		}
+	}
+}
+
+// TestRSSBridgeDetect tests following RSS-Bridge's detect redirect to a bridged feed
+func TestRSSBridgeDetect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("action") != "detect" || query.Get("token") != "secret" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if query.Get("url") != "https://social.example.com/alice" {
+			http.Error(w, "No bridge found for given URL", http.StatusNotFound)
+			return
+		}
+		http.Redirect(w, r, "/?action=display&bridge=SocialBridge&u=alice&format=Atom", http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	bridge := &rssBridge{baseURL: server.URL, token: "secret"}
+	feed, err := bridge.FeedFor("https://social.example.com/alice")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := server.URL + "/?action=display&bridge=SocialBridge&format=Atom&token=secret&u=alice"
+	if feed != expected {
+		t.Errorf("Expected %q, got %q", expected, feed)
+	}
+
+	feed, err = bridge.FeedFor("https://unsupported.example.com/")
+	if err != nil || feed != "" {
+		t.Errorf("Expected no feed and no error for unsupported page, got %q, %v", feed, err)
+	}
+}
+
+// TestRSSHubRadar tests matching pages against RSSHub radar rules
+func TestRSSHubRadar(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/radar/rules" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(radarRulesFixture))
+	}))
+	defer server.Close()
+
+	hub := &rssHub{baseURL: server.URL, token: "k"}
+	tests := []struct {
+		pageURL  string
+		expected string
+	}{
+		{"https://github.com/alice/widget/issues", server.URL + "/github/issue/alice/widget?key=k"},
+		{"https://www.github.com/alice/widget", server.URL + "/github/issue/alice/widget?key=k"},
+		{"https://github.com/alice", server.URL + "/github/repos/alice?key=k"},
+		{"https://gist.github.com/alice/abc123/revisions", server.URL + "/github/gist/abc123?key=k"},
+		{"https://example.com/tags/go", server.URL + "/example/tag/go?key=k"},
+		{"https://example.com/tags/go/2", server.URL + "/example/tag/go/2?key=k"},
+		{"https://example.com/computed", ""},
+		{"https://github.com/alice/widget/pulls/1/files", ""},
+		{"https://unknown.example.org/", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pageURL, func(t *testing.T) {
+			feed, err := hub.FeedFor(tt.pageURL)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if feed != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, feed)
+			}
+		})
+	}
+	if requests != 1 {
+		t.Errorf("Expected radar rules to be fetched once, got %d requests", requests)
+	}
+}
+
+// TestBridgedFeedOrdering tests that bridges are asked in order and the first
+// offer that serves a feed wins, and that bridges are only asked about so many
+// pages during a run
+func TestBridgedFeedOrdering(t *testing.T) {
+	serveFeed := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		_, _ = w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+	}
+	hubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/radar/rules":
+			_, _ = w.Write([]byte(radarRulesFixture))
+		case "/github/repos/alice":
+			serveFeed(w)
+		default:
+			http.Error(w, "route not found", http.StatusServiceUnavailable)
+		}
+	}))
+	defer hubServer.Close()
+	bridgeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("action") == "display" {
+			serveFeed(w)
+			return
+		}
+		http.Redirect(w, r, "/?action=display&bridge=AnyBridge", http.StatusMovedPermanently)
+	}))
+	defer bridgeServer.Close()
+
+	s := &settings{bridges: []Bridge{&rssHub{baseURL: hubServer.URL}, &rssBridge{baseURL: bridgeServer.URL}}}
+
+	got := s.bridgedFeed("https://github.com/alice")
+	expected := &Candidate{URL: hubServer.URL + "/github/repos/alice", Bridge: "rsshub"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v from the preferred bridge, got %+v", expected, got)
+	}
+
+	got = s.bridgedFeed("https://unknown.example.org/")
+	if got == nil || got.Bridge != "rss-bridge" {
+		t.Errorf("Expected fallback to RSS-Bridge, got %+v", got)
+	}
+
+	// RSSHub offers a route for the issues page, but it doesn't serve a feed
+	got = s.bridgedFeed("https://github.com/alice/widget/issues")
+	if got == nil || got.Bridge != "rss-bridge" {
+		t.Errorf("Expected fallback to RSS-Bridge for a route that doesn't serve a feed, got %+v", got)
+	}
+
+	s.bridgeLookups.Store(maxBridgeLookups)
+	if got := s.bridgedFeed("https://github.com/alice"); got != nil {
+		t.Errorf("Expected no feed once bridges were asked about %d pages, got %+v", maxBridgeLookups, got)
+	}
+
+	if got := (&settings{}).bridgedFeed("https://github.com/alice"); got != nil {
+		t.Errorf("Expected no feed without bridges, got %+v", got)
+	}
+}
+
+// TestMatchRadarSource tests radar source pattern matching
+func TestMatchRadarSource(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected map[string]string
+		ok       bool
+	}{
+		{"/:user/:repo", "/alice/widget", map[string]string{"user": "alice", "repo": "widget"}, true},
+		{"/:user/:repo", "/alice/widget/", map[string]string{"user": "alice", "repo": "widget"}, true},
+		{"/:user/:repo", "/alice", nil, false},
+		{"/:user/:repo?", "/alice", map[string]string{"user": "alice"}, true},
+		{"/blog/*", "/blog/2024/05/post", map[string]string{}, true},
+		{"/blog", "/news", nil, false},
+		{"/", "/", map[string]string{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			params, ok := matchRadarSource(tt.pattern, tt.path)
+			if ok != tt.ok {
+				t.Fatalf("Expected match %t, got %t", tt.ok, ok)
+			}
+			if ok && !reflect.DeepEqual(params, tt.expected) {
+				t.Errorf("Expected params %v, got %v", tt.expected, params)
+			}
+		})
+	}
+}
+
+// TestWithToken tests adding auth tokens to bridged feed URLs
+func TestWithToken(t *testing.T) {
+	if got := withToken("https://rsshub.example.com/a", "key", ""); got != "https://rsshub.example.com/a" {
+		t.Errorf("Expected URL unchanged without token, got %q", got)
+	}
+	got := withToken("https://rsshub.example.com/a?limit=5", "key", "s3cret")
+	u, _ := url.Parse(got)
+	if u.Query().Get("key") != "s3cret" || u.Query().Get("limit") != "5" {
+		t.Errorf("Expected key and existing query preserved, got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxCategorySuggestions caps how many similarly named categories are suggested
+const maxCategorySuggestions = 3
+
+// CategoryNotFoundError is returned when no category in the RSS reader has
+// the given name. Matches lists existing categories with similar names.
+type CategoryNotFoundError struct {
+	Name    string
+	Matches []string
+}
+
+// Error describes the missing category along with any close matches
+func (e *CategoryNotFoundError) Error() string {
+	message := fmt.Sprintf("category %q does not exist in the RSS reader", e.Name)
+	if len(e.Matches) > 0 {
+		quoted := make([]string, len(e.Matches))
+		for i, match := range e.Matches {
+			quoted[i] = fmt.Sprintf("%q", match)
+		}
+		message += " (did you mean " + strings.Join(quoted, ", ") + "?)"
+	}
+	return message
+}
+
+// closeCategoryNames returns the titles of the categories whose names are
+// close to name: within a few typos of it, or containing it or contained in
+// it, ignoring case. The closest come first.
+func closeCategoryNames(name string, categories []Category) []string {
+	target := strings.ToLower(strings.TrimSpace(name))
+	maxDistance := max(2, len([]rune(target))/3)
+
+	type match struct {
+		title    string
+		distance int
+	}
+	var matches []match
+	for _, category := range categories {
+		title := strings.ToLower(category.Title)
+		distance := editDistance(target, title)
+		if distance > maxDistance && !strings.Contains(title, target) && !strings.Contains(target, title) {
+			continue
+		}
+		matches = append(matches, match{category.Title, distance})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].title < matches[j].title
+	})
+
+	var titles []string
+	for _, m := range matches[:min(len(matches), maxCategorySuggestions)] {
+		titles = append(titles, m.title)
+	}
+	return titles
+}
+
+// editDistance returns the Levenshtein distance between a and b, counting runes
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
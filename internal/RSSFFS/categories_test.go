@@ -0,0 +1,118 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestCloseCategoryNames tests suggesting existing categories close to a missing one
+func TestCloseCategoryNames(t *testing.T) {
+	categories := []Category{{ID: 1, Title: "Found"}, {ID: 2, Title: "Link Blogs"}, {ID: 3, Title: "Podcasts"}, {ID: 4, Title: "Tech"}}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Link Blog", []string{"Link Blogs"}},
+		{"podcast", []string{"Podcasts"}},
+		{"Fuond", []string{"Found"}},
+		{"Tec", []string{"Tech"}},
+		{"Gardening", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closeCategoryNames(tt.name, categories); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("closeCategoryNames(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEditDistance tests the Levenshtein distance between category names
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"tech", "", 4},
+		{"tech", "tech", 0},
+		{"found", "fuond", 2},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestCategoryNotFoundError tests the message naming the missing category and close matches
+func TestCategoryNotFoundError(t *testing.T) {
+	err := &CategoryNotFoundError{Name: "Link Blog", Matches: []string{"Link Blogs", "Blogs"}}
+	want := `category "Link Blog" does not exist in the RSS reader (did you mean "Link Blogs", "Blogs"?)`
+	if err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	err = &CategoryNotFoundError{Name: "Gardening"}
+	want = `category "Gardening" does not exist in the RSS reader`
+	if err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
+
+// TestResolveCategoryID tests looking up categories, failing with suggestions
+// when one is missing and creating it only when asked to
+func TestResolveCategoryID(t *testing.T) {
+	withoutRateLimit(t)
+	var created []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/categories" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var body struct {
+				Title string `json:"title"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body.Title)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"id": 9, "title": %q}`, body.Title)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[{"id": 1, "title": "Found"}, {"id": 7, "title": "Link Blogs"}]`)
+	}))
+	defer server.Close()
+	client := NewReaderClient(server.URL, "key")
+
+	if id, err := client.ResolveCategoryID("link blogs", false); err != nil || id != 7 {
+		t.Errorf("Expected existing category 7, got %d, %v", id, err)
+	}
+	if id, err := client.ResolveCategoryID("", true); err != nil || id != 0 {
+		t.Errorf("Expected no category for an empty name, got %d, %v", id, err)
+	}
+
+	_, err := client.ResolveCategoryID("Link Blog", false)
+	var notFound *CategoryNotFoundError
+	if !errors.As(err, &notFound) || !reflect.DeepEqual(notFound.Matches, []string{"Link Blogs"}) {
+		t.Errorf("Expected a CategoryNotFoundError suggesting Link Blogs, got %v", err)
+	}
+	if len(created) != 0 {
+		t.Errorf("Expected no category to be created without create, got %v", created)
+	}
+
+	if id, err := client.ResolveCategoryID("Gardening", true); err != nil || id != 9 {
+		t.Errorf("Expected created category 9, got %d, %v", id, err)
+	}
+	if !reflect.DeepEqual(created, []string{"Gardening"}) {
+		t.Errorf("Expected Gardening to be created, got %v", created)
+	}
+}
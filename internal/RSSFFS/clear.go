@@ -0,0 +1,146 @@
+package RSSFFS
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ClearMode says what a run does with the feeds already in its category
+type ClearMode string
+
+const (
+	// ClearNone keeps the feeds already in the category
+	ClearNone ClearMode = ""
+	// ClearBefore unsubscribes from every feed in the category before discovery
+	ClearBefore ClearMode = "before"
+	// ClearReplace unsubscribes from the feeds in the category that the run
+	// didn't find again, once it has subscribed to what it found. Feeds found
+	// again are kept even when rules or the feed filter skip them.
+	ClearReplace ClearMode = "replace"
+)
+
+// backupFileName returns the name of a backup of category taken at now,
+// e.g. "link-blogs-20240131-154500.opml"
+func backupFileName(category string, now time.Time) string {
+	slug := strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, category), "-")
+	if slug == "" {
+		slug = "category"
+	}
+	return slug + "-" + now.Format("20060102-150405") + ".opml"
+}
+
+// backupCategory writes the feeds in category to a timestamped OPML file in
+// dir, returning its path
+func backupCategory(dir string, category string, feeds []Feed) (string, error) {
+	if dir == "" {
+		return "", errors.New("no backup directory is configured (set RSSFFS_BACKUP_DIR)")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("error creating backup directory %s: %w", dir, err)
+	}
+	now := time.Now()
+	path := filepath.Join(dir, backupFileName(category, now))
+	file, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("error writing backup %s: %w", path, err)
+	}
+	title := fmt.Sprintf("RSSFFS backup of category %s, %s", category, now.Format(time.RFC3339))
+	if err := writeOPML(file, title, category, feeds); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("error writing backup %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("error writing backup %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// clearFeeds unsubscribes from feeds in category, after backing the whole
+// category (all of categoryFeeds) up, and records what it did in report. In
+// debug mode nothing is backed up or unsubscribed from, and the feeds that
+// would have been are recorded instead.
+func (s *settings) clearFeeds(report *Report, category string, categoryFeeds []Feed, feeds []Feed, debug bool) error {
+	if len(feeds) == 0 {
+		return nil
+	}
+	if debug {
+		log.Infof("Would unsubscribe from %d feeds in category %s", len(feeds), category)
+		report.Removed = feeds
+		return nil
+	}
+
+	// Without a backup there is no way back, so don't delete anything
+	backup, err := backupCategory(s.backupDir, category, categoryFeeds)
+	if err != nil {
+		return fmt.Errorf("not unsubscribing from the feeds in category %s: %w", category, err)
+	}
+	report.Backup = backup
+	log.Infof("Backed up the %d feeds in category %s to %s", len(categoryFeeds), category, backup)
+
+	for _, feed := range feeds {
+		log.Debug("Deleting feedId ", feed.ID)
+		if err := s.reader.DeleteFeed(feed.ID); err != nil {
+			log.Errorf("Error deleting feedId %d: %v\n ", feed.ID, err)
+			continue
+		}
+		report.Removed = append(report.Removed, feed)
+	}
+	return nil
+}
+
+// replacedFeeds returns the feeds in a category that a run replaces: those
+// it didn't find again, by feed URL or by the site the feed links to. Feeds
+// found again are kept even when a rule or the feed filter skipped them, since
+// the run only leaves out feeds it isn't already subscribed to. Nothing is
+// replaced unless the run found a feed to subscribe to or keep and every
+// subscription succeeded, so that a run that fails or finds nothing leaves
+// the category as it was.
+func replacedFeeds(feeds []Feed, report *Report) []Feed {
+	foundURLs := make(map[string]bool)
+	foundSites := make(map[string]bool)
+	kept := 0
+	for _, result := range report.Results {
+		if result.Error != "" {
+			log.Warnf("Not replacing any feeds, since subscribing to %s failed", result.URL)
+			return nil
+		}
+		if result.Subscribed || result.AlreadySubscribed() {
+			kept++
+		}
+		foundURLs[canonicalURL(result.URL)] = true
+		if result.SubscribedAs != "" {
+			foundURLs[canonicalURL(result.SubscribedAs)] = true
+		}
+		if result.Info != nil {
+			if site := canonicalURL(result.Info.Link); site != "" {
+				foundSites[site] = true
+			}
+		}
+	}
+	if kept == 0 {
+		log.Warn("Not replacing any feeds, since no feeds were found")
+		return nil
+	}
+
+	var replaced []Feed
+	for _, feed := range feeds {
+		if foundURLs[canonicalURL(feed.FeedURL)] || (feed.SiteURL != "" && foundSites[canonicalURL(feed.SiteURL)]) {
+			log.Debugf("Keeping RSS feed %s, which was found again", feed.FeedURL)
+			continue
+		}
+		replaced = append(replaced, feed)
+	}
+	return replaced
+}
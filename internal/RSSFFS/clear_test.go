@@ -0,0 +1,154 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// TestBackupCategory tests that a category is backed up to a timestamped
+// OPML file listing its feeds
+func TestBackupCategory(t *testing.T) {
+	if name := backupFileName("Link Blogs / Été", time.Date(2024, 1, 31, 15, 45, 0, 0, time.UTC)); name != "link-blogs---été-20240131-154500.opml" {
+		t.Errorf("Unexpected backup file name %s", name)
+	}
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	feeds := []Feed{
+		{ID: 3, Title: "Alice", FeedURL: "https://alice.example.org/feed.xml", SiteURL: "https://alice.example.org/"},
+		{ID: 4, FeedURL: "https://bob.example.net/rss?a=1&b=2"},
+	}
+	path, err := backupCategory(dir, "Link Blogs", feeds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), "link-blogs-") {
+		t.Errorf("Expected a backup named after the category in %s, got %s", dir, path)
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	urls, err := parseOPML(file)
+	if err != nil {
+		t.Fatalf("Expected the backup to be valid OPML: %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://alice.example.org/feed.xml", "https://bob.example.net/rss?a=1&b=2"}) {
+		t.Errorf("Expected the backup to list both feeds, got %v", urls)
+	}
+
+	if _, err := backupCategory("", "Link Blogs", feeds); err == nil {
+		t.Error("Expected an error without a backup directory")
+	}
+}
+
+// TestReplacedFeeds tests that only feeds not found again are replaced, and
+// only when the run went well
+func TestReplacedFeeds(t *testing.T) {
+	feeds := []Feed{
+		{ID: 3, FeedURL: "https://alice.example.org/feed.xml"},
+		{ID: 4, FeedURL: "https://bob.example.net/rss"},
+		{ID: 5, FeedURL: "https://carol.example.com/atom.xml", SiteURL: "https://carol.example.com"},
+	}
+	found := func(url string) Result { return Result{Candidate: Candidate{URL: url}, Subscribed: true} }
+
+	tests := []struct {
+		name    string
+		results []Result
+		want    []int
+	}{
+		{"feeds found again are kept", []Result{
+			found("http://www.alice.example.org/feed.xml/"),
+			{Candidate: Candidate{URL: "https://bob.example.net/feed"}, Subscription: SubscriptionOtherURL, SubscribedAs: "https://bob.example.net/rss", Subscribed: true},
+			found("https://dave.example.org/feed"),
+		}, []int{5}},
+		{"feeds found again but skipped are kept", []Result{
+			found("https://dave.example.org/feed"),
+			{Candidate: Candidate{URL: "https://alice.example.org/feed.xml"}, Skipped: "too old"},
+			{Candidate: Candidate{URL: "https://carol.example.com/feed", Info: &FeedInfo{Link: "https://carol.example.com/"}}, Skipped: "rejected by rule old"},
+		}, []int{4}},
+		{"only skipped feeds", []Result{{Candidate: Candidate{URL: "https://alice.example.org/feed.xml"}, Skipped: "too old"}}, nil},
+		{"nothing found", nil, nil},
+		{"a failed subscription", []Result{found("https://dave.example.org/feed"), {Candidate: Candidate{URL: "https://erin.example.org/feed"}, Error: "unable to fetch feed"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feedIDs(replacedFeeds(feeds, &Report{Results: tt.results})); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected feeds %v replaced, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestRunReportClearsCategory tests that clearing a category backs it up
+// before unsubscribing, that dry runs, replacements that find nothing and
+// bad input unsubscribe from nothing, and that clearing needs a category
+func TestRunReportClearsCategory(t *testing.T) {
+	withoutRateLimit(t)
+
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/categories":
+			_, _ = fmt.Fprint(w, `[{"id": 7, "title": "Link Blogs"}]`)
+		case r.URL.Path == "/v1/categories/7/feeds":
+			_, _ = fmt.Fprint(w, `[{"id": 3, "feed_url": "https://alice.example.org/feed.xml"}, {"id": 4, "feed_url": "https://bob.example.net/rss"}]`)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	conf := config.Config{RSSReaderEndpoint: server.URL, BackupDir: dir}
+	// Private addresses are refused, so discovery finds nothing without any network access
+	const unreachable = "http://192.168.0.1/"
+
+	if _, err := RunReport(unreachable, "", false, ClearBefore, true, conf); err == nil {
+		t.Error("Expected an error clearing without a category")
+	}
+
+	report, err := RunReport(unreachable, "Link Blogs", true, ClearBefore, true, conf)
+	if err != nil || len(deleted) != 0 || report.Backup != "" {
+		t.Errorf("Expected a dry run neither to back up nor to unsubscribe, got %v, %+v, %v", deleted, report, err)
+	}
+	if err == nil && !reflect.DeepEqual(feedIDs(report.Removed), []int{3, 4}) {
+		t.Errorf("Expected a dry run to list both feeds, got %v", feedIDs(report.Removed))
+	}
+
+	if report, err := RunReport(unreachable, "Link Blogs", false, ClearReplace, true, conf); err != nil || len(deleted) != 0 || report.Backup != "" {
+		t.Errorf("Expected a run that finds nothing to replace nothing, got %v, %+v, %v", deleted, report, err)
+	}
+
+	// Input that can't be used is turned down before the category is cleared
+	for _, input := range []string{"@alice@social.invalid", "http://"} {
+		if _, err := RunReport(input, "Link Blogs", false, ClearBefore, false, conf); err == nil || len(deleted) != 0 {
+			t.Errorf("Expected %s to be turned down before clearing, got %v, %v", input, deleted, err)
+		}
+	}
+
+	report, err = RunReport(unreachable, "Link Blogs", false, ClearBefore, true, conf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"/v1/feeds/3", "/v1/feeds/4"}) || !reflect.DeepEqual(feedIDs(report.Removed), []int{3, 4}) {
+		t.Errorf("Expected both feeds to be unsubscribed from, got %v, %v", deleted, feedIDs(report.Removed))
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, "link-blogs-*.opml"))
+	if len(backups) != 1 || report.Backup != backups[0] {
+		t.Errorf("Expected one backup, reported as %s, got %v", report.Backup, backups)
+	}
+}
@@ -0,0 +1,250 @@
+package RSSFFS
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// defaultQueueMinMentions is how many pushed entries must link to a site for
+// a DiscoveryQueue to look for its feeds when no threshold is configured
+const defaultQueueMinMentions = 2
+
+// discoveryWindow is how long a DiscoveryQueue remembers a site for: the
+// entries linking to a site count towards queueing it for that long after it
+// was first linked, and a site looked at, or the site of a feed entries came
+// from, isn't looked at again until that long after
+const discoveryWindow = 30 * 24 * time.Hour
+
+// DiscoveryQueue grows the reading list from entries the RSS reader pushes as
+// they arrive. It counts the sites each entry links to, and once a site has
+// been linked from enough entries looks for its feeds in the background, each
+// site at most once every discoveryWindow. Feeds found are subscribed to in
+// the configured webhook category, or the podcast category for podcasts, or
+// kept for review when there is no webhook category.
+type DiscoveryQueue struct {
+	mu        sync.Mutex
+	conf      config.Config
+	threshold int
+	mentions  *domainMentions
+	// firstMentioned is when each site counted in mentions was first linked
+	firstMentioned map[string]time.Time
+	// queued is when each site was queued, or last seen as the site of a feed
+	// entries came from
+	queued  map[string]time.Time
+	pending []pendingSite
+	review  []Result
+	wake    chan struct{}
+
+	// discover finds the feeds for a domain from the URLs linked on it
+	discover func(s *settings, domain string, links []string) []Candidate
+}
+
+// pendingSite is a site queued for discovery, with the URLs linked on it and
+// the number of entries that linked to it
+type pendingSite struct {
+	domain string
+	links  []string
+	count  int
+}
+
+// NewDiscoveryQueue creates a queue that uses the run settings in conf,
+// checking sites linked from at least conf.MinMentions entries (2 by default)
+// and subscribing to their feeds in conf.WebhookCategory when set
+func NewDiscoveryQueue(conf config.Config) *DiscoveryQueue {
+	threshold := conf.MinMentions
+	if threshold <= 0 {
+		threshold = defaultQueueMinMentions
+	}
+	return &DiscoveryQueue{
+		conf:           conf,
+		threshold:      threshold,
+		mentions:       newDomainMentions(),
+		firstMentioned: make(map[string]time.Time),
+		queued:         make(map[string]time.Time),
+		wake:           make(chan struct{}, 1),
+		discover: func(s *settings, domain string, links []string) []Candidate {
+			return s.findLinkedRSSFeeds(domain, links, "")
+		},
+	}
+}
+
+// AddEntries counts the sites linked from new entries' links and content,
+// leaving out the sites of the feeds they came from, and queues those that
+// have now been linked from enough entries. Once a site is queued or left out
+// its mentions are forgotten, as are those of sites not linked from enough
+// entries within discoveryWindow. It returns the number of sites queued.
+func (q *DiscoveryQueue) AddEntries(entries []Entry) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range entries {
+		q.mentions.addDocument(entry.Content, entry.URL)
+		// Sites of subscribed feeds are never worth checking
+		for _, domain := range hostnames(entry.Feed.SiteURL, entry.Feed.FeedURL) {
+			q.queued[domain] = now
+		}
+	}
+	q.expire(now)
+
+	queued := 0
+	for domain, links := range q.mentions.above(q.threshold) {
+		if _, ok := q.queued[domain]; !ok {
+			q.queued[domain] = now
+			q.pending = append(q.pending, pendingSite{domain: domain, links: links, count: q.mentions.Counts[domain]})
+			queued++
+		}
+	}
+	for domain := range q.mentions.Counts {
+		if _, ok := q.queued[domain]; ok {
+			q.forget(domain)
+		} else if _, ok := q.firstMentioned[domain]; !ok {
+			q.firstMentioned[domain] = now
+		}
+	}
+
+	if queued > 0 {
+		log.Infof("Discovery queue: Queued %d sites linked from at least %d entries", queued, q.threshold)
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return queued
+}
+
+// expire forgets the mentions of sites first linked more than discoveryWindow
+// ago, and lets sites queued that long ago be queued again; q.mu must be held
+func (q *DiscoveryQueue) expire(now time.Time) {
+	for domain, at := range q.firstMentioned {
+		if now.Sub(at) > discoveryWindow {
+			q.forget(domain)
+		}
+	}
+	for domain, at := range q.queued {
+		if now.Sub(at) > discoveryWindow {
+			delete(q.queued, domain)
+		}
+	}
+}
+
+// forget drops what is known of the entries linking to a site; q.mu must be held
+func (q *DiscoveryQueue) forget(domain string) {
+	q.mentions.forget(domain)
+	delete(q.firstMentioned, domain)
+}
+
+// Run looks for feeds on queued sites as they are queued, until ctx is done
+func (q *DiscoveryQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+			q.processPending()
+		}
+	}
+}
+
+// processPending looks for feeds on every queued site, subscribing to them or
+// keeping them for review
+func (q *DiscoveryQueue) processPending() {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	// Settings are built for each batch, so that the queue shares nothing
+	// with runs started by the web server meanwhile
+	s, err := newSettings(q.conf)
+	if err != nil {
+		log.Errorf("Discovery queue: Invalid configuration: %v", err)
+		return
+	}
+
+	var candidates []Candidate
+	for _, site := range pending {
+		for _, candidate := range q.discover(s, site.domain, site.links) {
+			candidate.Mentions = site.count
+			candidate.Source = fmt.Sprintf("linked from %d new entries", site.count)
+			candidates = append(candidates, candidate)
+		}
+	}
+	log.Infof("Discovery queue: Found %d feeds on %d sites", len(candidates), len(pending))
+	if len(candidates) == 0 {
+		return
+	}
+
+	if q.conf.WebhookCategory != "" {
+		categoryId, err := s.reader.ResolveCategoryID(q.conf.WebhookCategory, s.createCategories)
+		if err != nil {
+			log.Errorf("Discovery queue: Error getting categoryId from category %s: %v", q.conf.WebhookCategory, err)
+			return
+		}
+		if err := s.resolvePodcastCategory(q.conf.PodcastCategory); err != nil {
+			log.Errorf("Discovery queue: %v", err)
+			return
+		}
+		s.subscribeCandidates(candidates, "", categoryId, false, "Discovery queue")
+		return
+	}
+
+	now := time.Now()
+	inspectFeeds(candidates)
+	for _, candidate := range candidates {
+		result := s.evaluateCandidate(candidate, "", now)
+		if result.Skipped != "" {
+			log.Infof("Discovery queue: Skipping RSS feed %s: %s", candidate.URL, result.Skipped)
+			continue
+		}
+		q.AddReview(result)
+	}
+}
+
+// AddReview keeps a feed for review, unless it is already awaiting review
+func (q *DiscoveryQueue) AddReview(result Result) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.find(result.URL); !ok {
+		q.review = append(q.review, result)
+	}
+}
+
+// Review returns the feeds found that are awaiting review, oldest first
+func (q *DiscoveryQueue) Review() []Result {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Result(nil), q.review...)
+}
+
+// Take removes a feed from the review queue once it has been subscribed to or
+// dismissed, reporting whether it was there
+func (q *DiscoveryQueue) Take(feedURL string) (Result, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i, ok := q.find(feedURL)
+	if !ok {
+		return Result{}, false
+	}
+	result := q.review[i]
+	q.review = append(q.review[:i], q.review[i+1:]...)
+	return result, true
+}
+
+// find returns the index of a feed in the review queue; q.mu must be held
+func (q *DiscoveryQueue) find(feedURL string) (int, bool) {
+	for i, result := range q.review {
+		if result.URL == feedURL {
+			return i, true
+		}
+	}
+	return 0, false
+}
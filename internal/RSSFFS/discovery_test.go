@@ -0,0 +1,237 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// newTestDiscoveryQueue creates a queue whose discovery returns a feed for
+// every domain without fetching anything, recording the domains looked at
+func newTestDiscoveryQueue(t *testing.T, checked *[]string) *DiscoveryQueue {
+	t.Helper()
+	queue := NewDiscoveryQueue(config.Config{})
+	queue.discover = func(s *settings, domain string, links []string) []Candidate {
+		*checked = append(*checked, domain)
+		return []Candidate{{URL: "https://" + domain + "/feed.xml", Info: &FeedInfo{Title: domain}}}
+	}
+	return queue
+}
+
+// TestDiscoveryQueue tests that sites are queued once linked from enough
+// entries, looked at only once, and their feeds kept for review
+func TestDiscoveryQueue(t *testing.T) {
+	var checked []string
+	queue := newTestDiscoveryQueue(t, &checked)
+	own := Feed{FeedURL: "https://links.example.com/feed.xml", SiteURL: "https://links.example.com/"}
+
+	entry := func(content string) Entry {
+		return Entry{URL: "https://links.example.com/post", Content: content, Feed: own}
+	}
+	if queued := queue.AddEntries([]Entry{entry(`<a href="https://bob.example.net/a">Bob</a>`)}); queued != 0 {
+		t.Errorf("Expected nothing queued after one mention, got %d", queued)
+	}
+	if queued := queue.AddEntries([]Entry{entry(`<a href="https://bob.example.net/b">Bob</a> <a href="https://links.example.com/about">About</a>`), entry(`<a href="https://links.example.com/x">Self</a>`)}); queued != 1 {
+		t.Errorf("Expected bob.example.net queued, got %d sites", queued)
+	}
+	queue.processPending()
+
+	// A third mention doesn't queue the site again
+	if queued := queue.AddEntries([]Entry{entry(`<a href="https://bob.example.net/c">Bob</a>`)}); queued != 0 {
+		t.Errorf("Expected nothing queued again, got %d", queued)
+	}
+	queue.processPending()
+
+	if len(checked) != 1 || checked[0] != "bob.example.net" {
+		t.Errorf("Expected only bob.example.net looked at, got %v", checked)
+	}
+	review := queue.Review()
+	if len(review) != 1 || review[0].URL != "https://bob.example.net/feed.xml" || review[0].Source != "linked from 2 new entries" {
+		t.Fatalf("Expected bob's feed awaiting review, got %+v", review)
+	}
+
+	if _, ok := queue.Take("https://bob.example.net/feed.xml"); !ok {
+		t.Error("Expected to take bob's feed from the review queue")
+	}
+	if _, ok := queue.Take("https://bob.example.net/feed.xml"); ok || len(queue.Review()) != 0 {
+		t.Error("Expected the review queue to be empty")
+	}
+}
+
+// TestDiscoveryQueueForgets tests that the queue only remembers the mentions
+// of sites it may still queue, and sites for no longer than discoveryWindow
+func TestDiscoveryQueueForgets(t *testing.T) {
+	var checked []string
+	queue := newTestDiscoveryQueue(t, &checked)
+	own := Feed{FeedURL: "https://links.example.com/feed.xml", SiteURL: "https://links.example.com/"}
+	entry := func(content string) Entry {
+		return Entry{URL: "https://links.example.com/post", Content: content, Feed: own}
+	}
+	remembered := func() []int {
+		return []int{len(queue.mentions.Links), len(queue.mentions.Counts), len(queue.mentions.seen), len(queue.firstMentioned)}
+	}
+
+	queue.AddEntries([]Entry{entry(`<a href="https://bob.example.net/a">Bob</a> <a href="https://carol.example.com/">Carol</a>`)})
+	if got := remembered(); !reflect.DeepEqual(got, []int{2, 2, 2, 2}) {
+		t.Errorf("Expected the mentions of bob and carol remembered, got %v", got)
+	}
+	if queued := queue.AddEntries([]Entry{entry(`<a href="https://bob.example.net/b">Bob</a>`)}); queued != 1 {
+		t.Fatalf("Expected bob.example.net queued, got %d sites", queued)
+	}
+	if got := remembered(); !reflect.DeepEqual(got, []int{1, 1, 1, 1}) {
+		t.Errorf("Expected only carol's mentions remembered once bob is queued, got %v", got)
+	}
+	queue.AddEntries([]Entry{entry(`<a href="https://bob.example.net/c">Bob</a>`)})
+	if got := remembered(); !reflect.DeepEqual(got, []int{1, 1, 1, 1}) {
+		t.Errorf("Expected mentions of a queued site not remembered, got %v", got)
+	}
+	queue.processPending()
+	if len(checked) != 1 || checked[0] != "bob.example.net" {
+		t.Fatalf("Expected bob.example.net looked at with its links, got %v", checked)
+	}
+
+	// Once discoveryWindow has passed, carol's mention and bob's queueing are forgotten
+	longAgo := time.Now().Add(-discoveryWindow - time.Hour)
+	queue.mu.Lock()
+	queue.firstMentioned["carol.example.com"] = longAgo
+	queue.queued["bob.example.net"] = longAgo
+	queue.mu.Unlock()
+	queue.AddEntries(nil)
+	if got := remembered(); !reflect.DeepEqual(got, []int{0, 0, 0, 0}) {
+		t.Errorf("Expected nothing remembered, got %v", got)
+	}
+	if _, ok := queue.queued["bob.example.net"]; ok || len(queue.queued) != 1 {
+		t.Errorf("Expected only the feed's own site left queued, got %v", queue.queued)
+	}
+}
+
+// TestDiscoveryQueueRules tests that feeds the rules reject aren't kept for review
+func TestDiscoveryQueueRules(t *testing.T) {
+	var checked []string
+	queue := newTestDiscoveryQueue(t, &checked)
+	queue.conf.MinMentions = 1
+	queue.threshold = 1
+	rulesFile := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(rulesFile, []byte("rules:\n  - when: domain == \"spam.example.net\"\n    action: reject\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	queue.conf.RulesFile = rulesFile
+
+	queue.AddEntries([]Entry{{Content: `<a href="https://spam.example.net/">Spam</a> <a href="https://carol.example.org/">Carol</a>`}})
+	queue.processPending()
+
+	review := queue.Review()
+	if len(checked) != 2 || len(review) != 1 || review[0].URL != "https://carol.example.org/feed.xml" {
+		t.Errorf("Expected only carol's feed awaiting review, got %+v after checking %v", review, checked)
+	}
+}
+
+// TestDiscoveryQueueAlongsideRuns tests that the queue subscribing to feeds in
+// the background shares no settings with runs started meanwhile; run it with
+// -race
+func TestDiscoveryQueueAlongsideRuns(t *testing.T) {
+	withoutRateLimit(t)
+	var mu sync.Mutex
+	subscribed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/categories":
+			_, _ = fmt.Fprint(w, `[{"id": 7, "title": "Found"}, {"id": 8, "title": "Link Blogs"}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/feeds":
+			mu.Lock()
+			subscribed++
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"feed_id": 42}`)
+		default:
+			_, _ = fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	var checked []string
+	queue := newTestDiscoveryQueue(t, &checked)
+	queue.conf = config.Config{RSSReaderEndpoint: server.URL, WebhookCategory: "Found"}
+	queue.threshold = 1
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			queue.AddEntries([]Entry{{Content: fmt.Sprintf(`<a href="https://site%d.example.org/">Site</a>`, i)}})
+			queue.processPending()
+		}(i)
+		go func() {
+			defer wg.Done()
+			// Private addresses are refused, so the run finds nothing without any network access
+			conf := config.Config{RSSReaderEndpoint: server.URL, MinItems: 5, CreateCategory: true}
+			if _, err := RunReport("http://192.168.0.1/", "Link Blogs", false, ClearNone, true, conf); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if subscribed != 4 {
+		t.Errorf("Expected the queue to subscribe to a feed on each of 4 sites, got %d", subscribed)
+	}
+}
+
+// TestDiscoveryQueuePodcastCategory tests that the queue routes podcasts to
+// the podcast category, and subscribes to nothing when it can't be found
+func TestDiscoveryQueuePodcastCategory(t *testing.T) {
+	subscribed := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/categories":
+			_, _ = fmt.Fprint(w, `[{"id": 7, "title": "Found"}, {"id": 9, "title": "Podcasts"}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/feeds":
+			var body struct {
+				FeedURL    string `json:"feed_url"`
+				CategoryID int    `json:"category_id"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			subscribed[body.FeedURL] = body.CategoryID
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"feed_id": 42}`)
+		default:
+			_, _ = fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	var checked []string
+	queue := newTestDiscoveryQueue(t, &checked)
+	queue.discover = func(s *settings, domain string, links []string) []Candidate {
+		return []Candidate{
+			{URL: "https://" + domain + "/feed.xml", Info: &FeedInfo{}},
+			{URL: "https://" + domain + "/podcast.xml", Info: &FeedInfo{Podcast: true}},
+		}
+	}
+	queue.conf = config.Config{RSSReaderEndpoint: server.URL, WebhookCategory: "Found", PodcastCategory: "Podcasts"}
+	queue.threshold = 1
+
+	queue.AddEntries([]Entry{{Content: `<a href="https://alice.example.org/">Alice</a>`}})
+	queue.processPending()
+	expected := map[string]int{"https://alice.example.org/feed.xml": 7, "https://alice.example.org/podcast.xml": 9}
+	if !reflect.DeepEqual(subscribed, expected) {
+		t.Errorf("Expected the podcast subscribed to in the podcast category, got %v", subscribed)
+	}
+
+	queue.conf.PodcastCategory = "Missing"
+	queue.AddEntries([]Entry{{Content: `<a href="https://bob.example.net/">Bob</a>`}})
+	queue.processPending()
+	if len(subscribed) != 2 {
+		t.Errorf("Expected nothing subscribed to without the podcast category, got %v", subscribed)
+	}
+}
@@ -0,0 +1,324 @@
+package RSSFFS
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// FeedInfo describes a feed document: what it is called, where its site is,
+// how often it is updated and what its items contain
+type FeedInfo struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Link is the home page of the site publishing the feed
+	Link      string `json:"link,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Generator string `json:"generator,omitempty"`
+
+	ItemCount int       `json:"item_count"`
+	Newest    time.Time `json:"newest,omitzero"`
+	Oldest    time.Time `json:"oldest,omitzero"`
+	// AverageInterval is the mean time between dated items, zero with fewer than two
+	AverageInterval time.Duration `json:"average_interval,omitempty"`
+	// FullText reports whether items carry whole articles rather than summaries
+	FullText bool `json:"full_text"`
+
+	// Hub is the WebSub hub the feed announces updates to, if any
+	Hub         string `json:"hub,omitempty"`
+	Podcast     bool   `json:"podcast,omitempty"`
+	PodcastGUID string `json:"podcast_guid,omitempty"`
+}
+
+// fullTextMinLength is the text length from which an item body counts as a whole article
+const fullTextMinLength = 500
+
+// maxConcurrentFeedInspections caps how many candidate feeds inspectFeeds fetches at once
+const maxConcurrentFeedInspections = 8
+
+// feedDateLayouts are the date formats tried, in order, when reading item dates
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// feedLink is a <link> element in either RSS (text content) or Atom (href attribute) form
+type feedLink struct {
+	XMLName xml.Name
+	Rel     string `xml:"rel,attr"`
+	Href    string `xml:"href,attr"`
+	Value   string `xml:",chardata"`
+}
+
+// feedText is an Atom text construct, whose content is markup when its type is xhtml
+type feedText struct {
+	Type   string `xml:"type,attr"`
+	Text   string `xml:",chardata"`
+	Markup string `xml:",innerxml"`
+}
+
+// String returns the text construct's content, as HTML or plain text
+func (t feedText) String() string {
+	if t.Type == "xhtml" {
+		return t.Markup
+	}
+	return t.Text
+}
+
+// rssItem is an RSS 2.0 or RSS 1.0 item
+type rssItem struct {
+	Link        string `xml:"link"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+// rssDocument is an RSS 2.0 document, or an RSS 1.0 one whose items are siblings of its channel
+type rssDocument struct {
+	Channel struct {
+		Title       string     `xml:"title"`
+		Description string     `xml:"description"`
+		Links       []feedLink `xml:"link"`
+		Language    []string   `xml:"language"`
+		Generator   string     `xml:"generator"`
+		Items       []rssItem  `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+// atomDocumentEntry is an Atom entry
+type atomDocumentEntry struct {
+	Links     []feedLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   feedText   `xml:"summary"`
+	Content   feedText   `xml:"content"`
+}
+
+// atomDocument is an Atom feed document
+type atomDocument struct {
+	Lang      string              `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title     string              `xml:"title"`
+	Subtitle  string              `xml:"subtitle"`
+	Links     []feedLink          `xml:"link"`
+	Generator string              `xml:"generator"`
+	Entries   []atomDocumentEntry `xml:"entry"`
+}
+
+// parseFeedInfo extracts metadata from an RSS or Atom feed document
+func parseFeedInfo(body []byte) (*FeedInfo, error) {
+	root, err := feedRootElement(body)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &FeedInfo{}
+	var dates []time.Time
+	var bodies []string
+
+	switch root {
+	case "rss", "RDF":
+		var doc rssDocument
+		if err := decodeFeed(body, &doc); err != nil {
+			return nil, err
+		}
+		channel := doc.Channel
+		info.Title = strings.TrimSpace(channel.Title)
+		info.Description = strings.TrimSpace(channel.Description)
+		info.Generator = strings.TrimSpace(channel.Generator)
+		if len(channel.Language) > 0 {
+			info.Language = strings.TrimSpace(channel.Language[0])
+		}
+		for _, link := range channel.Links {
+			switch {
+			case link.Rel == "hub" && info.Hub == "":
+				info.Hub = strings.TrimSpace(link.Href)
+			case link.Href == "" && info.Link == "":
+				info.Link = strings.TrimSpace(link.Value)
+			}
+		}
+
+		items := append(channel.Items, doc.Items...)
+		for _, item := range items {
+			if date := parseFeedDate(item.PubDate, item.Date); !date.IsZero() {
+				dates = append(dates, date)
+			}
+			content := item.Content
+			if strings.TrimSpace(content) == "" {
+				content = item.Description
+			}
+			bodies = append(bodies, content)
+		}
+		info.ItemCount = len(items)
+
+	case "feed":
+		var doc atomDocument
+		if err := decodeFeed(body, &doc); err != nil {
+			return nil, err
+		}
+		info.Title = strings.TrimSpace(doc.Title)
+		info.Description = strings.TrimSpace(doc.Subtitle)
+		info.Generator = strings.TrimSpace(doc.Generator)
+		info.Language = strings.TrimSpace(doc.Lang)
+		for _, link := range doc.Links {
+			switch link.Rel {
+			case "hub":
+				if info.Hub == "" {
+					info.Hub = strings.TrimSpace(link.Href)
+				}
+			case "", "alternate":
+				if info.Link == "" {
+					info.Link = strings.TrimSpace(link.Href)
+				}
+			}
+		}
+
+		for _, entry := range doc.Entries {
+			if date := parseFeedDate(entry.Published, entry.Updated); !date.IsZero() {
+				dates = append(dates, date)
+			}
+			content := entry.Content.String()
+			if strings.TrimSpace(content) == "" {
+				content = entry.Summary.String()
+			}
+			bodies = append(bodies, content)
+		}
+		info.ItemCount = len(doc.Entries)
+
+	default:
+		return nil, errors.New("not an RSS or Atom feed")
+	}
+
+	if len(dates) > 0 {
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		info.Oldest = dates[0]
+		info.Newest = dates[len(dates)-1]
+		if len(dates) > 1 {
+			info.AverageInterval = info.Newest.Sub(info.Oldest) / time.Duration(len(dates)-1)
+		}
+	}
+	info.FullText = isFullText(bodies)
+	info.Podcast, info.PodcastGUID = podcastMetadata(body)
+	return info, nil
+}
+
+// newFeedDecoder returns a lenient XML decoder that understands the
+// non-UTF-8 encodings feeds are sometimes served in
+func newFeedDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder
+}
+
+// decodeFeed unmarshals a feed document into v
+func decodeFeed(body []byte, v interface{}) error {
+	return newFeedDecoder(body).Decode(v)
+}
+
+// feedRootElement returns the local name of a document's root element
+func feedRootElement(body []byte) (string, error) {
+	decoder := newFeedDecoder(body)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// parseFeedDate returns the first of values that parses as a date, or the zero time
+func parseFeedDate(values ...string) time.Time {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		for _, layout := range feedDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC()
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// isFullText reports whether at least half of the item bodies are long enough
+// to be whole articles rather than summaries
+func isFullText(bodies []string) bool {
+	if len(bodies) == 0 {
+		return false
+	}
+	full := 0
+	for _, body := range bodies {
+		if len(htmlText(body)) >= fullTextMinLength {
+			full++
+		}
+	}
+	return full*2 >= len(bodies)
+}
+
+// htmlText returns the whitespace-normalised text of an HTML fragment
+func htmlText(fragment string) string {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return fragment
+	}
+	return nodeText(doc)
+}
+
+// inspectFeed fetches a candidate feed and records its metadata on the candidate
+func inspectFeed(candidate *Candidate) {
+	page, err := fetchPage(candidate.URL)
+	if err != nil {
+		log.Debugf("Could not fetch %s to read its metadata: %v", candidate.URL, err)
+		return
+	}
+	info, err := parseFeedInfo(page.Body)
+	if err != nil {
+		log.Debugf("Could not read metadata of feed %s: %v", candidate.URL, err)
+		return
+	}
+	candidate.Info = info
+}
+
+// inspectFeeds inspects the candidates whose metadata hasn't been read yet,
+// several at a time
+func inspectFeeds(candidates []Candidate) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentFeedInspections)
+	for i := range candidates {
+		if candidates[i].Info != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(candidate *Candidate) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			inspectFeed(candidate)
+		}(&candidates[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,151 @@
+package RSSFFS
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseFeedInfo tests metadata extraction from RSS 2.0, RSS 1.0 and Atom feeds
+func TestParseFeedInfo(t *testing.T) {
+	article := strings.Repeat("A whole paragraph of the article. ", 20)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected FeedInfo
+	}{
+		{
+			name: "RSS 2.0 with WebSub hub",
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title> Example Blog </title>
+    <link>https://example.com/</link>
+    <atom:link rel="self" href="https://example.com/feed/"/>
+    <atom:link rel="hub" href="https://pubsubhubbub.appspot.com/"/>
+    <description>Notes and essays</description>
+    <language>en-GB</language>
+    <generator>https://wordpress.org/?v=6.5</generator>
+    <item><title>Third</title><pubDate>Wed, 15 May 2024 09:00:00 +0000</pubDate><content:encoded><![CDATA[<p>` + article + `</p>]]></content:encoded></item>
+    <item><title>Second</title><pubDate>Wed, 8 May 2024 09:00:00 GMT</pubDate><content:encoded><![CDATA[<p>` + article + `</p>]]></content:encoded></item>
+    <item><title>First</title><pubDate>Wed, 01 May 2024 09:00:00 +0000</pubDate><description>Short</description></item>
+  </channel>
+</rss>`,
+			expected: FeedInfo{
+				Title:           "Example Blog",
+				Description:     "Notes and essays",
+				Link:            "https://example.com/",
+				Language:        "en-GB",
+				Generator:       "https://wordpress.org/?v=6.5",
+				ItemCount:       3,
+				Newest:          time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC),
+				Oldest:          time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+				AverageInterval: 7 * 24 * time.Hour,
+				FullText:        true,
+				Hub:             "https://pubsubhubbub.appspot.com/",
+			},
+		},
+		{
+			name: "RSS 1.0 in another encoding",
+			body: `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://example.org/">
+    <title>Caf` + "\xe9" + ` News</title>
+    <link>https://example.org/</link>
+    <description>Daily</description>
+    <dc:language>fr</dc:language>
+  </channel>
+  <item rdf:about="https://example.org/1"><title>One</title><dc:date>2024-05-02T10:00:00+02:00</dc:date><description>Summary</description></item>
+</rdf:RDF>`,
+			expected: FeedInfo{
+				Title:       "Café News",
+				Description: "Daily",
+				Link:        "https://example.org/",
+				Language:    "fr",
+				ItemCount:   1,
+				Newest:      time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+				Oldest:      time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "Atom",
+			body: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="de">
+  <title>Beispiel</title>
+  <subtitle>Ein Blog</subtitle>
+  <link rel="self" href="https://example.de/atom.xml"/>
+  <link href="https://example.de/"/>
+  <generator uri="https://gohugo.io/" version="0.125">Hugo</generator>
+  <entry><title>B</title><updated>2024-05-03T00:00:00Z</updated><summary>Kurz</summary></entry>
+  <entry><title>A</title><published>2024-05-01T00:00:00Z</published><updated>2024-05-05T00:00:00Z</updated><content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">Kurz</div></content></entry>
+</feed>`,
+			expected: FeedInfo{
+				Title:           "Beispiel",
+				Description:     "Ein Blog",
+				Link:            "https://example.de/",
+				Language:        "de",
+				Generator:       "Hugo",
+				ItemCount:       2,
+				Newest:          time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC),
+				Oldest:          time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+				AverageInterval: 48 * time.Hour,
+			},
+		},
+		{
+			name: "Podcast",
+			body: `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:podcast="https://podcastindex.org/namespace/1.0"><channel><title>Show</title><podcast:guid>abc</podcast:guid></channel></rss>`,
+			expected: FeedInfo{
+				Title:       "Show",
+				Podcast:     true,
+				PodcastGUID: "abc",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseFeedInfo([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *info != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *info)
+			}
+		})
+	}
+}
+
+// TestParseFeedInfoRejectsNonFeeds tests that documents other than feeds are rejected
+func TestParseFeedInfoRejectsNonFeeds(t *testing.T) {
+	for _, body := range []string{
+		`<html><head><title>Not a feed</title></head></html>`,
+		`<?xml version="1.0"?><opml version="2.0"><body/></opml>`,
+		``,
+	} {
+		if _, err := parseFeedInfo([]byte(body)); err == nil {
+			t.Errorf("Expected error for %q, got none", body)
+		}
+	}
+}
+
+// TestInspectFeeds tests that only candidates not inspected yet are fetched
+func TestInspectFeeds(t *testing.T) {
+	inspected := &FeedInfo{Title: "Alice"}
+	candidates := make([]Candidate, 2*maxConcurrentFeedInspections)
+	for i := range candidates {
+		// Private addresses are refused, so nothing is fetched
+		candidates[i].URL = "http://192.168.0.1/feed"
+	}
+	candidates[0].Info = inspected
+
+	inspectFeeds(candidates)
+	if candidates[0].Info != inspected {
+		t.Errorf("Expected an inspected candidate to be left as it was, got %+v", candidates[0].Info)
+	}
+	for _, candidate := range candidates[1:] {
+		if candidate.Info != nil {
+			t.Errorf("Expected no metadata for a feed that couldn't be fetched, got %+v", candidate.Info)
+		}
+	}
+}
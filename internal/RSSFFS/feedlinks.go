@@ -0,0 +1,194 @@
+package RSSFFS
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// feedItem is a feed item reduced to what traversal needs: its link and its content HTML
+type feedItem struct {
+	Link    string
+	Content string
+}
+
+// domainMentions collects the domains linked from a set of documents, such as
+// a feed's items, with the distinct URLs linked on each domain and how many
+// of the documents link to it
+type domainMentions struct {
+	Links  map[string][]string
+	Counts map[string]int
+	seen   map[string]bool
+}
+
+// newDomainMentions returns an empty domainMentions
+func newDomainMentions() *domainMentions {
+	return &domainMentions{Links: make(map[string][]string), Counts: make(map[string]int), seen: make(map[string]bool)}
+}
+
+// add records the links of one document, given as a map from each domain to
+// the URLs linked on it. Each domain is counted once per document however
+// often the document links to it.
+func (m *domainMentions) add(links map[string][]string) {
+	for domain, urls := range links {
+		m.Counts[domain]++
+		for _, link := range urls {
+			if !m.seen[link] {
+				m.seen[link] = true
+				m.Links[domain] = append(m.Links[domain], link)
+			}
+		}
+	}
+}
+
+// addDocument records the links in an HTML document along with any other
+// absolute URLs the document links to, such as a feed item's own link
+func (m *domainMentions) addDocument(content string, links ...string) {
+	domains := domainsFromHTML([]byte(content))
+	for _, link := range links {
+		u, err := url.Parse(strings.TrimSpace(link))
+		if err != nil || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		domain := u.Hostname()
+		if !containsString(domains[domain], u.String()) {
+			domains[domain] = append([]string{u.String()}, domains[domain]...)
+		}
+	}
+	m.add(domains)
+}
+
+// above returns the links of the domains mentioned at least min times,
+// leaving out the excluded domains (such as the feed's own site)
+func (m *domainMentions) above(min int, exclude ...string) map[string][]string {
+	domains := make(map[string][]string)
+	for domain, links := range m.Links {
+		if m.Counts[domain] < min || containsString(exclude, domain) {
+			continue
+		}
+		domains[domain] = links
+	}
+	return domains
+}
+
+// forget drops a domain's links and count, so that it is counted afresh if
+// it is linked again
+func (m *domainMentions) forget(domain string) {
+	for _, link := range m.Links[domain] {
+		delete(m.seen, link)
+	}
+	delete(m.Links, domain)
+	delete(m.Counts, domain)
+}
+
+// containsString reports whether s is one of values
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isFeedDocument reports whether a fetched document is an RSS or Atom feed
+func isFeedDocument(contentType string, body []byte) bool {
+	if strings.Contains(contentType, "html") {
+		return false
+	}
+	root, err := feedRootElement(body)
+	return err == nil && (root == "rss" || root == "RDF" || root == "feed")
+}
+
+// parseFeedItems returns the link and content HTML of each item in an RSS or Atom feed
+func parseFeedItems(body []byte) ([]feedItem, error) {
+	root, err := feedRootElement(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []feedItem
+	switch root {
+	case "rss", "RDF":
+		var doc rssDocument
+		if err := decodeFeed(body, &doc); err != nil {
+			return nil, err
+		}
+		for _, item := range append(doc.Channel.Items, doc.Items...) {
+			content := item.Content
+			if strings.TrimSpace(content) == "" {
+				content = item.Description
+			}
+			items = append(items, feedItem{Link: strings.TrimSpace(item.Link), Content: content})
+		}
+	case "feed":
+		var doc atomDocument
+		if err := decodeFeed(body, &doc); err != nil {
+			return nil, err
+		}
+		for _, entry := range doc.Entries {
+			item := feedItem{Content: entry.Content.String() + entry.Summary.String()}
+			for _, link := range entry.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					item.Link = strings.TrimSpace(link.Href)
+					break
+				}
+			}
+			items = append(items, item)
+		}
+	default:
+		return nil, errors.New("not an RSS or Atom feed")
+	}
+	return items, nil
+}
+
+// feedItemCandidates finds the feeds of the sites a feed's items link to, from
+// each item's own link and the links in its content. This suits link blogs and
+// newsletters, whose items mostly point elsewhere. Only domains linked from at
+// least minMentions items are checked, and the feed's own site is left out.
+func (s *settings) feedItemCandidates(page *fetchedPage) ([]Candidate, error) {
+	items, err := parseFeedItems(page.Body)
+	if err != nil {
+		return nil, fmt.Errorf("traversal mode: Error reading feed %s: %w", page.URL, err)
+	}
+
+	mentions := newDomainMentions()
+	for _, item := range items {
+		mentions.addDocument(item.Content, item.Link)
+	}
+
+	var exclude []string
+	feedHost := ""
+	if u, err := url.Parse(page.URL); err == nil {
+		feedHost = u.Hostname()
+		exclude = append(exclude, feedHost)
+	}
+	if info, err := parseFeedInfo(page.Body); err == nil && info.Link != "" {
+		if u, err := url.Parse(info.Link); err == nil && u.Hostname() != "" {
+			feedHost = u.Hostname()
+			exclude = append(exclude, feedHost)
+		}
+	}
+
+	domains := mentions.above(max(s.minMentions, 1), exclude...)
+	log.Infof("Traversal mode: Found %d domains linked from at least %d of the %d items in feed %s", len(domains), max(s.minMentions, 1), len(items), page.URL)
+	if len(domains) == 0 {
+		return nil, nil
+	}
+
+	candidates := s.checkDomainsForRSS(domains, page.URL, mentions.Counts)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Mentions > candidates[j].Mentions })
+	for i := range candidates {
+		candidates[i].Source = "linked from " + feedHost
+		if candidates[i].Mentions > 1 {
+			candidates[i].Source += fmt.Sprintf(" (%d items)", candidates[i].Mentions)
+		}
+	}
+	log.Infof("Traversal mode: Found %d RSS feeds across %d domains linked from feed %s", len(candidates), len(domains), page.URL)
+	return candidates, nil
+}
@@ -0,0 +1,115 @@
+package RSSFFS
+
+import (
+	"reflect"
+	"testing"
+)
+
+const linkBlogFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Links</title>
+    <link>https://links.example.com/</link>
+    <item>
+      <link>https://alice.example.org/2024/05/post</link>
+      <description><![CDATA[<p>Via <a href="https://bob.example.net/">Bob</a> and <a href="https://links.example.com/about">me</a>, see <a href="https://alice.example.org/more#top">more</a></p>]]></description>
+    </item>
+    <item>
+      <link>https://alice.example.org/2024/04/other</link>
+      <content:encoded><![CDATA[<a href="/relative">Relative</a>]]></content:encoded>
+    </item>
+    <item>
+      <link>https://links.example.com/2024/03/note</link>
+      <description><![CDATA[<a href="https://bob.example.net/x">x</a> <a href="https://bob.example.net/y">y</a>]]></description>
+    </item>
+  </channel>
+</rss>`
+
+// TestParseFeedItems tests reading item links and content from RSS and Atom feeds
+func TestParseFeedItems(t *testing.T) {
+	items, err := parseFeedItems([]byte(linkBlogFeed))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(items) != 3 || items[0].Link != "https://alice.example.org/2024/05/post" || items[1].Content != `<a href="/relative">Relative</a>` {
+		t.Errorf("Unexpected RSS items %+v", items)
+	}
+
+	items, err = parseFeedItems([]byte(`<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry><link rel="replies" href="https://example.com/comments"/><link href="https://example.com/post"/><summary type="html">&lt;a href="https://other.example.com/"&gt;x&lt;/a&gt;</summary></entry>
+</feed>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Link != "https://example.com/post" || items[0].Content != `<a href="https://other.example.com/">x</a>` {
+		t.Errorf("Unexpected Atom items %+v", items)
+	}
+
+	if _, err := parseFeedItems([]byte(`<html><body>Not a feed</body></html>`)); err == nil {
+		t.Error("Expected error for HTML page, got none")
+	}
+}
+
+// TestDomainMentions tests counting the items that link to each domain
+func TestDomainMentions(t *testing.T) {
+	items, err := parseFeedItems([]byte(linkBlogFeed))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mentions := newDomainMentions()
+	for _, item := range items {
+		mentions.addDocument(item.Content, item.Link)
+	}
+
+	expectedCounts := map[string]int{"alice.example.org": 2, "bob.example.net": 2, "links.example.com": 2}
+	if !reflect.DeepEqual(mentions.Counts, expectedCounts) {
+		t.Errorf("Expected counts %v, got %v", expectedCounts, mentions.Counts)
+	}
+	expectedLinks := []string{"https://alice.example.org/2024/05/post", "https://alice.example.org/more", "https://alice.example.org/2024/04/other"}
+	if !reflect.DeepEqual(mentions.Links["alice.example.org"], expectedLinks) {
+		t.Errorf("Expected links %v, got %v", expectedLinks, mentions.Links["alice.example.org"])
+	}
+
+	domains := mentions.above(2, "links.example.com")
+	if len(domains) != 2 || domains["alice.example.org"] == nil || domains["bob.example.net"] == nil {
+		t.Errorf("Expected alice and bob above the threshold, got %v", domains)
+	}
+	if domains := mentions.above(3); len(domains) != 0 {
+		t.Errorf("Expected no domains above 3 mentions, got %v", domains)
+	}
+}
+
+// TestIsFeedDocument tests telling feeds apart from other documents
+func TestIsFeedDocument(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    bool
+	}{
+		{name: "RSS", contentType: "application/rss+xml", body: linkBlogFeed, expected: true},
+		{name: "Atom served as XML", contentType: "text/xml", body: `<feed xmlns="http://www.w3.org/2005/Atom"/>`, expected: true},
+		{name: "OPML", contentType: "text/xml", body: `<opml version="2.0"/>`},
+		{name: "HTML", contentType: "text/html", body: `<rss version="2.0"/>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFeedDocument(tt.contentType, []byte(tt.body)); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestFeedItemCandidatesThreshold tests that no domain is checked when none is mentioned often enough
+func TestFeedItemCandidatesThreshold(t *testing.T) {
+	s := &settings{minMentions: 3}
+	candidates, err := s.feedItemCandidates(&fetchedPage{URL: "https://links.example.com/feed.xml", Body: []byte(linkBlogFeed)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("Expected no candidates, got %+v", candidates)
+	}
+}
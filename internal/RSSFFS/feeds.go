@@ -0,0 +1,99 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FeedQuery narrows a list of subscribed feeds down to those in a category,
+// those failing to parse or those disabled. Zero values don't narrow it.
+type FeedQuery struct {
+	CategoryID int
+	Errors     bool
+	Disabled   bool
+}
+
+// Matches reports whether feed passes every condition of the query
+func (q FeedQuery) Matches(feed Feed) bool {
+	if q.CategoryID != 0 && feed.Category.ID != q.CategoryID {
+		return false
+	}
+	if q.Errors && feed.ParsingErrorCount == 0 {
+		return false
+	}
+	if q.Disabled && !feed.Disabled {
+		return false
+	}
+	return true
+}
+
+// Filter returns the feeds matching the query, in order
+func (q FeedQuery) Filter(feeds []Feed) []Feed {
+	var matched []Feed
+	for _, feed := range feeds {
+		if q.Matches(feed) {
+			matched = append(matched, feed)
+		}
+	}
+	return matched
+}
+
+// SelectFeeds returns the feeds picked out by selectors, in order and each
+// once. A selector is a feed ID, a feed URL (compared like canonicalURL
+// does) or a glob such as "*.example.com/*" matched against feed URLs,
+// ignoring case, where "*" matches any run of characters.
+// A selector matching no feed is an error, so that typos don't go unnoticed.
+func SelectFeeds(feeds []Feed, selectors []string) ([]Feed, error) {
+	selected := make(map[int]bool)
+	var unmatched []string
+	for _, selector := range selectors {
+		selector = strings.TrimSpace(selector)
+		match, err := feedSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, feed := range feeds {
+			if match(feed) {
+				selected[feed.ID] = true
+				found = true
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, selector)
+		}
+	}
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("no subscribed feed matches %s", strings.Join(unmatched, ", "))
+	}
+
+	var matched []Feed
+	for _, feed := range feeds {
+		if selected[feed.ID] {
+			matched = append(matched, feed)
+		}
+	}
+	return matched, nil
+}
+
+// feedSelector returns a function reporting whether a feed is picked out by selector
+func feedSelector(selector string) (func(Feed) bool, error) {
+	if selector == "" {
+		return nil, fmt.Errorf("empty feed selector")
+	}
+	if id, err := strconv.Atoi(selector); err == nil {
+		return func(feed Feed) bool { return feed.ID == id }, nil
+	}
+	if strings.Contains(selector, "*") {
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(selector), `\*`, ".*")
+		glob, err := regexp.Compile("(?i)^" + pattern + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid feed glob %s: %w", selector, err)
+		}
+		return func(feed Feed) bool { return glob.MatchString(feed.FeedURL) }, nil
+	}
+	canonical := canonicalURL(selector)
+	return func(feed Feed) bool { return canonicalURL(feed.FeedURL) == canonical }, nil
+}
@@ -0,0 +1,77 @@
+package RSSFFS
+
+import (
+	"reflect"
+	"testing"
+)
+
+// feedIDs returns the IDs of feeds, for comparing selections
+func feedIDs(feeds []Feed) []int {
+	var ids []int
+	for _, feed := range feeds {
+		ids = append(ids, feed.ID)
+	}
+	return ids
+}
+
+// TestSelectFeeds tests picking feeds out by ID, URL and glob
+func TestSelectFeeds(t *testing.T) {
+	feeds := []Feed{
+		{ID: 1, FeedURL: "https://alice.example.org/feed.xml"},
+		{ID: 2, FeedURL: "https://blog.example.com/rss"},
+		{ID: 3, FeedURL: "https://www.example.com/?format=rss"},
+		{ID: 4, FeedURL: "https://bob.example.net/atom.xml"},
+	}
+
+	tests := []struct {
+		name      string
+		selectors []string
+		want      []int
+		wantErr   bool
+	}{
+		{"ID", []string{"4"}, []int{4}, false},
+		{"URL ignoring scheme and www", []string{"http://example.com/?format=rss"}, []int{3}, false},
+		{"glob", []string{"*.example.com/*"}, []int{2, 3}, false},
+		{"glob ignoring case", []string{"HTTPS://ALICE.*"}, []int{1}, false},
+		{"each feed once, in order", []string{"4", "*example*", "1"}, []int{1, 2, 3, 4}, false},
+		{"unmatched selector", []string{"1", "https://carol.example.org/feed"}, nil, true},
+		{"empty selector", []string{" "}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectFeeds(feeds, tt.selectors)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %t, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(feedIDs(got), tt.want) {
+				t.Errorf("Expected feeds %v, got %v", tt.want, feedIDs(got))
+			}
+		})
+	}
+}
+
+// TestFeedQuery tests narrowing feeds down by category, parse errors and being disabled
+func TestFeedQuery(t *testing.T) {
+	feeds := []Feed{
+		{ID: 1, Category: Category{ID: 7}},
+		{ID: 2, Category: Category{ID: 7}, ParsingErrorCount: 3},
+		{ID: 3, Category: Category{ID: 8}, ParsingErrorCount: 1, Disabled: true},
+	}
+
+	tests := []struct {
+		query FeedQuery
+		want  []int
+	}{
+		{FeedQuery{}, []int{1, 2, 3}},
+		{FeedQuery{CategoryID: 7}, []int{1, 2}},
+		{FeedQuery{Errors: true}, []int{2, 3}},
+		{FeedQuery{Disabled: true}, []int{3}},
+		{FeedQuery{CategoryID: 7, Errors: true}, []int{2}},
+		{FeedQuery{CategoryID: 8, Errors: true, Disabled: true}, []int{3}},
+	}
+	for _, tt := range tests {
+		if got := feedIDs(tt.query.Filter(feeds)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: expected feeds %v, got %v", tt.query, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeedFilter skips discovered feeds before subscribing to them: feeds that
+// haven't posted within MaxAge, that have fewer than MinItems items, or whose
+// language isn't one of Languages. Zero values disable each check, and feeds
+// whose age or language can't be told are kept.
+type FeedFilter struct {
+	MaxAge    time.Duration
+	MinItems  int
+	Languages []string
+}
+
+// NewFeedFilter builds a FeedFilter from its configuration. maxAge is a
+// duration such as "8760h", and may also be given in days, weeks or years,
+// e.g. "90d", "6w" or "2y". Languages are tags such as "en" or "pt-BR".
+func NewFeedFilter(maxAge string, minItems int, languages []string) (FeedFilter, error) {
+	filter := FeedFilter{MinItems: minItems}
+	if minItems < 0 {
+		return filter, fmt.Errorf("invalid minimum item count %d", minItems)
+	}
+	if maxAge != "" {
+		age, err := ParseMaxAge(maxAge)
+		if err != nil {
+			return filter, err
+		}
+		filter.MaxAge = age
+	}
+	for _, language := range languages {
+		if language = strings.TrimSpace(language); language != "" {
+			filter.Languages = append(filter.Languages, strings.ToLower(language))
+		}
+	}
+	return filter, nil
+}
+
+// ParseMaxAge parses a duration that may use the d, w and y units besides those of time.ParseDuration
+func ParseMaxAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("max age is empty")
+	}
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'y': 365 * 24 * time.Hour}
+	var age time.Duration
+	var err error
+	if unit, ok := units[s[len(s)-1]]; ok && len(s) > 1 {
+		var n int
+		n, err = strconv.Atoi(s[:len(s)-1])
+		age = time.Duration(n) * unit
+	} else {
+		age, err = time.ParseDuration(s)
+	}
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid max age %q (use e.g. 720h, 90d, 6w or 2y)", s)
+	}
+	return age, nil
+}
+
+// skipReason returns why a candidate should not be subscribed to, or an empty
+// string when it passes the filter. The candidate's metadata must already have
+// been read for anything but its hreflang to be checked.
+func (f FeedFilter) skipReason(candidate Candidate, now time.Time) string {
+	info := candidate.Info
+	if info != nil && f.MaxAge > 0 && !info.Newest.IsZero() && now.Sub(info.Newest) > f.MaxAge {
+		return fmt.Sprintf("newest item from %s is older than %s", info.Newest.Format("2006-01-02"), formatAge(f.MaxAge))
+	}
+	if info != nil && f.MinItems > 0 && info.ItemCount < f.MinItems {
+		return fmt.Sprintf("%d items, fewer than %d", info.ItemCount, f.MinItems)
+	}
+
+	language := candidate.Hreflang
+	if info != nil && info.Language != "" {
+		language = info.Language
+	}
+	if len(f.Languages) > 0 && language != "" && f.languageRank(language) == len(f.Languages) {
+		return fmt.Sprintf("language %s is not one of %s", language, strings.Join(f.Languages, ", "))
+	}
+	return ""
+}
+
+// languageRank returns the position of the first preferred language that
+// language matches, or len(f.Languages) when it matches none. A preferred
+// language matches its regional variants, so "en" matches "en-GB".
+func (f FeedFilter) languageRank(language string) int {
+	language = strings.ToLower(strings.TrimSpace(language))
+	for i, preferred := range f.Languages {
+		if language == preferred || strings.HasPrefix(language, preferred+"-") {
+			return i
+		}
+	}
+	return len(f.Languages)
+}
+
+// formatAge formats a duration in whole days when it is one, e.g. "90d"
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
@@ -0,0 +1,121 @@
+package RSSFFS
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseMaxAge tests parsing durations with day, week and year units
+func TestParseMaxAge(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{input: "720h", expected: 720 * time.Hour},
+		{input: "90d", expected: 90 * 24 * time.Hour},
+		{input: " 6w ", expected: 42 * 24 * time.Hour},
+		{input: "2y", expected: 730 * 24 * time.Hour},
+		{input: "", expectErr: true},
+		{input: "d", expectErr: true},
+		{input: "-3d", expectErr: true},
+		{input: "soon", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMaxAge(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestNewFeedFilter tests building a filter from configuration
+func TestNewFeedFilter(t *testing.T) {
+	filter, err := NewFeedFilter("30d", 3, []string{" EN ", "", "pt-BR"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filter.MaxAge != 30*24*time.Hour || filter.MinItems != 3 || len(filter.Languages) != 2 || filter.Languages[0] != "en" || filter.Languages[1] != "pt-br" {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+
+	if _, err := NewFeedFilter("yesterday", 0, nil); err == nil {
+		t.Error("Expected error for invalid max age, got none")
+	}
+	if _, err := NewFeedFilter("", -1, nil); err == nil {
+		t.Error("Expected error for negative minimum item count, got none")
+	}
+}
+
+// TestFeedFilterSkipReason tests which feeds the filter skips and why
+func TestFeedFilterSkipReason(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	filter := FeedFilter{MaxAge: 365 * 24 * time.Hour, MinItems: 3, Languages: []string{"en", "de"}}
+
+	tests := []struct {
+		name      string
+		candidate Candidate
+		expected  string
+	}{
+		{
+			name:      "Active feed",
+			candidate: Candidate{Info: &FeedInfo{Newest: now.AddDate(0, -1, 0), ItemCount: 10, Language: "en-US"}},
+		},
+		{
+			name:      "Stale feed",
+			candidate: Candidate{Info: &FeedInfo{Newest: time.Date(2016, 3, 4, 0, 0, 0, 0, time.UTC), ItemCount: 10}},
+			expected:  "newest item from 2016-03-04 is older than 365d",
+		},
+		{
+			name:      "Too few items",
+			candidate: Candidate{Info: &FeedInfo{Newest: now, ItemCount: 1}},
+			expected:  "1 items, fewer than 3",
+		},
+		{
+			name:      "Other feed language",
+			candidate: Candidate{Info: &FeedInfo{Newest: now, ItemCount: 5, Language: "fr-FR"}},
+			expected:  "language fr-FR is not one of en, de",
+		},
+		{
+			name:      "Other hreflang",
+			candidate: Candidate{Hreflang: "fr", Info: &FeedInfo{Newest: now, ItemCount: 5}},
+			expected:  "language fr is not one of en, de",
+		},
+		{
+			name:      "Feed language wins over hreflang",
+			candidate: Candidate{Hreflang: "fr", Info: &FeedInfo{Newest: now, ItemCount: 5, Language: "de"}},
+		},
+		{
+			name:      "Undated feed of unknown language",
+			candidate: Candidate{Info: &FeedInfo{ItemCount: 5}},
+		},
+		{
+			name:      "Unreadable feed",
+			candidate: Candidate{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.skipReason(tt.candidate, now); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if got := (FeedFilter{}).skipReason(Candidate{Info: &FeedInfo{Language: "fr"}}, now); got != "" {
+		t.Errorf("Expected empty filter to skip nothing, got %q", got)
+	}
+}
@@ -0,0 +1,131 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// defaultHarvestMinMentions is how many entries must link to a site for
+// Harvest to look for its feeds when no threshold is configured
+const defaultHarvestMinMentions = 2
+
+// HarvestOptions selects the RSS reader entries Harvest reads: starred ones,
+// those in a category, those published within a date range, or any
+// combination. Zero values don't filter. Limit caps how many entries are
+// read, newest first.
+type HarvestOptions struct {
+	Starred  bool
+	Category string
+	After    time.Time
+	Before   time.Time
+	Limit    int
+}
+
+// Harvest finds new sources in entries already in the RSS reader: it counts
+// the sites linked from the selected entries' links and content, looks for
+// feeds on those linked from at least conf.MinMentions entries (2 by default)
+// that aren't already subscribed to, and subscribes to them in category, or
+// podcast feeds in conf.PodcastCategory when it is set.
+func Harvest(options HarvestOptions, category string, debug bool, conf config.Config) (*Report, error) {
+	s, err := newSettings(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryId, err := s.reader.ResolveCategoryID(category, s.createCategories)
+	if err != nil {
+		return nil, fmt.Errorf("error getting categoryId from category %s: %w", category, err)
+	}
+	if err := s.resolvePodcastCategory(conf.PodcastCategory); err != nil {
+		return nil, err
+	}
+
+	query, err := s.harvestQuery(options)
+	if err != nil {
+		return nil, err
+	}
+	limit := options.Limit
+	if limit <= 0 {
+		limit = entriesPageSize
+	}
+	entries, err := s.reader.Entries(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("harvest: Error getting entries: %w", err)
+	}
+	log.Infof("Harvest: Read %d entries from the RSS reader", len(entries))
+
+	mentions := newDomainMentions()
+	for _, entry := range entries {
+		mentions.addDocument(entry.Content, entry.URL)
+	}
+
+	// Sites already subscribed to, and those the entries come from, aren't new sources
+	feeds, err := s.reader.Feeds()
+	if err != nil {
+		return nil, fmt.Errorf("harvest: Error getting subscribed feeds: %w", err)
+	}
+	var subscribed []string
+	for _, feed := range feeds {
+		subscribed = append(subscribed, hostnames(feed.SiteURL, feed.FeedURL)...)
+	}
+	for _, entry := range entries {
+		subscribed = append(subscribed, hostnames(entry.Feed.SiteURL, entry.Feed.FeedURL)...)
+	}
+
+	threshold := conf.MinMentions
+	if threshold <= 0 {
+		threshold = defaultHarvestMinMentions
+	}
+	domains := mentions.above(threshold, subscribed...)
+	log.Infof("Harvest: Found %d unsubscribed sites linked from at least %d entries", len(domains), threshold)
+	if len(domains) == 0 {
+		return &Report{}, nil
+	}
+
+	candidates := s.checkDomainsForRSS(domains, "", mentions.Counts)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Mentions > candidates[j].Mentions })
+	for i := range candidates {
+		candidates[i].Source = fmt.Sprintf("linked from %d entries", candidates[i].Mentions)
+	}
+	return s.subscribeCandidates(candidates, "", categoryId, debug, "Harvest"), nil
+}
+
+// harvestQuery turns harvest options into RSS reader entry filters
+func (s *settings) harvestQuery(options HarvestOptions) (url.Values, error) {
+	query := url.Values{}
+	if options.Starred {
+		query.Set("starred", "true")
+	}
+	if options.Category != "" {
+		id, err := s.reader.CategoryID(options.Category)
+		if err != nil {
+			// Not wrapped, since creating the category entries are read from wouldn't help
+			return nil, fmt.Errorf("error getting categoryId from category %s: %v", options.Category, err)
+		}
+		query.Set("category_id", strconv.Itoa(id))
+	}
+	if !options.After.IsZero() {
+		query.Set("after", strconv.FormatInt(options.After.Unix(), 10))
+	}
+	if !options.Before.IsZero() {
+		query.Set("before", strconv.FormatInt(options.Before.Unix(), 10))
+	}
+	return query, nil
+}
+
+// hostnames returns the hostnames of the given URLs, skipping any that don't parse
+func hostnames(urls ...string) []string {
+	var hosts []string
+	for _, raw := range urls {
+		if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
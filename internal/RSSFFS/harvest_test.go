@@ -0,0 +1,115 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// newReaderServer starts a stand-in RSS reader serving categories, feeds and
+// the given entries, recording the entry queries it receives
+func newReaderServer(t *testing.T, entries []Entry, queries *[]url.Values) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/categories":
+			_, _ = fmt.Fprint(w, `[{"id": 1, "title": "Found"}, {"id": 7, "title": "Link Blogs"}]`)
+		case "/v1/feeds":
+			_, _ = fmt.Fprint(w, `[{"id": 3, "feed_url": "https://alice.example.org/feed.xml", "site_url": "https://alice.example.org/"}]`)
+		case "/v1/entries":
+			*queries = append(*queries, r.URL.Query())
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			end := min(offset+limit, len(entries))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"total": len(entries), "entries": entries[min(offset, end):end]})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestGetEntries tests paging through RSS reader entries
+func TestGetEntries(t *testing.T) {
+	entries := make([]Entry, 250)
+	for i := range entries {
+		entries[i] = Entry{ID: i + 1}
+	}
+	var queries []url.Values
+	server := newReaderServer(t, entries, &queries)
+
+	got, err := NewReaderClient(server.URL, "key").Entries(url.Values{"starred": {"true"}}, 1000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(got) != 250 || got[249].ID != 250 {
+		t.Errorf("Expected all 250 entries, got %d", len(got))
+	}
+	if len(queries) != 3 || queries[2].Get("offset") != "200" || queries[2].Get("starred") != "true" {
+		t.Errorf("Expected 3 paged queries keeping the filter, got %v", queries)
+	}
+
+	queries = nil
+	if got, _ := NewReaderClient(server.URL, "key").Entries(url.Values{}, 150); len(got) != 150 || queries[1].Get("limit") != "50" {
+		t.Errorf("Expected entries capped at the limit, got %d with queries %v", len(got), queries)
+	}
+}
+
+// TestHarvestQuery tests turning harvest options into entry filters
+func TestHarvestQuery(t *testing.T) {
+	var queries []url.Values
+	server := newReaderServer(t, nil, &queries)
+	s := &settings{reader: NewReaderClient(server.URL, "")}
+
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query, err := s.harvestQuery(HarvestOptions{Starred: true, Category: "link blogs", After: after})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := url.Values{"starred": {"true"}, "category_id": {"7"}, "after": {"1704067200"}}
+	if query.Encode() != expected.Encode() {
+		t.Errorf("Expected %q, got %q", expected.Encode(), query.Encode())
+	}
+
+	if _, err := s.harvestQuery(HarvestOptions{Category: "Missing"}); err == nil {
+		t.Error("Expected error for unknown category, got none")
+	}
+}
+
+// TestHarvestSkipsSubscribedSites tests that sites already subscribed to, or
+// linked from too few entries, aren't looked at
+func TestHarvestSkipsSubscribedSites(t *testing.T) {
+	entries := []Entry{
+		{URL: "https://alice.example.org/1", Content: `<a href="https://bob.example.net/">Bob</a>`, Feed: Feed{SiteURL: "https://links.example.com/"}},
+		{URL: "https://alice.example.org/2", Content: `<a href="https://links.example.com/about">About</a>`, Feed: Feed{SiteURL: "https://links.example.com/"}},
+		{URL: "https://links.example.com/3"},
+	}
+	var queries []url.Values
+	server := newReaderServer(t, entries, &queries)
+
+	conf := config.Config{RSSReaderEndpoint: server.URL}
+	report, err := Harvest(HarvestOptions{Starred: true}, "Found", false, conf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("Expected no candidates, got %+v", report.Results)
+	}
+	if len(queries) != 1 || queries[0].Get("starred") != "true" {
+		t.Errorf("Expected one starred entries query, got %v", queries)
+	}
+	// Podcasts found would have nowhere to go
+	conf.PodcastCategory = "Missing"
+	if _, err := Harvest(HarvestOptions{Starred: true}, "Found", false, conf); err == nil || !strings.Contains(err.Error(), "podcast category Missing") {
+		t.Errorf("Expected an error for the missing podcast category, got %v", err)
+	}
+}
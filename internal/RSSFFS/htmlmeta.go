@@ -0,0 +1,91 @@
+package RSSFFS
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// headLink is a <link> element found in a page's <head>
+type headLink struct {
+	Rel      string
+	Href     string
+	Type     string
+	Hreflang string
+	Title    string
+}
+
+// headMeta holds the <meta> and <link> elements of a page's <head>
+type headMeta struct {
+	// Meta maps lowercased name, property and http-equiv keys to their content
+	Meta  map[string]string
+	Links []headLink
+}
+
+// parseHeadMeta extracts the <meta> and <link> elements from an HTML document,
+// stopping at <body> since neither belongs there
+func parseHeadMeta(body []byte) headMeta {
+	meta := headMeta{Meta: make(map[string]string)}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			return meta
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := tokenizer.Token()
+			switch t.Data {
+			case "body":
+				return meta
+			case "meta":
+				var key, content string
+				for _, attr := range t.Attr {
+					switch attr.Key {
+					case "name", "property", "http-equiv", "itemprop":
+						key = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+				if key != "" {
+					if _, exists := meta.Meta[key]; !exists {
+						meta.Meta[key] = content
+					}
+				}
+			case "link":
+				var link headLink
+				for _, attr := range t.Attr {
+					switch attr.Key {
+					case "rel":
+						link.Rel = attr.Val
+					case "href":
+						link.Href = strings.TrimSpace(attr.Val)
+					case "type":
+						link.Type = strings.ToLower(attr.Val)
+					case "hreflang":
+						link.Hreflang = attr.Val
+					case "title":
+						link.Title = attr.Val
+					}
+				}
+				meta.Links = append(meta.Links, link)
+			}
+		}
+	}
+}
+
+// link returns the href of the first <link> with the given rel token, or an empty string
+func (m headMeta) link(rel string) string {
+	for _, link := range m.Links {
+		if hasRelToken(link.Rel, rel) {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// metaContains reports whether the meta value for key contains substr, case-insensitively
+func (m headMeta) metaContains(key string, substr string) bool {
+	return strings.Contains(strings.ToLower(m.Meta[key]), strings.ToLower(substr))
+}
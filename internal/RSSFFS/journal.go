@@ -0,0 +1,218 @@
+package RSSFFS
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// journalMu serialises reading and rewriting the run journal within a process
+var journalMu sync.Mutex
+
+// JournalRun is a run recorded in the run journal: the feeds it subscribed
+// to, so that they can be unsubscribed from again with Undo
+type JournalRun struct {
+	ID       string        `json:"id"`
+	Time     time.Time     `json:"time"`
+	Mode     string        `json:"mode"`
+	Input    string        `json:"input,omitempty"`
+	Feeds    []JournalFeed `json:"feeds"`
+	UndoneAt time.Time     `json:"undone_at,omitzero"`
+}
+
+// JournalFeed is a feed subscribed to by a run. ID is the RSS reader's feed
+// ID, 0 when it didn't give one, and Removed is set once Undo has
+// unsubscribed from it.
+type JournalFeed struct {
+	ID         int    `json:"id"`
+	URL        string `json:"url"`
+	CategoryID int    `json:"category_id"`
+	Category   string `json:"category,omitempty"`
+	Removed    bool   `json:"removed,omitempty"`
+}
+
+// Undone reports whether every feed the run subscribed to has been unsubscribed from
+func (r JournalRun) Undone() bool {
+	return !r.UndoneAt.IsZero()
+}
+
+// readJournal returns the runs recorded in the journal at path, oldest first
+func readJournal(path string) ([]JournalRun, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading run journal %s: %w", path, err)
+	}
+	var runs []JournalRun
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("error parsing run journal %s: %w", path, err)
+	}
+	return runs, nil
+}
+
+// writeJournal replaces the journal at path with runs, atomically
+func writeJournal(path string, runs []JournalRun) error {
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error writing run journal %s: %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("error writing run journal %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing run journal %s: %w", path, err)
+	}
+	return nil
+}
+
+// newRunID returns an ID for a run started at now, sortable by time, e.g. "20240131-154500-3fa2"
+func newRunID(now time.Time) string {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	return now.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// recordRun adds the feeds a run subscribed to to the run journal at path,
+// returning the run's ID, or "" when there is no journal or nothing was subscribed
+func recordRun(path string, mode string, input string, feeds []JournalFeed) string {
+	if path == "" || len(feeds) == 0 {
+		return ""
+	}
+
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	runs, err := readJournal(path)
+	if err != nil {
+		log.Errorf("%s: Could not record run: %v", mode, err)
+		return ""
+	}
+	now := time.Now()
+	run := JournalRun{ID: newRunID(now), Time: now, Mode: mode, Input: input, Feeds: feeds}
+	if err := writeJournal(path, append(runs, run)); err != nil {
+		log.Errorf("%s: Could not record run: %v", mode, err)
+		return ""
+	}
+	log.Infof("%s: Recorded %d subscribed feeds as run %s", mode, len(feeds), run.ID)
+	return run.ID
+}
+
+// ListRuns returns the runs recorded in the run journal, newest first
+func ListRuns(conf config.Config) ([]JournalRun, error) {
+	if conf.JournalFile == "" {
+		return nil, errors.New("no run journal is configured (set RSSFFS_JOURNAL_FILE)")
+	}
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	runs, err := readJournal(conf.JournalFile)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	return runs, nil
+}
+
+// Undo unsubscribes from the feeds a run subscribed to, given its ID, or the
+// latest run not yet undone when runID is empty. With dryRun nothing is
+// unsubscribed from, and the run is returned to preview what would be.
+// Feeds already removed from the RSS reader count as unsubscribed from, and
+// feeds that couldn't be are left in the journal to try again.
+func Undo(runID string, dryRun bool, conf config.Config) (*JournalRun, error) {
+	if conf.JournalFile == "" {
+		return nil, errors.New("no run journal is configured (set RSSFFS_JOURNAL_FILE)")
+	}
+
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	runs, err := readJournal(conf.JournalFile)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].ID == runID || (runID == "" && !runs[i].Undone()) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		if runID == "" {
+			return nil, errors.New("no run to undo")
+		}
+		return nil, fmt.Errorf("run %s not found", runID)
+	}
+	run := &runs[index]
+	if run.Undone() {
+		return nil, fmt.Errorf("run %s was already undone at %s", run.ID, run.UndoneAt.Format(time.RFC3339))
+	}
+	if dryRun {
+		return run, nil
+	}
+
+	// Feeds whose ID the RSS reader didn't give are looked up by URL
+	client := NewReaderClient(conf.RSSReaderEndpoint, conf.RSSReaderAPIKey)
+	var subscribed *subscriptions
+	var failed []error
+	for i := range run.Feeds {
+		feed := &run.Feeds[i]
+		if feed.Removed {
+			continue
+		}
+		id := feed.ID
+		if id == 0 {
+			if subscribed == nil {
+				feeds, err := client.Feeds()
+				if err != nil {
+					return nil, fmt.Errorf("error getting subscribed feeds: %w", err)
+				}
+				subscribed = newSubscriptions(feeds)
+			}
+			if existing, ok := subscribed.byURL[canonicalURL(feed.URL)]; ok {
+				id = existing.ID
+			}
+		}
+
+		if id == 0 {
+			log.Infof("Undo: RSS feed %s is no longer subscribed to", feed.URL)
+		} else if err := client.DeleteFeed(id); err != nil {
+			var readerErr *ReaderError
+			if !errors.As(err, &readerErr) || readerErr.StatusCode != http.StatusNotFound {
+				log.Errorf("Undo: Error unsubscribing from RSS feed %s: %v", feed.URL, err)
+				failed = append(failed, fmt.Errorf("%s: %w", feed.URL, err))
+				continue
+			}
+			log.Infof("Undo: RSS feed %s is no longer subscribed to", feed.URL)
+		} else {
+			log.Infof("Undo: Unsubscribed from RSS feed %s", feed.URL)
+		}
+		feed.Removed = true
+	}
+	if len(failed) == 0 {
+		run.UndoneAt = time.Now()
+	}
+
+	if err := writeJournal(conf.JournalFile, runs); err != nil {
+		return run, err
+	}
+	if len(failed) > 0 {
+		return run, fmt.Errorf("could not unsubscribe from %d feeds: %w", len(failed), errors.Join(failed...))
+	}
+	return run, nil
+}
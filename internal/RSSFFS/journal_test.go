@@ -0,0 +1,118 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/toozej/RSSFFS/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// withoutRateLimit lets a test make RSS reader requests without waiting on the rate limiter
+func withoutRateLimit(t *testing.T) {
+	t.Helper()
+	saved := limiter
+	limiter = rate.NewLimiter(rate.Inf, 0)
+	t.Cleanup(func() { limiter = saved })
+}
+
+// TestSubscribeCandidatesRecordsRun tests that the feeds a run subscribes to
+// are recorded in the run journal with their IDs and categories
+func TestSubscribeCandidatesRecordsRun(t *testing.T) {
+	withoutRateLimit(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/feeds" {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"feed_id": 42}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	s := &settings{reader: NewReaderClient(server.URL, ""), journalFile: filepath.Join(t.TempDir(), "runs", "journal.json")}
+
+	report := s.subscribeCandidates([]Candidate{{URL: "https://example.com/feed.xml", Info: &FeedInfo{}}}, "https://example.com/links", 5, false, "Test")
+	if report.RunID == "" || report.Results[0].FeedID != 42 {
+		t.Fatalf("Expected the run recorded with the feed's ID, got %+v", report)
+	}
+
+	// Debug runs subscribe to nothing, so aren't recorded
+	if report := s.subscribeCandidates([]Candidate{{URL: "https://example.org/feed.xml", Info: &FeedInfo{}}}, "", 5, true, "Test"); report.RunID != "" {
+		t.Errorf("Expected a debug run not to be recorded, got run %s", report.RunID)
+	}
+
+	runs, err := ListRuns(config.Config{JournalFile: s.journalFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(runs) != 1 || runs[0].ID != report.RunID || runs[0].Input != "https://example.com/links" || runs[0].Mode != "Test" {
+		t.Fatalf("Expected one recorded run, got %+v", runs)
+	}
+	if feeds := runs[0].Feeds; len(feeds) != 1 || feeds[0] != (JournalFeed{ID: 42, URL: "https://example.com/feed.xml", CategoryID: 5}) {
+		t.Errorf("Expected the subscribed feed recorded, got %+v", feeds)
+	}
+}
+
+// TestUndo tests previewing and undoing runs
+func TestUndo(t *testing.T) {
+	withoutRateLimit(t)
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/feeds":
+			_, _ = fmt.Fprint(w, `[{"id": 9, "feed_url": "https://carol.example.org/feed.xml"}]`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/feeds/8":
+			// Already removed by hand
+			http.NotFound(w, r)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1/feeds/"):
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	conf := config.Config{RSSReaderEndpoint: server.URL, JournalFile: filepath.Join(t.TempDir(), "journal.json")}
+	first := recordRun(conf.JournalFile, "Test", "https://example.com/", []JournalFeed{{ID: 7, URL: "https://alice.example.org/feed.xml"}})
+	second := recordRun(conf.JournalFile, "Test", "https://example.net/", []JournalFeed{
+		{ID: 8, URL: "https://bob.example.org/feed.xml"},
+		{URL: "https://carol.example.org/feed.xml"},
+	})
+
+	// The latest run is undone by default, after a preview that changes nothing
+	run, err := Undo("", true, conf)
+	if err != nil || run.ID != second || len(deleted) != 0 {
+		t.Fatalf("Expected a preview of run %s, got %+v, %v after deleting %v", second, run, err, deleted)
+	}
+	if run, err = Undo("", false, conf); err != nil || !run.Undone() {
+		t.Fatalf("Expected run %s undone, got %+v, %v", second, run, err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/v1/feeds/9" {
+		t.Errorf("Expected carol's feed looked up by URL and deleted, got %v", deleted)
+	}
+
+	// The next undo picks the earlier run, and undone runs can't be undone again
+	if _, err := Undo(second, false, conf); err == nil {
+		t.Error("Expected error undoing a run twice, got none")
+	}
+	if run, err = Undo("", false, conf); err != nil || run.ID != first {
+		t.Fatalf("Expected run %s undone, got %+v, %v", first, run, err)
+	}
+	if _, err := Undo("", true, conf); err == nil {
+		t.Error("Expected error with no run left to undo, got none")
+	}
+	if _, err := Undo("missing", true, conf); err == nil {
+		t.Error("Expected error for an unknown run, got none")
+	}
+
+	runs, _ := ListRuns(conf)
+	if len(runs) != 2 || runs[0].ID != second || !runs[0].Feeds[0].Removed || !runs[1].Undone() {
+		t.Errorf("Expected both runs recorded as undone, newest first, got %+v", runs)
+	}
+}
@@ -0,0 +1,215 @@
+package RSSFFS
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+)
+
+// wellKnownRecommendationsPath is where sites publish their blogroll when they
+// don't advertise it with a <link rel="blogroll"> element
+const wellKnownRecommendationsPath = "/.well-known/recommendations.opml"
+
+// opmlDocument is the subset of an OPML file RSSFFS cares about
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr,omitempty"`
+	Title   string        `xml:"head>title"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is a single, possibly nested, OPML outline element
+type opmlOutline struct {
+	Text     string        `xml:"text,attr,omitempty"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// parseOPML returns every feed URL listed in an OPML document, in document order
+// and without duplicates. Nested outlines (folders) are flattened.
+func parseOPML(r io.Reader) ([]string, error) {
+	var doc opmlDocument
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// OPML files in the wild are overwhelmingly UTF-8 or ASCII, even when they claim otherwise
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OPML document: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var feeds []string
+	var walk func(outlines []opmlOutline)
+	walk = func(outlines []opmlOutline) {
+		for _, outline := range outlines {
+			feedURL := strings.TrimSpace(outline.XMLURL)
+			if feedURL != "" && !seen[feedURL] {
+				seen[feedURL] = true
+				feeds = append(feeds, feedURL)
+			}
+			walk(outline.Outlines)
+		}
+	}
+	walk(doc.Body)
+
+	return feeds, nil
+}
+
+// writeOPML writes feeds to w as an OPML 2.0 document titled title, with
+// the feeds nested in an outline named after the category they are in, so
+// that importing it into an RSS reader restores them to their category
+func writeOPML(w io.Writer, title string, category string, feeds []Feed) error {
+	folder := opmlOutline{Text: category, Title: category}
+	for _, feed := range feeds {
+		name := feed.Title
+		if name == "" {
+			name = feed.FeedURL
+		}
+		folder.Outlines = append(folder.Outlines, opmlOutline{Text: name, Title: name, Type: "rss", XMLURL: feed.FeedURL, HTMLURL: feed.SiteURL})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(opmlDocument{Version: "2.0", Title: title, Body: []opmlOutline{folder}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// isOPML reports whether a fetched document looks like an OPML file, based on
+// its Content-Type header and, failing that, its root element
+func isOPML(contentType string, body []byte) bool {
+	if strings.Contains(contentType, "opml") {
+		return true
+	}
+	if strings.Contains(contentType, "html") {
+		return false
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return strings.EqualFold(start.Name.Local, "opml")
+		}
+	}
+}
+
+// findBlogrollURL returns the absolute URL of the OPML blogroll advertised by an
+// HTML page via <link rel="blogroll">, or an empty string if there is none
+func findBlogrollURL(body []byte, base *url.URL) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := tokenizer.Token()
+			if t.Data == "body" {
+				// <link> elements belong in <head>, no need to scan the whole page
+				return ""
+			}
+			if t.Data != "link" {
+				continue
+			}
+			var rel, href string
+			for _, attr := range t.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = attr.Val
+				case "href":
+					href = attr.Val
+				}
+			}
+			if href == "" || !hasRelToken(rel, "blogroll") {
+				continue
+			}
+			ref, err := url.Parse(strings.TrimSpace(href))
+			if err != nil {
+				continue
+			}
+			return base.ResolveReference(ref).String()
+		}
+	}
+}
+
+// hasRelToken reports whether a space-separated rel attribute contains token
+func hasRelToken(rel string, token string) bool {
+	for _, field := range strings.Fields(rel) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchOPMLFeeds downloads an OPML file and returns the feeds listed in it
+func fetchOPMLFeeds(opmlURL string) ([]string, error) {
+	page, err := fetchPage(opmlURL)
+	if err != nil {
+		return nil, err
+	}
+	if !isOPML(page.ContentType, page.Body) {
+		return nil, fmt.Errorf("%s is not an OPML document", opmlURL)
+	}
+	return parseOPML(bytes.NewReader(page.Body))
+}
+
+// findBlogrollFeeds looks for a blogroll published by the site behind page,
+// first via <link rel="blogroll"> and then at /.well-known/recommendations.opml.
+// It returns the feeds listed in the blogroll as candidates labelled
+// "recommended by <site>", or nil if the site has no blogroll.
+func findBlogrollFeeds(page *fetchedPage) []Candidate {
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil
+	}
+
+	locations := []string{}
+	if blogrollURL := findBlogrollURL(page.Body, base); blogrollURL != "" {
+		locations = append(locations, blogrollURL)
+	}
+	locations = append(locations, (&url.URL{Scheme: base.Scheme, Host: base.Host, Path: wellKnownRecommendationsPath}).String())
+
+	for _, location := range locations {
+		log.Debugf("Checking for blogroll OPML at: %s", location)
+		feeds, err := fetchOPMLFeeds(location)
+		if err != nil {
+			log.Debugf("No usable blogroll at %s: %v", location, err)
+			continue
+		}
+		if len(feeds) == 0 {
+			continue
+		}
+		log.Infof("Found blogroll with %d feeds at: %s", len(feeds), location)
+		return candidatesFromURLs(feeds, "recommended by "+base.Hostname())
+	}
+
+	return nil
+}
+
+// candidatesFromURLs wraps a list of feed URLs into candidates sharing a source label
+func candidatesFromURLs(feeds []string, source string) []Candidate {
+	candidates := make([]Candidate, 0, len(feeds))
+	for _, feed := range feeds {
+		candidates = append(candidates, Candidate{URL: feed, Source: source})
+	}
+	return candidates
+}
@@ -0,0 +1,152 @@
+package RSSFFS
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testOPML = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>My blogroll</title></head>
+  <body>
+    <outline text="Alice" type="rss" xmlUrl="https://alice.example.com/feed.xml" htmlUrl="https://alice.example.com/"/>
+    <outline text="Friends">
+      <outline text="Bob" type="rss" xmlUrl="https://bob.example.org/index.xml"/>
+      <outline text="Alice again" type="rss" xmlUrl="https://alice.example.com/feed.xml"/>
+    </outline>
+    <outline text="Just a link" htmlUrl="https://carol.example.net/"/>
+  </body>
+</opml>`
+
+// TestParseOPML tests that feeds are extracted from flat and nested outlines without duplicates
+func TestParseOPML(t *testing.T) {
+	feeds, err := parseOPML(strings.NewReader(testOPML))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"https://alice.example.com/feed.xml", "https://bob.example.org/index.xml"}
+	if !reflect.DeepEqual(feeds, expected) {
+		t.Errorf("Expected feeds %v, got %v", expected, feeds)
+	}
+}
+
+// TestParseOPMLInvalid tests that non-OPML input is rejected
+func TestParseOPMLInvalid(t *testing.T) {
+	if _, err := parseOPML(strings.NewReader("<html><body>nope</body></html>")); err == nil {
+		t.Error("Expected error for HTML input, got none")
+	}
+}
+
+// TestIsOPML tests OPML detection from content type and document root
+func TestIsOPML(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    bool
+	}{
+		{
+			name:        "OPML content type",
+			contentType: "text/x-opml",
+			body:        "",
+			expected:    true,
+		},
+		{
+			name:        "Generic XML content type with OPML root",
+			contentType: "application/xml; charset=utf-8",
+			body:        testOPML,
+			expected:    true,
+		},
+		{
+			name:        "No content type with OPML root",
+			contentType: "",
+			body:        testOPML,
+			expected:    true,
+		},
+		{
+			name:        "RSS feed",
+			contentType: "application/rss+xml",
+			body:        `<?xml version="1.0"?><rss version="2.0"><channel></channel></rss>`,
+			expected:    false,
+		},
+		{
+			name:        "HTML page",
+			contentType: "text/html; charset=utf-8",
+			body:        "<html><body><opml></opml></body></html>",
+			expected:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOPML(tt.contentType, []byte(tt.body)); got != tt.expected {
+				t.Errorf("Expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestFindBlogrollURL tests discovery of <link rel="blogroll"> elements
+func TestFindBlogrollURL(t *testing.T) {
+	base, _ := url.Parse("https://example.com/about/")
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "Relative href",
+			body:     `<html><head><link rel="blogroll" type="text/xml" href="/blogroll.opml"></head><body></body></html>`,
+			expected: "https://example.com/blogroll.opml",
+		},
+		{
+			name:     "Absolute href with multiple rel tokens",
+			body:     `<html><head><link rel="alternate Blogroll" href="https://cdn.example.net/roll.opml" /></head></html>`,
+			expected: "https://cdn.example.net/roll.opml",
+		},
+		{
+			name:     "No blogroll link",
+			body:     `<html><head><link rel="alternate" type="application/rss+xml" href="/feed"></head></html>`,
+			expected: "",
+		},
+		{
+			name:     "Link in body is ignored",
+			body:     `<html><head></head><body><link rel="blogroll" href="/blogroll.opml"></body></html>`,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findBlogrollURL([]byte(tt.body), base); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestCandidatesFromURLs tests that every feed is labelled with the shared source
+func TestCandidatesFromURLs(t *testing.T) {
+	candidates := candidatesFromURLs([]string{"https://a.example.com/feed", "https://b.example.com/rss"}, "recommended by example.com")
+
+	if len(candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %d", len(candidates))
+	}
+	for _, candidate := range candidates {
+		if candidate.Source != "recommended by example.com" {
+			t.Errorf("Expected source to be set, got %q", candidate.Source)
+		}
+	}
+
+	report := &Report{Results: []Result{
+		{Candidate: candidates[0], Subscribed: true},
+		{Candidate: candidates[1], Error: "failed"},
+	}}
+	if report.SubscribedCount() != 1 {
+		t.Errorf("Expected 1 subscribed feed, got %d", report.SubscribedCount())
+	}
+}
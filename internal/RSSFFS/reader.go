@@ -1,11 +1,17 @@
 package RSSFFS
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/time/rate"
 
@@ -13,24 +19,106 @@ import (
 )
 
 type Feed struct {
-	ID int `json:"id"`
+	ID       int      `json:"id"`
+	Title    string   `json:"title"`
+	FeedURL  string   `json:"feed_url"`
+	SiteURL  string   `json:"site_url"`
+	Category Category `json:"category"`
+
+	// CheckedAt is when the RSS reader last fetched the feed, and
+	// ParsingErrorCount and ParsingErrorMessage describe how fetching it
+	// has been failing since
+	CheckedAt           time.Time `json:"checked_at"`
+	ParsingErrorCount   int       `json:"parsing_error_count"`
+	ParsingErrorMessage string    `json:"parsing_error_message"`
+	Disabled            bool      `json:"disabled"`
 	// Other fields in the feed struct can be added as needed
 }
 
+// Status describes whether the RSS reader is fetching the feed: "disabled",
+// "error: <message>" once fetching it has failed, or "ok"
+func (f Feed) Status() string {
+	switch {
+	case f.Disabled:
+		return "disabled"
+	case f.ParsingErrorCount > 0:
+		if f.ParsingErrorMessage == "" {
+			return fmt.Sprintf("error (%d failures)", f.ParsingErrorCount)
+		}
+		return "error: " + f.ParsingErrorMessage
+	}
+	return "ok"
+}
+
+// Entry is an entry (feed item) stored by the RSS reader
+type Entry struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content"`
+	Starred bool   `json:"starred"`
+	Feed    Feed   `json:"feed"`
+}
+
+// entriesPageSize is how many entries are requested from the RSS reader at a time
+const entriesPageSize = 100
+
+// readerTimeout caps how long a request to the RSS reader API may take
+const readerTimeout = 30 * time.Second
+
 var limiter = rate.NewLimiter(1, 5) // Allow 1 request per second with a burst size of 1
 
-func getCategoryId(apiEndpoint, apiKey, category string) (int, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf(`%s/v1/categories`, apiEndpoint), nil)
+// errUnreadableResponse is wrapped by errors decoding a successful response
+var errUnreadableResponse = errors.New("error reading response")
+
+// ReaderClient makes requests to the RSS reader's (Miniflux's) API. Requests
+// that change anything wait for the package's rate limiter, and refused
+// requests give a *ReaderError.
+type ReaderClient struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewReaderClient returns a client for the RSS reader API at endpoint,
+// authenticating with apiKey
+func NewReaderClient(endpoint string, apiKey string) *ReaderClient {
+	return &ReaderClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: readerTimeout},
+	}
+}
+
+// do sends a method request for path, with body encoded as JSON when given,
+// and decodes the JSON response into v when given. op describes the request
+// in errors, e.g. "subscribe". An empty response body leaves v unchanged.
+func (c *ReaderClient) do(method string, path string, body interface{}, v interface{}, op string) error {
+	if method != http.MethodGet {
+		// Wait for permission to proceed from the rate limiter
+		if err := limiter.Wait(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.endpoint+path, reqBody)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	req.Header.Set("X-Auth-Token", apiKey)
+	req.Header.Set("X-Auth-Token", c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req) // #nosec G704 -- apiEndpoint is from config, not user input
+	resp, err := c.client.Do(req) // #nosec G704 -- endpoint is from config, not user input
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -38,140 +126,188 @@ func getCategoryId(apiEndpoint, apiKey, category string) (int, error) {
 		}
 	}()
 
-	// Check for a successful response
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed to fetch categories, status code: %d", resp.StatusCode)
+	if resp.StatusCode >= 400 || (method == http.MethodGet && resp.StatusCode != http.StatusOK) {
+		log.Debugf("Got response %s when trying to %s", resp.Status, op)
+		return newReaderError(op, resp)
 	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w to %s: %v", errUnreadableResponse, op, err)
+	}
+	return nil
+}
 
-	// Parse the JSON response
+// Categories returns every category in the RSS reader, with their feed and
+// unread counts when the RSS reader gives them
+func (c *ReaderClient) Categories() ([]Category, error) {
 	var categories []Category
-	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
-		return 0, err
+	if err := c.do(http.MethodGet, "/v1/categories?counts=true", nil, &categories, "fetch categories"); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// Category returns the category titled title (case insensitive). A category
+// that doesn't exist gives a *CategoryNotFoundError suggesting similarly
+// named ones.
+func (c *ReaderClient) Category(title string) (Category, error) {
+	categories, err := c.Categories()
+	if err != nil {
+		return Category{}, err
 	}
 
 	// Search for the category by title (case insensitive)
 	for _, cat := range categories {
-		if strings.EqualFold(cat.Title, category) {
-			log.Debugf("Found RSS reader category %s which has ID %d\n", category, cat.ID)
-			return cat.ID, nil
+		if strings.EqualFold(cat.Title, title) {
+			log.Debugf("Found RSS reader category %s which has ID %d\n", title, cat.ID)
+			return cat, nil
 		}
 	}
-
-	// Return 0 if the category is not found
-	return 0, nil
+	return Category{}, &CategoryNotFoundError{Name: title, Matches: closeCategoryNames(title, categories)}
 }
 
-func subscribeToFeed(apiEndpoint string, apiKey string, categoryId int, rssFeed string) error {
-	// Wait for permission to proceed from the rate limiter
-	err := limiter.Wait(context.Background())
-	if err != nil {
-		return err
+// CategoryID returns the ID of the category titled title like Category, or
+// 0 when title is empty so the RSS reader's default is used
+func (c *ReaderClient) CategoryID(title string) (int, error) {
+	if title == "" {
+		return 0, nil
 	}
+	category, err := c.Category(title)
+	return category.ID, err
+}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf(`%s/v1/feeds`, apiEndpoint), strings.NewReader(fmt.Sprintf(`{"feed_url": "%s", "category_id": %d}`, rssFeed, categoryId)))
-	if err != nil {
-		return err
+// ResolveCategoryID returns the ID of the category titled title like
+// CategoryID, creating the category when it doesn't exist and create is set
+func (c *ReaderClient) ResolveCategoryID(title string, create bool) (int, error) {
+	id, err := c.CategoryID(title)
+	var notFound *CategoryNotFoundError
+	if !create || !errors.As(err, &notFound) {
+		return id, err
 	}
-	req.Header.Set("X-Auth-Token", apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	created, err := c.CreateCategory(title)
+	return created.ID, err
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req) // #nosec G704 -- apiEndpoint/rssFeed are from config
-	if err != nil {
-		return err
+// CreateCategory creates a category titled title
+func (c *ReaderClient) CreateCategory(title string) (Category, error) {
+	var created Category
+	if err := c.do(http.MethodPost, "/v1/categories", map[string]string{"title": title}, &created, "create category"); err != nil {
+		return Category{}, err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Errorf("Error closing response body: %v", err)
-		}
-	}()
+	log.Infof("Created RSS reader category %s with ID %d", title, created.ID)
+	return created, nil
+}
 
-	if resp.StatusCode >= 400 {
-		log.Debugf("Got response %s with response code %d\n", resp.Status, resp.StatusCode)
-		return fmt.Errorf("failed to subscribe, status code: %d", resp.StatusCode)
+// RenameCategory renames the category with ID id to title
+func (c *ReaderClient) RenameCategory(id int, title string) (Category, error) {
+	var renamed Category
+	if err := c.do(http.MethodPut, fmt.Sprintf("/v1/categories/%d", id), map[string]string{"title": title}, &renamed, "rename category"); err != nil {
+		return Category{}, err
 	}
-
-	log.Info("Subscribed to RSS feed: ", rssFeed)
-	return nil
+	log.Infof("Renamed RSS reader category ID %d to %s", id, title)
+	return renamed, nil
 }
 
-func getCategoryFeeds(apiEndpoint string, apiKey string, categoryId int) ([]int, error) {
-	url := fmt.Sprintf("%s/v1/categories/%d/feeds", apiEndpoint, categoryId)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// DeleteCategory deletes the category with ID id. The RSS reader deletes
+// the feeds in it along with it.
+func (c *ReaderClient) DeleteCategory(id int) error {
+	if err := c.do(http.MethodDelete, fmt.Sprintf("/v1/categories/%d", id), nil, nil, "delete category"); err != nil {
+		return err
 	}
-	req.Header.Set("X-Auth-Token", apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	log.Infof("Deleted RSS reader category ID %d", id)
+	return nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req) // #nosec G704 -- apiEndpoint is from config
-	if err != nil {
+// CategoryFeeds returns the feeds in the category with ID categoryId
+func (c *ReaderClient) CategoryFeeds(categoryId int) ([]Feed, error) {
+	var feeds []Feed
+	if err := c.do(http.MethodGet, fmt.Sprintf("/v1/categories/%d/feeds", categoryId), nil, &feeds, "fetch feeds"); err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Errorf("Error closing response body: %v", err)
-		}
-	}()
-	if resp.StatusCode != http.StatusOK {
-		log.Debugf("Received unexpected status code %d when fetching feeds for category %d", resp.StatusCode, categoryId)
-		return nil, fmt.Errorf("failed to fetch feeds, status code: %d", resp.StatusCode)
-	}
+	log.Infof("Found %d feeds for category ID %d", len(feeds), categoryId)
+	return feeds, nil
+}
 
-	// Parse the response body
+// Feeds returns every feed subscribed to in the RSS reader
+func (c *ReaderClient) Feeds() ([]Feed, error) {
 	var feeds []Feed
-	if err := json.NewDecoder(resp.Body).Decode(&feeds); err != nil {
+	if err := c.do(http.MethodGet, "/v1/feeds", nil, &feeds, "fetch feeds"); err != nil {
 		return nil, err
 	}
+	log.Debugf("Found %d subscribed feeds", len(feeds))
+	return feeds, nil
+}
 
-	// Extract the feed IDs from the feeds
-	var feedIDs []int
-	for _, feed := range feeds {
-		feedIDs = append(feedIDs, feed.ID)
+// Subscribe subscribes to feedURL in categoryId, returning the new feed's
+// ID, or 0 when the RSS reader doesn't say
+func (c *ReaderClient) Subscribe(categoryId int, feedURL string) (int, error) {
+	var created struct {
+		FeedID int `json:"feed_id"`
+	}
+	body := map[string]interface{}{"feed_url": feedURL, "category_id": categoryId}
+	if err := c.do(http.MethodPost, "/v1/feeds", body, &created, "subscribe"); err != nil {
+		if errors.Is(err, errUnreadableResponse) {
+			log.Debugf("Could not read the ID of feed %s: %v", feedURL, err)
+			return 0, nil
+		}
+		return 0, err
 	}
+	log.Info("Subscribed to RSS feed: ", feedURL)
+	return created.FeedID, nil
+}
 
-	log.Infof("Found %d feeds for category ID %d", len(feedIDs), categoryId)
-	return feedIDs, nil
+// RenameFeed renames the subscribed feed with ID feedId to title
+func (c *ReaderClient) RenameFeed(feedId int, title string) error {
+	return c.do(http.MethodPut, fmt.Sprintf("/v1/feeds/%d", feedId), map[string]string{"title": title}, nil, "rename feed")
 }
 
-func deleteFeed(apiEndpoint string, apiKey string, feedId int) error {
-	// Wait for permission to proceed from the rate limiter
-	err := limiter.Wait(context.Background())
-	if err != nil {
-		return err
-	}
+// MoveFeed moves the feed with ID feedId to the category with ID categoryId
+func (c *ReaderClient) MoveFeed(feedId int, categoryId int) error {
+	return c.do(http.MethodPut, fmt.Sprintf("/v1/feeds/%d", feedId), map[string]int{"category_id": categoryId}, nil, "move feed")
+}
 
-	// Construct the URL for the DELETE request
-	url := fmt.Sprintf("%s/v1/feeds/%d", apiEndpoint, feedId)
+// RefreshFeed asks the RSS reader to fetch the feed with ID feedId now
+func (c *ReaderClient) RefreshFeed(feedId int) error {
+	return c.do(http.MethodPut, fmt.Sprintf("/v1/feeds/%d/refresh", feedId), nil, nil, "refresh feed")
+}
 
-	// Create a new DELETE request
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
+// DeleteFeed unsubscribes from the feed with ID feedId
+func (c *ReaderClient) DeleteFeed(feedId int) error {
+	if err := c.do(http.MethodDelete, fmt.Sprintf("/v1/feeds/%d", feedId), nil, nil, "delete feed"); err != nil {
 		return err
 	}
-	req.Header.Set("X-Auth-Token", apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	log.Infof("Successfully deleted feed with ID %d", feedId)
+	return nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req) // #nosec G704 -- apiEndpoint is from config
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Errorf("Error closing response body: %v", err)
+// Entries returns up to limit entries matching query, such as starred=true
+// or category_id=3, newest first, fetching them a page at a time
+func (c *ReaderClient) Entries(query url.Values, limit int) ([]Entry, error) {
+	var entries []Entry
+	for len(entries) < limit {
+		page := url.Values{}
+		for key, values := range query {
+			page[key] = values
 		}
-	}()
-	if resp.StatusCode == http.StatusNoContent {
-		// HTTP 204 No Content means successful deletion
-		log.Infof("Successfully deleted feed with ID %d", feedId)
-		return nil
-	} else if resp.StatusCode >= 400 {
-		log.Debugf("Got response %s with response code %d when deleting feed ID %d", resp.Status, resp.StatusCode, feedId)
-		return fmt.Errorf("failed to delete feed, status code: %d", resp.StatusCode)
-	}
+		page.Set("order", "published_at")
+		page.Set("direction", "desc")
+		page.Set("limit", strconv.Itoa(min(entriesPageSize, limit-len(entries))))
+		page.Set("offset", strconv.Itoa(len(entries)))
 
-	return nil
+		var response struct {
+			Total   int     `json:"total"`
+			Entries []Entry `json:"entries"`
+		}
+		if err := c.do(http.MethodGet, "/v1/entries?"+page.Encode(), nil, &response, "fetch entries"); err != nil {
+			return nil, err
+		}
+		entries = append(entries, response.Entries...)
+		if len(response.Entries) == 0 || len(entries) >= response.Total {
+			break
+		}
+	}
+	log.Debugf("Fetched %d entries from the RSS reader", len(entries))
+	return entries, nil
 }
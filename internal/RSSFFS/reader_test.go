@@ -0,0 +1,96 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestReaderClient tests managing categories and feeds through the reader client
+func TestReaderClient(t *testing.T) {
+	withoutRateLimit(t)
+	var requests []string
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"error_message": "Access Unauthorized"}`)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/categories":
+			_, _ = fmt.Fprint(w, `[{"id": 7, "title": "Link Blogs", "feed_count": 2, "total_unread": 5}]`)
+		case "PUT /v1/categories/7":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"id": 7, "title": "Blogrolls"}`)
+		case "DELETE /v1/categories/7":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /v1/categories/7/feeds":
+			_, _ = fmt.Fprint(w, `[{"id": 3, "feed_url": "https://alice.example.org/feed.xml", "parsing_error_count": 2, "parsing_error_message": "unable to parse feed"}, {"id": 4, "disabled": true}]`)
+		case "PUT /v1/feeds/3", "PUT /v1/feeds/3/refresh":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /v1/feeds":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"feed_id": 42}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error_message": "Category not found for this user"}`)
+		}
+	}))
+	defer server.Close()
+	client := NewReaderClient(server.URL+"/", "key")
+
+	category, err := client.Category("link blogs")
+	if err != nil || category.ID != 7 || category.FeedCount != 2 || category.TotalUnread != 5 {
+		t.Errorf("Expected category 7 with its counts, got %+v, %v", category, err)
+	}
+	if requests[0] != "GET /v1/categories?counts=true" {
+		t.Errorf("Expected categories to be requested with counts, got %s", requests[0])
+	}
+
+	if renamed, err := client.RenameCategory(7, "Blogrolls"); err != nil || renamed.Title != "Blogrolls" {
+		t.Errorf("Expected category to be renamed, got %+v, %v", renamed, err)
+	}
+	if bodies[1]["title"] != "Blogrolls" {
+		t.Errorf("Expected the new title to be sent, got %v", bodies[1])
+	}
+
+	feeds, err := client.CategoryFeeds(7)
+	if err != nil || len(feeds) != 2 {
+		t.Fatalf("Expected 2 feeds, got %v, %v", feeds, err)
+	}
+	if feeds[0].Status() != "error: unable to parse feed" || feeds[1].Status() != "disabled" || (Feed{}).Status() != "ok" {
+		t.Errorf("Unexpected feed statuses %q, %q", feeds[0].Status(), feeds[1].Status())
+	}
+
+	if err := client.DeleteCategory(7); err != nil {
+		t.Errorf("Unexpected error deleting category: %v", err)
+	}
+	if err := client.DeleteCategory(8); !IsReaderError(err, ReaderErrorCategoryNotFound) {
+		t.Errorf("Expected a category not found error, got %v", err)
+	}
+
+	if id, err := client.Subscribe(7, `https://example.com/"feed"`); err != nil || id != 42 {
+		t.Errorf("Expected feed 42, got %d, %v", id, err)
+	}
+	if body := bodies[len(bodies)-1]; body["feed_url"] != `https://example.com/"feed"` || body["category_id"] != float64(7) {
+		t.Errorf("Expected the feed URL and category to be sent as JSON, got %v", body)
+	}
+
+	if err := client.MoveFeed(3, 7); err != nil || bodies[len(bodies)-1]["category_id"] != float64(7) {
+		t.Errorf("Expected the feed to be moved to category 7, got %v, %v", bodies[len(bodies)-1], err)
+	}
+	if err := client.RefreshFeed(3); err != nil || requests[len(requests)-1] != "PUT /v1/feeds/3/refresh" {
+		t.Errorf("Expected the feed to be refreshed, got %s, %v", requests[len(requests)-1], err)
+	}
+
+	if _, err := NewReaderClient(server.URL, "wrong").Categories(); !IsReaderError(err, ReaderErrorUnauthorized) {
+		t.Errorf("Expected an unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ReaderErrorKind classifies why the RSS reader refused a request
+type ReaderErrorKind string
+
+const (
+	// ReaderErrorOther is any failure not classified below
+	ReaderErrorOther ReaderErrorKind = "other"
+	// ReaderErrorDuplicateFeed means the feed is already subscribed to
+	ReaderErrorDuplicateFeed ReaderErrorKind = "duplicate_feed"
+	// ReaderErrorUnauthorized means the API key was missing, wrong or lacks access
+	ReaderErrorUnauthorized ReaderErrorKind = "unauthorized"
+	// ReaderErrorCategoryNotFound means the category doesn't exist for the user
+	ReaderErrorCategoryNotFound ReaderErrorKind = "category_not_found"
+	// ReaderErrorFetch means the RSS reader's crawler couldn't fetch or parse the feed
+	ReaderErrorFetch ReaderErrorKind = "fetch_error"
+	// ReaderErrorRateLimited means the RSS reader asked for fewer requests
+	ReaderErrorRateLimited ReaderErrorKind = "rate_limited"
+)
+
+// maxReaderErrorBody caps how much of an error response is read
+const maxReaderErrorBody = 64 * 1024
+
+// fetchErrorPhrases appear in the messages Miniflux gives when its crawler
+// can't fetch or parse a feed
+var fetchErrorPhrases = []string{"unable to", "unreachable", "remote server", "feed format", "not a valid feed", "timeout", "certificate", "redirect"}
+
+// ReaderError is a request the RSS reader refused, carrying the status code
+// and the message from Miniflux's {"error_message": "..."} response body
+type ReaderError struct {
+	Op         string
+	StatusCode int
+	Message    string
+	Kind       ReaderErrorKind
+}
+
+// Error returns the RSS reader's message, such as "This feed already exists",
+// or the status code when it gave none
+func (e *ReaderError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("failed to %s, status code: %d", e.Op, e.StatusCode)
+}
+
+// newReaderError reads the error message from a failed RSS reader response
+// and classifies it. op describes the request, e.g. "subscribe".
+func newReaderError(op string, resp *http.Response) *ReaderError {
+	e := &ReaderError{Op: op, StatusCode: resp.StatusCode}
+	var body struct {
+		ErrorMessage string `json:"error_message"`
+	}
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, maxReaderErrorBody)); err == nil && json.Unmarshal(data, &body) == nil {
+		e.Message = strings.TrimSpace(body.ErrorMessage)
+	}
+	e.Kind = classifyReaderError(e.StatusCode, e.Message)
+	return e
+}
+
+// classifyReaderError works out the kind of a failure from its status code
+// and message
+func classifyReaderError(statusCode int, message string) ReaderErrorKind {
+	lower := strings.ToLower(message)
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ReaderErrorUnauthorized
+	case statusCode == http.StatusTooManyRequests:
+		return ReaderErrorRateLimited
+	case statusCode == http.StatusConflict || strings.Contains(lower, "already exists"):
+		return ReaderErrorDuplicateFeed
+	case strings.Contains(lower, "category"):
+		return ReaderErrorCategoryNotFound
+	}
+	for _, phrase := range fetchErrorPhrases {
+		if strings.Contains(lower, phrase) {
+			return ReaderErrorFetch
+		}
+	}
+	return ReaderErrorOther
+}
+
+// IsReaderError reports whether err is, or wraps, an RSS reader error of kind
+func IsReaderError(err error, kind ReaderErrorKind) bool {
+	var readerErr *ReaderError
+	return errors.As(err, &readerErr) && readerErr.Kind == kind
+}
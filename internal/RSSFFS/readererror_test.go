@@ -0,0 +1,73 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestReaderErrors tests decoding and classifying the RSS reader's error responses
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		message    string
+		kind       ReaderErrorKind
+	}{
+		{"duplicate feed", http.StatusBadRequest, `{"error_message": "This feed already exists."}`, "This feed already exists.", ReaderErrorDuplicateFeed},
+		{"conflict", http.StatusConflict, `{"error_message": "duplicated feed"}`, "duplicated feed", ReaderErrorDuplicateFeed},
+		{"unauthorized", http.StatusUnauthorized, `{"error_message": "Access Unauthorized"}`, "Access Unauthorized", ReaderErrorUnauthorized},
+		{"category not found", http.StatusBadRequest, `{"error_message": "This category does not exist or does not belong to this user."}`, "This category does not exist or does not belong to this user.", ReaderErrorCategoryNotFound},
+		{"crawler error", http.StatusBadRequest, `{"error_message": "Unable to fetch this resource (Status Code = 404)"}`, "Unable to fetch this resource (Status Code = 404)", ReaderErrorFetch},
+		{"rate limited", http.StatusTooManyRequests, ``, "failed to subscribe, status code: 429", ReaderErrorRateLimited},
+		{"not JSON", http.StatusInternalServerError, `<html>oops</html>`, "failed to subscribe, status code: 500", ReaderErrorOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			recorder.WriteHeader(tt.statusCode)
+			_, _ = fmt.Fprint(recorder, tt.body)
+
+			var err error = newReaderError("subscribe", recorder.Result())
+			if err.Error() != tt.message {
+				t.Errorf("Expected error %q, got %q", tt.message, err.Error())
+			}
+			if !IsReaderError(err, tt.kind) {
+				t.Errorf("Expected a %s reader error, got %+v", tt.kind, err)
+			}
+			if readerErr := err.(*ReaderError); readerErr.StatusCode != tt.statusCode {
+				t.Errorf("Expected status code %d, got %d", tt.statusCode, readerErr.StatusCode)
+			}
+		})
+	}
+}
+
+// TestSubscribeCandidatesReaderErrors tests that a duplicate the RSS reader
+// refuses counts as already subscribed rather than as an error
+func TestSubscribeCandidatesReaderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/feeds":
+			if r.Method == http.MethodGet {
+				_, _ = fmt.Fprint(w, `[]`)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"error_message": "This feed already exists."}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	s := &settings{reader: NewReaderClient(server.URL, "")}
+
+	report := s.subscribeCandidates([]Candidate{{URL: "https://example.com/feed.xml", Info: &FeedInfo{}}}, "", 1, false, "Test")
+	if result := report.Results[0]; result.Error != "" || result.Subscription != SubscriptionExisting {
+		t.Errorf("Expected the duplicate reported as already subscribed, got %+v", result)
+	}
+	if report.AlreadySubscribedCount() != 1 || report.SubscribedCount() != 0 {
+		t.Errorf("Expected the duplicate counted as already subscribed, got %+v", report.Results)
+	}
+}
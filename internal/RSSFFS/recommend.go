@@ -0,0 +1,189 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// defaultRecommendMinMentions is how many subscriptions must link to a site
+// for Recommend to look for its feeds when no threshold is configured
+const defaultRecommendMinMentions = 2
+
+// maxConcurrentSiteFetches caps how many subscribed sites Recommend reads at once
+const maxConcurrentSiteFetches = 8
+
+// Recommend finds feeds that the sites already subscribed to in the RSS
+// reader recommend, in their blogrolls or by linking to them from their home
+// pages. Feeds and sites recommended by at least conf.MinMentions
+// subscriptions (2 by default) are returned, most often recommended first,
+// with Mentions counting the subscriptions recommending each. With top above
+// zero, the first top of them that the rules and feed filter let through are
+// subscribed to in category, or podcast feeds in conf.PodcastCategory when it
+// is set; otherwise none are, and the report lists every recommendation with
+// the rules and feed filter applied.
+func Recommend(top int, category string, debug bool, conf config.Config) (*Report, error) {
+	s, err := newSettings(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	feeds, err := s.reader.Feeds()
+	if err != nil {
+		return nil, fmt.Errorf("recommend: Error getting subscribed feeds: %w", err)
+	}
+	log.Infof("Recommend: Reading the sites of %d subscribed feeds", len(feeds))
+
+	blogrolls, mentions := readSubscribedSites(feeds)
+
+	threshold := conf.MinMentions
+	if threshold <= 0 {
+		threshold = defaultRecommendMinMentions
+	}
+	candidates := s.recommendations(feeds, blogrolls, mentions, threshold)
+
+	if top > 0 {
+		categoryId, err := s.reader.ResolveCategoryID(category, s.createCategories)
+		if err != nil {
+			return nil, fmt.Errorf("error getting categoryId from category %s: %w", category, err)
+		}
+		if err := s.resolvePodcastCategory(conf.PodcastCategory); err != nil {
+			return nil, err
+		}
+		return s.subscribeCandidates(s.topRecommendations(candidates, newSubscriptions(feeds), top), "", categoryId, debug, "Recommend"), nil
+	}
+
+	report := &Report{}
+	now := time.Now()
+	for _, candidate := range candidates {
+		inspectFeed(&candidate)
+		report.Results = append(report.Results, s.evaluateCandidate(candidate, "", now))
+	}
+	return report, nil
+}
+
+// topRecommendations returns the first top candidates that would be
+// subscribed to: those the rules and feed filter let through that aren't
+// already subscribed to, by feed URL or by site. Candidates are read only
+// until top of them are found.
+func (s *settings) topRecommendations(candidates []Candidate, existing *subscriptions, top int) []Candidate {
+	var picked []Candidate
+	now := time.Now()
+	for _, candidate := range candidates {
+		if len(picked) == top {
+			break
+		}
+		if candidate.Info == nil {
+			inspectFeed(&candidate)
+		}
+		if result := s.evaluateCandidate(candidate, "", now); result.Skipped != "" {
+			log.Infof("Recommend: Skipping RSS feed %s: %s", candidate.URL, result.Skipped)
+			continue
+		}
+		if subscription, _ := existing.match(candidate); subscription != SubscriptionNew {
+			log.Infof("Recommend: Already subscribed to RSS feed %s", candidate.URL)
+			continue
+		}
+		picked = append(picked, candidate)
+	}
+	return picked
+}
+
+// recommendations returns the feeds listed in at least threshold blogrolls,
+// and those found on the sites at least threshold subscriptions link to,
+// leaving out feeds and sites already subscribed to. The most often
+// recommended come first.
+func (s *settings) recommendations(feeds []Feed, blogrolls map[string]int, mentions *domainMentions, threshold int) []Candidate {
+	// Feed URLs are compared like canonicalURL does, so that e.g. the http and
+	// https variants of a feed already subscribed to aren't recommended
+	existing := newSubscriptions(feeds)
+	subscribed := func(feedURL string) bool {
+		subscription, _ := existing.match(Candidate{URL: feedURL})
+		return subscription != SubscriptionNew
+	}
+	var subscribedHosts []string
+	for _, feed := range feeds {
+		subscribedHosts = append(subscribedHosts, hostnames(feed.SiteURL, feed.FeedURL)...)
+	}
+
+	// Feeds listed in blogrolls are taken as they are, and linked sites are checked for feeds
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	for feedURL, count := range blogrolls {
+		hosts := hostnames(feedURL)
+		if count < threshold || subscribed(feedURL) || len(hosts) == 0 || containsString(subscribedHosts, hosts[0]) {
+			continue
+		}
+		seen[canonicalURL(feedURL)] = true
+		candidates = append(candidates, Candidate{URL: feedURL, Source: fmt.Sprintf("in %d blogrolls", count), Mentions: count})
+	}
+	domains := mentions.above(threshold, subscribedHosts...)
+	log.Infof("Recommend: Found %d blogroll feeds and %d sites recommended by at least %d subscriptions", len(candidates), len(domains), threshold)
+	if len(domains) > 0 {
+		for _, candidate := range s.checkDomainsForRSS(domains, "", mentions.Counts) {
+			if !seen[canonicalURL(candidate.URL)] && !subscribed(candidate.URL) {
+				seen[canonicalURL(candidate.URL)] = true
+				candidate.Source = fmt.Sprintf("linked from %d subscriptions", candidate.Mentions)
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Mentions != candidates[j].Mentions {
+			return candidates[i].Mentions > candidates[j].Mentions
+		}
+		return candidates[i].URL < candidates[j].URL
+	})
+	return candidates
+}
+
+// readSubscribedSites reads the home page of every subscribed feed's site,
+// counting how many sites list each feed in their blogroll and, for sites
+// without a blogroll, how many link to each domain
+func readSubscribedSites(feeds []Feed) (map[string]int, *domainMentions) {
+	blogrolls := make(map[string]int)
+	mentions := newDomainMentions()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, maxConcurrentSiteFetches)
+	sites := make(map[string]bool)
+	for _, feed := range feeds {
+		if feed.SiteURL == "" || sites[feed.SiteURL] {
+			continue
+		}
+		sites[feed.SiteURL] = true
+
+		wg.Add(1)
+		go func(siteURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			page, err := fetchPage(siteURL)
+			if err != nil {
+				log.Debugf("Recommend: Could not fetch %s: %v", siteURL, err)
+				return
+			}
+			blogroll := findBlogrollFeeds(page)
+			var domains map[string][]string
+			if len(blogroll) == 0 {
+				domains = domainsFromHTML(page.Body)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, candidate := range blogroll {
+				blogrolls[candidate.URL]++
+			}
+			mentions.add(domains)
+		}(feed.SiteURL)
+	}
+	wg.Wait()
+	return blogrolls, mentions
+}
@@ -0,0 +1,65 @@
+package RSSFFS
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRecommendations tests ranking blogroll feeds and leaving out those already subscribed to
+func TestRecommendations(t *testing.T) {
+	feeds := []Feed{
+		{FeedURL: "https://alice.example.org/feed.xml", SiteURL: "https://alice.example.org/"},
+		{FeedURL: "https://feeds.example.com/bob", SiteURL: "https://bob.example.net/"},
+		{FeedURL: "http://www.grace.example.com/feed/"},
+	}
+	blogrolls := map[string]int{
+		"https://carol.example.org/rss":     2,
+		"https://dave.example.org/atom.xml": 5,
+		"https://erin.example.org/feed":     1,
+		"https://alice.example.org/feed":    3,
+		"https://bob.example.net/rss":       4,
+		"not a url":                         9,
+		"https://grace.example.com/feed":    6,
+	}
+
+	// Sites linked from the subscriptions' home pages are only checked above the threshold
+	mentions := newDomainMentions()
+	mentions.add(map[string][]string{"frank.example.org": {"https://frank.example.org/"}, "alice.example.org": {"https://alice.example.org/"}})
+	mentions.add(map[string][]string{"alice.example.org": {"https://alice.example.org/about"}})
+
+	candidates := (&settings{}).recommendations(feeds, blogrolls, mentions, 2)
+	expected := []Candidate{
+		{URL: "https://dave.example.org/atom.xml", Source: "in 5 blogrolls", Mentions: 5},
+		{URL: "https://carol.example.org/rss", Source: "in 2 blogrolls", Mentions: 2},
+	}
+	if len(candidates) != len(expected) {
+		t.Fatalf("Expected %d candidates, got %+v", len(expected), candidates)
+	}
+	for i := range expected {
+		if candidates[i] != expected[i] {
+			t.Errorf("Expected %+v at %d, got %+v", expected[i], i, candidates[i])
+		}
+	}
+}
+
+// TestTopRecommendations tests that the top recommendations are those that
+// would be subscribed to, skipping those filtered out or already subscribed to
+func TestTopRecommendations(t *testing.T) {
+	s := &settings{filter: FeedFilter{MinItems: 2}}
+	existing := newSubscriptions([]Feed{{FeedURL: "https://carol.example.org/rss", SiteURL: "https://carol.example.org/"}})
+	candidates := []Candidate{
+		{URL: "https://dave.example.org/atom.xml", Info: &FeedInfo{ItemCount: 1}},
+		{URL: "https://feeds.example.org/carol", Info: &FeedInfo{ItemCount: 5, Link: "https://carol.example.org"}},
+		{URL: "https://erin.example.org/feed", Info: &FeedInfo{ItemCount: 5}},
+		{URL: "https://frank.example.org/feed", Info: &FeedInfo{ItemCount: 5}},
+		{URL: "https://grace.example.org/feed", Info: &FeedInfo{ItemCount: 5}},
+	}
+
+	var urls []string
+	for _, candidate := range s.topRecommendations(candidates, existing, 2) {
+		urls = append(urls, candidate.URL)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://erin.example.org/feed", "https://frank.example.org/feed"}) {
+		t.Errorf("Expected the two best recommendations that would be subscribed to, got %v", urls)
+	}
+}
@@ -0,0 +1,117 @@
+package RSSFFS
+
+import "errors"
+
+// Candidate is a feed found during discovery along with a description of
+// how it was found, e.g. "recommended by example.com", and the publishing
+// platform detected behind it, e.g. "wordpress". Feeds generated by a bridge
+// such as RSSHub rather than published by the site itself name the bridge.
+// Hreflang is the language the linking page gave for the feed, if any, and
+// Info holds the feed's metadata once its document has been read. Mentions
+// counts the feed items linking to the feed's site, when traversing a feed.
+type Candidate struct {
+	URL      string    `json:"url"`
+	Source   string    `json:"source,omitempty"`
+	Platform string    `json:"platform,omitempty"`
+	Bridge   string    `json:"bridge,omitempty"`
+	Hreflang string    `json:"hreflang,omitempty"`
+	Info     *FeedInfo `json:"info,omitempty"`
+	Mentions int       `json:"mentions,omitempty"`
+}
+
+// Result records what happened to a single candidate during a run. Skipped
+// gives the reason a candidate was left out by a rule or the feed filter. Rule
+// names the rule that matched the candidate, and Category and Title are the
+// category and title it set, if any. Subscription tells whether the feed was
+// new or already subscribed to, in which case SubscribedAs is the URL of the
+// existing feed and Category the category it is in. A new feed from a site
+// already subscribed to has SubscribedAs set to the site's existing feed.
+// FeedID is the RSS reader's ID for a feed subscribed to, when it gave one.
+type Result struct {
+	Candidate
+	Subscribed   bool   `json:"subscribed"`
+	FeedID       int    `json:"feed_id,omitempty"`
+	Skipped      string `json:"skipped,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Rule         string `json:"rule,omitempty"`
+	Category     string `json:"category,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Subscription string `json:"subscription,omitempty"`
+	SubscribedAs string `json:"subscribed_as,omitempty"`
+
+	// ErrorKind classifies an Error returned by the RSS reader
+	ErrorKind ReaderErrorKind `json:"error_kind,omitempty"`
+	// err is the error Error was set from, for callers that need to inspect it
+	err error
+}
+
+// setError records why subscribing to the candidate failed
+func (r *Result) setError(err error) {
+	r.err, r.Error = err, err.Error()
+	var readerErr *ReaderError
+	if errors.As(err, &readerErr) {
+		r.ErrorKind = readerErr.Kind
+	}
+}
+
+// AlreadySubscribed reports whether the candidate was found already
+// subscribed to at its own URL
+func (r Result) AlreadySubscribed() bool {
+	return r.Subscription == SubscriptionExisting
+}
+
+// Report summarises the outcome of a run. RunID identifies the run in the
+// run journal when it subscribed to any feeds, for undoing it. Removed lists
+// the feeds unsubscribed from when clearing the run's category (or that
+// would have been, in debug mode), and Backup is the OPML file the category
+// was backed up to first.
+type Report struct {
+	Results []Result `json:"results"`
+	RunID   string   `json:"run_id,omitempty"`
+	Removed []Feed   `json:"removed,omitempty"`
+	Backup  string   `json:"backup,omitempty"`
+}
+
+// SubscribedCount returns the number of candidates that were subscribed (or
+// would have been, in debug mode)
+func (r *Report) SubscribedCount() int {
+	if r == nil {
+		return 0
+	}
+	count := 0
+	for _, result := range r.Results {
+		if result.Subscribed {
+			count++
+		}
+	}
+	return count
+}
+
+// AlreadySubscribedCount returns the number of candidates that were already
+// subscribed to, which count as successes rather than errors
+func (r *Report) AlreadySubscribedCount() int {
+	if r == nil {
+		return 0
+	}
+	count := 0
+	for _, result := range r.Results {
+		if result.AlreadySubscribed() {
+			count++
+		}
+	}
+	return count
+}
+
+// SkippedCount returns the number of candidates left out by rules or the feed filter
+func (r *Report) SkippedCount() int {
+	if r == nil {
+		return 0
+	}
+	count := 0
+	for _, result := range r.Results {
+		if result.Skipped != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,198 @@
+package RSSFFS
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Resolver maps URLs on a particular platform to the feeds that platform publishes.
+//
+// Resolvers are consulted in priority order before the common feed patterns are
+// probed, so that site-specific knowledge (e.g. Medium's /feed/@user URLs) wins
+// over generic guesses.
+type Resolver interface {
+	// Name identifies the resolver in log output
+	Name() string
+	// Priority orders resolvers; lower values are consulted first
+	Priority() int
+	// Match reports whether the resolver knows how to handle the target
+	Match(t *Target) bool
+	// Candidates returns feed URLs for the target, most preferred first
+	Candidates(t *Target) []string
+}
+
+// Resolver priorities. Resolvers that can decide from the URL alone run before
+// those that need to inspect the page's HTML.
+const (
+	priorityHost = 10
+	priorityPage = 50
+)
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   []Resolver
+)
+
+// registerResolver adds a resolver to the registry, keeping it sorted by priority
+func registerResolver(r Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+
+	resolvers = append(resolvers, r)
+	sort.SliceStable(resolvers, func(i, j int) bool {
+		return resolvers[i].Priority() < resolvers[j].Priority()
+	})
+}
+
+// matchingResolvers returns the registered resolvers that match the target, in priority order
+func matchingResolvers(t *Target) []Resolver {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+
+	var matched []Resolver
+	for _, r := range resolvers {
+		if r.Match(t) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// resolveCandidates returns the feed URLs proposed by every matching resolver,
+// in priority order and without duplicates
+func resolveCandidates(t *Target) []string {
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, r := range matchingResolvers(t) {
+		found := r.Candidates(t)
+		log.Debugf("Resolver %s proposed %d candidate feeds for %s", r.Name(), len(found), t.URL)
+		for _, candidate := range found {
+			if !seen[candidate] {
+				seen[candidate] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// matchesHost reports whether a resolver that knows the target's host by name,
+// such as YouTube or GitHub, matches it without fetching its page
+func matchesHost(t *Target) bool {
+	for _, r := range matchingResolvers(&Target{URL: t.URL, urlOnly: true, run: t.run}) {
+		if r.Priority() <= priorityHost {
+			return true
+		}
+	}
+	return false
+}
+
+// Target is a URL being resolved to feeds, along with its page HTML and
+// response headers which are fetched lazily the first time a resolver asks for them
+type Target struct {
+	URL *url.URL
+
+	once   sync.Once
+	html   []byte
+	header http.Header
+	// offline targets were built from saved HTML, so resolvers must not make
+	// further network requests (e.g. NodeInfo lookups) for them
+	offline bool
+	// urlOnly targets are resolved from their URL alone, so their page is
+	// never fetched and resolvers that need its HTML don't match
+	urlOnly bool
+	// run is the run the target is resolved for, nil outside of one
+	run *settings
+}
+
+// newTarget creates a Target for rawURL, assuming https:// when no scheme is given
+func newTarget(rawURL string) (*Target, error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Target{URL: u}, nil
+}
+
+// newTargetWithHTML creates an offline Target whose page HTML is already known, so it is never fetched
+func newTargetWithHTML(u *url.URL, body []byte) *Target {
+	t := &Target{URL: u, html: body, offline: true}
+	t.once.Do(func() {})
+	return t
+}
+
+// targetForDomain returns a Target for originalURL when it points at domain, or nil otherwise
+func targetForDomain(domain string, originalURL string) *Target {
+	t, err := newTarget(originalURL)
+	if err != nil || !strings.EqualFold(t.URL.Hostname(), domain) {
+		return nil
+	}
+	return t
+}
+
+// HTML returns the target page's HTML, fetching it on first use. It returns nil
+// if the page could not be fetched.
+func (t *Target) HTML() []byte {
+	if t.urlOnly {
+		return nil
+	}
+	t.once.Do(func() {
+		page, err := fetchPage(t.URL.String())
+		if err != nil {
+			log.Debugf("Could not fetch %s for resolvers: %v", t.URL, err)
+			return
+		}
+		t.html = page.Body
+		t.header = page.Header
+	})
+	return t.html
+}
+
+// Header returns the response headers of the target page, fetching it on
+// first use. It returns nil if the page could not be fetched.
+func (t *Target) Header() http.Header {
+	t.HTML()
+	return t.header
+}
+
+// settings returns the settings of the run the target is resolved for. Outside
+// of a run they are empty, and resolvers fall back to their defaults.
+func (t *Target) settings() *settings {
+	if t.run == nil {
+		return &settings{}
+	}
+	return t.run
+}
+
+// Host returns the target's lowercased hostname
+func (t *Target) Host() string {
+	return strings.ToLower(t.URL.Hostname())
+}
+
+// PathSegments returns the non-empty segments of the target's path
+func (t *Target) PathSegments() []string {
+	var segments []string
+	for _, segment := range strings.Split(t.URL.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// origin returns the scheme and host of the target, e.g. "https://example.com"
+func (t *Target) origin() string {
+	scheme := t.URL.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	return scheme + "://" + t.URL.Host
+}
@@ -0,0 +1,102 @@
+package RSSFFS
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// priorityFallback orders resolvers that only apply when no platform-specific
+// resolver knew better
+const priorityFallback = 90
+
+// feedLinkTypes are the <link rel="alternate"> types that advertise a feed
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+	"application/rdf+xml":  true,
+}
+
+// alternateResolver returns the feeds a page advertises with
+// <link rel="alternate"> in its head. When a site offers the same feed in
+// several languages via hreflang, those in the filter's languages come first.
+type alternateResolver struct{}
+
+func init() {
+	registerResolver(alternateResolver{})
+}
+
+func (alternateResolver) Name() string { return "alternate" }
+
+func (alternateResolver) Priority() int { return priorityFallback }
+
+func (alternateResolver) Match(t *Target) bool {
+	return len(alternateFeedLinks(t)) > 0
+}
+
+func (alternateResolver) Candidates(t *Target) []string {
+	links := alternateFeedLinks(t)
+	filter := t.settings().filter
+
+	// Prefer the requested languages, then feeds with no language given, then the rest
+	rank := func(link headLink) int {
+		if len(filter.Languages) == 0 {
+			return 0
+		}
+		if link.Hreflang == "" {
+			return len(filter.Languages)
+		}
+		if r := filter.languageRank(link.Hreflang); r < len(filter.Languages) {
+			return r
+		}
+		return len(filter.Languages) + 1
+	}
+	sort.SliceStable(links, func(i, j int) bool { return rank(links[i]) < rank(links[j]) })
+
+	candidates := make([]string, 0, len(links))
+	for _, link := range links {
+		candidates = append(candidates, link.Href)
+	}
+	return candidates
+}
+
+// alternateFeedLinks returns the feed links in the target page's head with
+// their hrefs made absolute. Comment feeds are left out.
+func alternateFeedLinks(t *Target) []headLink {
+	var links []headLink
+	for _, link := range parseHeadMeta(t.HTML()).Links {
+		if !hasRelToken(link.Rel, "alternate") || !feedLinkTypes[strings.TrimSpace(strings.Split(link.Type, ";")[0])] || link.Href == "" {
+			continue
+		}
+		resolved, err := t.URL.Parse(link.Href)
+		if err != nil || isCommentFeed(link.Title, resolved) {
+			continue
+		}
+		link.Href = resolved.String()
+		links = append(links, link)
+	}
+	return links
+}
+
+// isCommentFeed reports whether a feed link is for comments rather than posts,
+// going by its title or, whatever the site's language, by the URLs WordPress
+// gives comment feeds: /comments/feed/ and ?feed=comments-rss2 (or -atom)
+func isCommentFeed(title string, feedURL *url.URL) bool {
+	if strings.Contains(strings.ToLower(title), "comments") {
+		return true
+	}
+	if strings.Contains(strings.ToLower(feedURL.Path)+"/", "/comments/feed/") {
+		return true
+	}
+	return strings.HasPrefix(strings.ToLower(feedURL.Query().Get("feed")), "comments-")
+}
+
+// feedHreflang returns the hreflang the target page gives for feedURL, if any
+func feedHreflang(t *Target, feedURL string) string {
+	for _, link := range alternateFeedLinks(t) {
+		if link.Href == feedURL {
+			return link.Hreflang
+		}
+	}
+	return ""
+}
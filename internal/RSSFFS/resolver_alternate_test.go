@@ -0,0 +1,68 @@
+package RSSFFS
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// TestAlternateResolver tests feed autodiscovery from <link rel="alternate"> elements
+func TestAlternateResolver(t *testing.T) {
+	runResolverTests(t, alternateResolver{}, []resolverTestCase{
+		{
+			name:      "Plain blog",
+			url:       "https://blog.example.org/",
+			fixture:   "plain_blog.html",
+			wantMatch: true,
+			want:      []string{"https://blog.example.org/index.xml"},
+		},
+		{
+			name:      "Multilingual site in page order",
+			url:       "https://example.eu/",
+			fixture:   "multilingual.html",
+			wantMatch: true,
+			want:      []string{"https://example.eu/de/feed.xml", "https://example.eu/en/atom.xml", "https://example.eu/all.xml"},
+		},
+		{
+			name:      "Comment feeds of a French WordPress site",
+			url:       "https://carnet.example.fr/",
+			fixture:   "wordpress_fr.html",
+			wantMatch: true,
+			want:      []string{"https://carnet.example.fr/feed/"},
+		},
+		{
+			name:      "No feed links",
+			url:       "https://example.com/",
+			fixture:   "youtube_watch.html",
+			wantMatch: false,
+		},
+	})
+}
+
+// TestAlternateResolverLanguagePreference tests that hreflang alternates in the preferred languages come first
+func TestAlternateResolverLanguagePreference(t *testing.T) {
+	u, _ := url.Parse("https://example.eu/")
+	target := newTargetWithHTML(u, loadResolverFixture(t, "multilingual.html"))
+
+	tests := []struct {
+		languages []string
+		expected  []string
+	}{
+		{[]string{"en"}, []string{"https://example.eu/en/atom.xml", "https://example.eu/all.xml", "https://example.eu/de/feed.xml"}},
+		{[]string{"fr", "de"}, []string{"https://example.eu/de/feed.xml", "https://example.eu/all.xml", "https://example.eu/en/atom.xml"}},
+	}
+	for _, tt := range tests {
+		target.run = &settings{filter: FeedFilter{Languages: tt.languages}}
+		got := alternateResolver{}.Candidates(target)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Languages %v: expected %v, got %v", tt.languages, tt.expected, got)
+		}
+	}
+
+	if got := feedHreflang(target, "https://example.eu/en/atom.xml"); got != "en-GB" {
+		t.Errorf("Expected hreflang en-GB, got %q", got)
+	}
+	if got := feedHreflang(target, "https://example.eu/all.xml"); got != "" {
+		t.Errorf("Expected no hreflang, got %q", got)
+	}
+}
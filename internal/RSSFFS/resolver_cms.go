@@ -0,0 +1,305 @@
+package RSSFFS
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Platforms recognised by CMS fingerprinting, reported in Candidate.Platform
+const (
+	PlatformWordPress  = "wordpress"
+	PlatformGhost      = "ghost"
+	PlatformSubstack   = "substack"
+	PlatformButtondown = "buttondown"
+	PlatformBeehiiv    = "beehiiv"
+	PlatformBlogger    = "blogger"
+	PlatformTumblr     = "tumblr"
+	PlatformHugo       = "hugo"
+)
+
+// maxSectionFeeds caps how many section feeds linked from a page are offered as candidates
+const maxSectionFeeds = 20
+
+// platformKeywords maps the names platforms use in generator meta tags and
+// X-Powered-By headers to platform identifiers, checked in order
+var platformKeywords = []struct {
+	keyword  string
+	platform string
+}{
+	{"wordpress", PlatformWordPress},
+	{"ghost", PlatformGhost},
+	{"hugo", PlatformHugo},
+	{"blogger", PlatformBlogger},
+	{"beehiiv", PlatformBeehiiv},
+	{"substack", PlatformSubstack},
+	{"buttondown", PlatformButtondown},
+	{"tumblr", PlatformTumblr},
+}
+
+// platformMarkers are strings that only appear in pages rendered by a platform,
+// such as the hosts its themes load assets from
+var platformMarkers = []struct {
+	marker   string
+	platform string
+}{
+	{"/wp-content/", PlatformWordPress},
+	{"/wp-includes/", PlatformWordPress},
+	{"data-ghost=", PlatformGhost},
+	{"substackcdn.com", PlatformSubstack},
+	{"assets.buttondown.email", PlatformButtondown},
+	{"media.beehiiv.com", PlatformBeehiiv},
+	{"blogger.com/static/", PlatformBlogger},
+	{"assets.tumblr.com", PlatformTumblr},
+}
+
+// paginationSuffix matches the /page/N suffix of paginated archive pages
+var paginationSuffix = regexp.MustCompile(`/page/\d+/?$`)
+
+// cmsResolver maps blogs and newsletters on common publishing platforms to their
+// site-wide feed and to the feeds of their sections, categories, tags and
+// authors. The platform is fingerprinted from the host, generator meta tag,
+// response headers and markup.
+type cmsResolver struct{}
+
+func init() {
+	registerResolver(cmsResolver{})
+}
+
+func (cmsResolver) Name() string { return "cms" }
+
+func (cmsResolver) Priority() int { return priorityPage }
+
+func (cmsResolver) Match(t *Target) bool {
+	return detectPlatform(t) != ""
+}
+
+// Candidates returns the feed of the section being viewed first, then the
+// site-wide feed, then the feeds of sections linked from the page so they can
+// be offered as alternatives
+func (cmsResolver) Candidates(t *Target) []string {
+	platform := detectPlatform(t)
+	origin := t.origin()
+
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(feed string) {
+		if feed != "" && !seen[feed] {
+			seen[feed] = true
+			candidates = append(candidates, feed)
+		}
+	}
+
+	add(sectionFeed(platform, origin, t.URL))
+	add(siteFeed(platform, t))
+
+	sections := 0
+	for _, link := range sameSiteLinks(t) {
+		if sections >= maxSectionFeeds {
+			break
+		}
+		if feed := sectionFeed(platform, origin, link); feed != "" && !seen[feed] {
+			add(feed)
+			sections++
+		}
+	}
+	return candidates
+}
+
+// detectPlatform fingerprints the publishing platform behind a target, returning
+// one of the Platform constants or an empty string when it isn't recognised
+func detectPlatform(t *Target) string {
+	if platform := platformFromHost(t.Host()); platform != "" {
+		return platform
+	}
+
+	body := t.HTML()
+	meta := parseHeadMeta(body)
+	if platform := platformFromKeyword(meta.Meta["generator"]); platform != "" {
+		return platform
+	}
+	if platform := platformFromHeader(t.Header()); platform != "" {
+		return platform
+	}
+
+	// WordPress advertises its REST API (wp-json) with a link relation
+	if meta.link("https://api.w.org/") != "" {
+		return PlatformWordPress
+	}
+	lower := bytes.ToLower(body)
+	for _, m := range platformMarkers {
+		if bytes.Contains(lower, []byte(m.marker)) {
+			return m.platform
+		}
+	}
+	return ""
+}
+
+// platformFromHost recognises sites hosted on a platform's own domain
+func platformFromHost(host string) string {
+	switch {
+	case strings.HasSuffix(host, ".wordpress.com"):
+		return PlatformWordPress
+	case strings.HasSuffix(host, ".ghost.io"):
+		return PlatformGhost
+	case strings.HasSuffix(host, ".substack.com"):
+		return PlatformSubstack
+	case host == "buttondown.com" || host == "buttondown.email":
+		return PlatformButtondown
+	case strings.HasSuffix(host, ".beehiiv.com"):
+		return PlatformBeehiiv
+	case strings.HasSuffix(host, ".blogspot.com"):
+		return PlatformBlogger
+	case strings.HasSuffix(host, ".tumblr.com") && host != "www.tumblr.com":
+		return PlatformTumblr
+	}
+	return ""
+}
+
+// platformFromKeyword recognises a platform named in a generator or X-Powered-By value
+func platformFromKeyword(value string) string {
+	value = strings.ToLower(value)
+	if value == "" {
+		return ""
+	}
+	for _, k := range platformKeywords {
+		if strings.Contains(value, k.keyword) {
+			return k.platform
+		}
+	}
+	return ""
+}
+
+// platformFromHeader recognises the response headers platforms add to every page
+func platformFromHeader(header http.Header) string {
+	if header == nil {
+		return ""
+	}
+	switch {
+	case strings.Contains(header.Get("Link"), "api.w.org"), strings.Contains(header.Get("X-Pingback"), "xmlrpc.php"):
+		return PlatformWordPress
+	case header.Get("X-Ghost-Cache-Status") != "":
+		return PlatformGhost
+	case header.Get("X-Tumblr-User") != "":
+		return PlatformTumblr
+	}
+	return platformFromKeyword(header.Get("X-Powered-By"))
+}
+
+// siteFeed returns the site-wide feed URL for a platform
+func siteFeed(platform string, t *Target) string {
+	origin := t.origin()
+	switch platform {
+	case PlatformWordPress:
+		return origin + "/feed/"
+	case PlatformGhost:
+		return origin + "/rss/"
+	case PlatformSubstack, PlatformBeehiiv:
+		return origin + "/feed"
+	case PlatformButtondown:
+		// Newsletters hosted on buttondown.com live under the author's username
+		if segments := t.PathSegments(); platformFromHost(t.Host()) == PlatformButtondown && len(segments) > 0 {
+			return origin + "/" + segments[0] + "/rss"
+		}
+		return origin + "/rss"
+	case PlatformBlogger:
+		return origin + "/feeds/posts/default"
+	case PlatformTumblr:
+		return origin + "/rss"
+	case PlatformHugo:
+		return origin + "/index.xml"
+	}
+	return ""
+}
+
+// sectionFeed maps a category, tag, author, label or section page on a platform
+// to its feed URL, or returns an empty string when u isn't such a page
+func sectionFeed(platform string, origin string, u *url.URL) string {
+	segments := escapedSegments(paginationSuffix.ReplaceAllString(u.EscapedPath(), "/"))
+	if len(segments) < 2 {
+		return ""
+	}
+
+	switch platform {
+	case PlatformWordPress:
+		// Category paths nest, e.g. /category/parent/child/
+		switch segments[0] {
+		case "category", "tag", "author":
+			return origin + "/" + strings.Join(segments, "/") + "/feed/"
+		}
+	case PlatformGhost:
+		if len(segments) == 2 && (segments[0] == "tag" || segments[0] == "author") {
+			return origin + "/" + segments[0] + "/" + segments[1] + "/rss/"
+		}
+	case PlatformSubstack:
+		if segments[0] == "s" {
+			return origin + "/s/" + segments[1] + "/feed"
+		}
+	case PlatformBlogger:
+		if len(segments) >= 3 && segments[0] == "search" && segments[1] == "label" {
+			return origin + "/feeds/posts/default/-/" + segments[2]
+		}
+	case PlatformTumblr:
+		if segments[0] == "tagged" {
+			return origin + "/tagged/" + segments[1] + "/rss"
+		}
+	case PlatformHugo:
+		if len(segments) == 2 {
+			switch segments[0] {
+			case "tags", "categories", "authors", "series":
+				return origin + "/" + segments[0] + "/" + segments[1] + "/index.xml"
+			}
+		}
+	}
+	return ""
+}
+
+// escapedSegments returns the non-empty segments of an escaped URL path, so
+// that labels containing spaces or slashes keep their escaping
+func escapedSegments(escapedPath string) []string {
+	var segments []string
+	for _, segment := range strings.Split(escapedPath, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// sameSiteLinks returns the distinct URLs on the target's host linked from its
+// page, with relative links resolved against the target URL
+func sameSiteLinks(t *Target) []*url.URL {
+	tokenizer := html.NewTokenizer(bytes.NewReader(t.HTML()))
+	seen := make(map[string]bool)
+	var links []*url.URL
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "a" {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				link, err := t.URL.Parse(attr.Val)
+				if err != nil || !strings.EqualFold(link.Hostname(), t.URL.Hostname()) {
+					continue
+				}
+				link.RawQuery, link.Fragment = "", ""
+				if !seen[link.String()] {
+					seen[link.String()] = true
+					links = append(links, link)
+				}
+			}
+		}
+	}
+}
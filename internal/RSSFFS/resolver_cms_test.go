@@ -0,0 +1,84 @@
+package RSSFFS
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestCMSResolver tests platform detection and section feed enumeration for each supported platform
+func TestCMSResolver(t *testing.T) {
+	runResolverTests(t, cmsResolver{}, []resolverTestCase{
+		{name: "WordPress home enumerates sections", url: "https://blog.example.com/", fixture: "wordpress.html", wantMatch: true, want: []string{
+			"https://blog.example.com/feed/",
+			"https://blog.example.com/category/go/feed/",
+			"https://blog.example.com/category/go/generics/feed/",
+			"https://blog.example.com/tag/release-notes/feed/",
+			"https://blog.example.com/author/alice/feed/",
+		}},
+		{name: "WordPress category page prefers its own feed", url: "https://blog.example.com/category/go/generics/page/3/", fixture: "wordpress.html", wantMatch: true, want: []string{
+			"https://blog.example.com/category/go/generics/feed/",
+			"https://blog.example.com/feed/",
+			"https://blog.example.com/category/go/feed/",
+			"https://blog.example.com/tag/release-notes/feed/",
+			"https://blog.example.com/author/alice/feed/",
+		}},
+		{name: "WordPress.com", url: "https://someone.wordpress.com/author/someone/", wantMatch: true, want: []string{
+			"https://someone.wordpress.com/author/someone/feed/",
+			"https://someone.wordpress.com/feed/",
+		}},
+		{name: "Ghost tag page", url: "https://journal.example.net/tag/essays/", fixture: "ghost.html", wantMatch: true, want: []string{
+			"https://journal.example.net/tag/essays/rss/",
+			"https://journal.example.net/rss/",
+			"https://journal.example.net/author/bob/rss/",
+		}},
+		{name: "Hugo with relative links", url: "https://notes.example.org/posts/", fixture: "hugo.html", wantMatch: true, want: []string{
+			"https://notes.example.org/index.xml",
+			"https://notes.example.org/tags/linux/index.xml",
+			"https://notes.example.org/categories/homelab/index.xml",
+		}},
+		{name: "Substack section", url: "https://newsletter.substack.com/s/podcast", wantMatch: true, want: []string{
+			"https://newsletter.substack.com/s/podcast/feed",
+			"https://newsletter.substack.com/feed",
+		}},
+		{name: "Buttondown", url: "https://buttondown.com/alice/archive/", wantMatch: true, want: []string{"https://buttondown.com/alice/rss"}},
+		{name: "Beehiiv", url: "https://weekly.beehiiv.com/p/issue-12", wantMatch: true, want: []string{"https://weekly.beehiiv.com/feed"}},
+		{name: "Blogger label", url: "https://someone.blogspot.com/search/label/Home%20Lab", wantMatch: true, want: []string{
+			"https://someone.blogspot.com/feeds/posts/default/-/Home%20Lab",
+			"https://someone.blogspot.com/feeds/posts/default",
+		}},
+		{name: "Tumblr tag", url: "https://someone.tumblr.com/tagged/art", wantMatch: true, want: []string{
+			"https://someone.tumblr.com/tagged/art/rss",
+			"https://someone.tumblr.com/rss",
+		}},
+		{name: "Tumblr dashboard", url: "https://www.tumblr.com/dashboard", wantMatch: false},
+		{name: "Unknown platform", url: "https://blog.example.org/", fixture: "plain_blog.html", wantMatch: false},
+	})
+}
+
+// TestDetectPlatformFromHeaders tests fingerprinting from response headers when the markup has no markers
+func TestDetectPlatformFromHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected string
+	}{
+		{name: "WordPress REST API link", header: http.Header{"Link": {`<https://example.com/wp-json/>; rel="https://api.w.org/"`}}, expected: PlatformWordPress},
+		{name: "WordPress pingback", header: http.Header{"X-Pingback": {"https://example.com/xmlrpc.php"}}, expected: PlatformWordPress},
+		{name: "Ghost cache", header: http.Header{"X-Ghost-Cache-Status": {"HIT"}}, expected: PlatformGhost},
+		{name: "Tumblr user", header: http.Header{"X-Tumblr-User": {"someone"}}, expected: PlatformTumblr},
+		{name: "Powered by", header: http.Header{"X-Powered-By": {"Ghost 5.0"}}, expected: PlatformGhost},
+		{name: "Unrelated headers", header: http.Header{"Server": {"nginx"}}, expected: ""},
+	}
+
+	u, _ := url.Parse("https://example.com/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTargetWithHTML(u, []byte("<html><head><title>Example</title></head></html>"))
+			target.header = tt.header
+			if got := detectPlatform(target); got != tt.expected {
+				t.Errorf("Expected platform %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
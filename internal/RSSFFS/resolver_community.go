@@ -0,0 +1,246 @@
+package RSSFFS
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultHNRSSBaseURL is the public hnrss instance used for Hacker News feeds
+const DefaultHNRSSBaseURL = "https://hnrss.org"
+
+// redditResolver maps subreddits, users and multireddits to Reddit's .rss feeds
+type redditResolver struct{}
+
+// hackerNewsResolver maps Hacker News user and listing pages to an hnrss-compatible service
+type hackerNewsResolver struct{}
+
+// discourseResolver maps Discourse topics, categories, tags and users to their .rss feeds
+type discourseResolver struct{}
+
+// forumResolver maps phpBB, Flarum and XenForo boards to their feed endpoints
+type forumResolver struct{}
+
+func init() {
+	registerResolver(redditResolver{})
+	registerResolver(hackerNewsResolver{})
+	registerResolver(discourseResolver{})
+	registerResolver(forumResolver{})
+}
+
+func (redditResolver) Name() string { return "reddit" }
+
+func (redditResolver) Priority() int { return priorityHost }
+
+func (redditResolver) Match(t *Target) bool {
+	switch t.Host() {
+	case "reddit.com", "www.reddit.com", "old.reddit.com", "new.reddit.com", "np.reddit.com", "m.reddit.com":
+		return true
+	}
+	return false
+}
+
+func (redditResolver) Candidates(t *Target) []string {
+	segments := t.PathSegments()
+	if len(segments) < 2 {
+		return nil
+	}
+
+	const base = "https://www.reddit.com/"
+	switch segments[0] {
+	case "r":
+		// Posts and listings within a subreddit map to the subreddit itself
+		return []string{base + "r/" + segments[1] + "/.rss"}
+	case "u", "user":
+		if len(segments) >= 4 && segments[2] == "m" {
+			return []string{base + "user/" + segments[1] + "/m/" + segments[3] + "/.rss"}
+		}
+		return []string{base + "user/" + segments[1] + "/.rss"}
+	}
+	return nil
+}
+
+func (hackerNewsResolver) Name() string { return "hackernews" }
+
+func (hackerNewsResolver) Priority() int { return priorityHost }
+
+func (hackerNewsResolver) Match(t *Target) bool {
+	return t.Host() == "news.ycombinator.com"
+}
+
+func (hackerNewsResolver) Candidates(t *Target) []string {
+	base := strings.TrimSuffix(t.settings().hnrssBaseURL, "/")
+	if base == "" {
+		base = DefaultHNRSSBaseURL
+	}
+
+	id := t.URL.Query().Get("id")
+	switch strings.Trim(t.URL.Path, "/") {
+	case "user", "submitted":
+		if id != "" {
+			return []string{base + "/submitted?id=" + url.QueryEscape(id)}
+		}
+	case "threads":
+		if id != "" {
+			return []string{base + "/threads?id=" + url.QueryEscape(id)}
+		}
+	case "", "news":
+		return []string{base + "/frontpage"}
+	case "newest":
+		return []string{base + "/newest"}
+	case "ask":
+		return []string{base + "/ask"}
+	case "show":
+		return []string{base + "/show"}
+	case "jobs":
+		return []string{base + "/jobs"}
+	}
+	return nil
+}
+
+func (discourseResolver) Name() string { return "discourse" }
+
+func (discourseResolver) Priority() int { return priorityPage }
+
+func (discourseResolver) Match(t *Target) bool {
+	return parseHeadMeta(t.HTML()).metaContains("generator", "discourse")
+}
+
+func (discourseResolver) Candidates(t *Target) []string {
+	origin := t.origin()
+	segments := t.PathSegments()
+	if len(segments) == 0 {
+		return []string{origin + "/latest.rss"}
+	}
+
+	switch segments[0] {
+	case "t":
+		// /t/slug/id[/post] -> /t/slug/id.rss
+		if len(segments) >= 3 {
+			return []string{origin + "/t/" + segments[1] + "/" + segments[2] + ".rss"}
+		}
+	case "c":
+		// /c/parent/child/id -> /c/parent/child/id.rss
+		if len(segments) >= 2 {
+			return []string{origin + "/" + strings.Join(segments, "/") + ".rss"}
+		}
+	case "tag":
+		if len(segments) >= 2 {
+			return []string{origin + "/tag/" + segments[1] + ".rss"}
+		}
+	case "u":
+		if len(segments) >= 2 {
+			return []string{origin + "/u/" + segments[1] + "/activity.rss"}
+		}
+	}
+	return []string{origin + "/latest.rss"}
+}
+
+func (forumResolver) Name() string { return "forum" }
+
+func (forumResolver) Priority() int { return priorityPage }
+
+func (forumResolver) Match(t *Target) bool {
+	return forumSoftware(t.HTML()) != ""
+}
+
+func (forumResolver) Candidates(t *Target) []string {
+	switch forumSoftware(t.HTML()) {
+	case "phpbb":
+		return phpbbCandidates(t)
+	case "flarum":
+		return flarumCandidates(t)
+	case "xenforo":
+		return xenforoCandidates(t)
+	}
+	return nil
+}
+
+// forumSoftware identifies phpBB, Flarum and XenForo boards from markers in their HTML
+func forumSoftware(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	lower := bytes.ToLower(body)
+	switch {
+	case bytes.Contains(lower, []byte(`id="phpbb"`)) || bytes.Contains(lower, []byte("powered by <a href=\"https://www.phpbb.com/\">phpbb")):
+		return "phpbb"
+	case bytes.Contains(lower, []byte("flarum-loading")) || bytes.Contains(lower, []byte("flarum.core")):
+		return "flarum"
+	case bytes.Contains(lower, []byte(`id="xf"`)) && bytes.Contains(lower, []byte("xenforo")):
+		return "xenforo"
+	}
+	return ""
+}
+
+// phpbbCandidates maps phpBB boards, forums and topics to the phpBB 3.1+
+// app.php/feed endpoints, followed by the phpBB 3.0 feed.php equivalents
+func phpbbCandidates(t *Target) []string {
+	// Boards are often installed in a subdirectory such as /forum/
+	dir := t.URL.Path
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "." {
+		dir = ""
+	}
+	base := t.origin() + dir
+
+	query := t.URL.Query()
+	if topic := query.Get("t"); topic != "" {
+		return []string{base + "/app.php/feed/topic/" + url.PathEscape(topic), base + "/feed.php?t=" + url.QueryEscape(topic)}
+	}
+	if forum := query.Get("f"); forum != "" {
+		return []string{base + "/app.php/feed/forum/" + url.PathEscape(forum), base + "/feed.php?f=" + url.QueryEscape(forum)}
+	}
+	return []string{base + "/app.php/feed", base + "/feed.php"}
+}
+
+// flarumCandidates maps Flarum discussions and tags to the feeds published by
+// the Syndication/RSS extension
+func flarumCandidates(t *Target) []string {
+	origin := t.origin()
+	segments := t.PathSegments()
+	if len(segments) >= 2 {
+		switch segments[0] {
+		case "d":
+			// /d/123-some-title -> /atom/d/123
+			id, _, _ := strings.Cut(segments[1], "-")
+			return []string{origin + "/atom/d/" + id}
+		case "t":
+			return []string{origin + "/atom/t/" + segments[1]}
+		}
+	}
+	return []string{origin + "/atom", origin + "/rss"}
+}
+
+// xenforoCandidates maps XenForo boards and forums to their index.rss feeds
+func xenforoCandidates(t *Target) []string {
+	segments := t.PathSegments()
+
+	// Boards may be installed under a prefix such as /community/
+	for i, segment := range segments {
+		if segment == "forums" || segment == "threads" {
+			prefix := ""
+			if i > 0 {
+				prefix = "/" + strings.Join(segments[:i], "/")
+			}
+			if segment == "forums" && i+1 < len(segments) && segments[i+1] != "-" {
+				return []string{t.origin() + prefix + "/forums/" + segments[i+1] + "/index.rss"}
+			}
+			return []string{t.origin() + prefix + "/forums/-/index.rss"}
+		}
+	}
+
+	// Elsewhere the prefix isn't visible in the path, but the board-wide feed is advertised in the head
+	for _, link := range parseHeadMeta(t.HTML()).Links {
+		if hasRelToken(link.Rel, "alternate") && strings.HasSuffix(link.Href, "/forums/-/index.rss") {
+			if feed, err := t.URL.Parse(link.Href); err == nil {
+				return []string{feed.String()}
+			}
+		}
+	}
+	return []string{t.origin() + "/forums/-/index.rss"}
+}
@@ -0,0 +1,105 @@
+package RSSFFS
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestRedditResolver tests subreddit, user and multireddit feed mapping
+func TestRedditResolver(t *testing.T) {
+	runResolverTests(t, redditResolver{}, []resolverTestCase{
+		{name: "Subreddit", url: "https://www.reddit.com/r/golang/", wantMatch: true, want: []string{"https://www.reddit.com/r/golang/.rss"}},
+		{name: "Subreddit post", url: "https://old.reddit.com/r/golang/comments/abc123/some_title/", wantMatch: true, want: []string{"https://www.reddit.com/r/golang/.rss"}},
+		{name: "User short form", url: "https://reddit.com/u/spez", wantMatch: true, want: []string{"https://www.reddit.com/user/spez/.rss"}},
+		{name: "User long form", url: "https://www.reddit.com/user/spez/submitted/", wantMatch: true, want: []string{"https://www.reddit.com/user/spez/.rss"}},
+		{name: "Multireddit", url: "https://www.reddit.com/user/someone/m/programming", wantMatch: true, want: []string{"https://www.reddit.com/user/someone/m/programming/.rss"}},
+		{name: "Front page", url: "https://www.reddit.com/", wantMatch: true, want: nil},
+		{name: "Other site", url: "https://example.com/r/golang", wantMatch: false},
+	})
+}
+
+// TestHackerNewsResolver tests Hacker News mapping to the configured hnrss base URL
+func TestHackerNewsResolver(t *testing.T) {
+	runResolverTests(t, hackerNewsResolver{}, []resolverTestCase{
+		{name: "User", url: "https://news.ycombinator.com/user?id=pg", wantMatch: true, want: []string{"https://hnrss.org/submitted?id=pg"}},
+		{name: "User comments", url: "https://news.ycombinator.com/threads?id=pg", wantMatch: true, want: []string{"https://hnrss.org/threads?id=pg"}},
+		{name: "Front page", url: "https://news.ycombinator.com/", wantMatch: true, want: []string{"https://hnrss.org/frontpage"}},
+		{name: "Show HN", url: "https://news.ycombinator.com/show", wantMatch: true, want: []string{"https://hnrss.org/show"}},
+		{name: "Item", url: "https://news.ycombinator.com/item?id=1", wantMatch: true, want: nil},
+		{name: "Other site", url: "https://example.com/user?id=pg", wantMatch: false},
+	})
+
+	runResolverTestsWith(t, &settings{hnrssBaseURL: "https://hnrss.internal.example/"}, hackerNewsResolver{}, []resolverTestCase{
+		{name: "Custom base URL", url: "https://news.ycombinator.com/user?id=dang", wantMatch: true, want: []string{"https://hnrss.internal.example/submitted?id=dang"}},
+	})
+}
+
+// TestDiscourseResolver tests Discourse detection and topic, category, tag and user feed mapping
+func TestDiscourseResolver(t *testing.T) {
+	runResolverTests(t, discourseResolver{}, []resolverTestCase{
+		{name: "Home", url: "https://forum.example.com/", fixture: "discourse.html", wantMatch: true, want: []string{"https://forum.example.com/latest.rss"}},
+		{name: "Topic", url: "https://forum.example.com/t/welcome-to-the-forum/42", fixture: "discourse.html", wantMatch: true, want: []string{"https://forum.example.com/t/welcome-to-the-forum/42.rss"}},
+		{name: "Topic post", url: "https://forum.example.com/t/welcome-to-the-forum/42/7", fixture: "discourse.html", wantMatch: true, want: []string{"https://forum.example.com/t/welcome-to-the-forum/42.rss"}},
+		{name: "Category", url: "https://forum.example.com/c/announcements/5", fixture: "discourse.html", wantMatch: true, want: []string{"https://forum.example.com/c/announcements/5.rss"}},
+		{name: "Subcategory", url: "https://forum.example.com/c/dev/plugins/12", fixture: "discourse.html", wantMatch: true, want: []string{"https://forum.example.com/c/dev/plugins/12.rss"}},
+		{name: "Tag", url: "https://forum.example.com/tag/release", fixture: "discourse.html", wantMatch: true, want: []string{"https://forum.example.com/tag/release.rss"}},
+		{name: "User", url: "https://forum.example.com/u/alice/summary", fixture: "discourse.html", wantMatch: true, want: []string{"https://forum.example.com/u/alice/activity.rss"}},
+		{name: "Not Discourse", url: "https://blog.example.org/t/something/1", fixture: "plain_blog.html", wantMatch: false},
+	})
+}
+
+// TestForumResolver tests phpBB, Flarum and XenForo detection and feed mapping
+func TestForumResolver(t *testing.T) {
+	runResolverTests(t, forumResolver{}, []resolverTestCase{
+		{name: "phpBB index in subdirectory", url: "https://boards.example.com/forum/index.php", fixture: "phpbb.html", wantMatch: true, want: []string{
+			"https://boards.example.com/forum/app.php/feed",
+			"https://boards.example.com/forum/feed.php",
+		}},
+		{name: "phpBB forum", url: "https://boards.example.com/forum/viewforum.php?f=3", fixture: "phpbb.html", wantMatch: true, want: []string{
+			"https://boards.example.com/forum/app.php/feed/forum/3",
+			"https://boards.example.com/forum/feed.php?f=3",
+		}},
+		{name: "phpBB topic at root", url: "https://boards.example.com/viewtopic.php?t=99", fixture: "phpbb.html", wantMatch: true, want: []string{
+			"https://boards.example.com/app.php/feed/topic/99",
+			"https://boards.example.com/feed.php?t=99",
+		}},
+		{name: "Flarum home", url: "https://discuss.example.org/", fixture: "flarum.html", wantMatch: true, want: []string{
+			"https://discuss.example.org/atom",
+			"https://discuss.example.org/rss",
+		}},
+		{name: "Flarum discussion", url: "https://discuss.example.org/d/123-hello-world", fixture: "flarum.html", wantMatch: true, want: []string{"https://discuss.example.org/atom/d/123"}},
+		{name: "Flarum tag", url: "https://discuss.example.org/t/general", fixture: "flarum.html", wantMatch: true, want: []string{"https://discuss.example.org/atom/t/general"}},
+		{name: "XenForo home under prefix", url: "https://www.example.net/community/", fixture: "xenforo.html", wantMatch: true, want: []string{"https://www.example.net/community/forums/-/index.rss"}},
+		{name: "XenForo forum", url: "https://www.example.net/community/forums/general-discussion.4/", fixture: "xenforo.html", wantMatch: true, want: []string{"https://www.example.net/community/forums/general-discussion.4/index.rss"}},
+		{name: "XenForo thread", url: "https://www.example.net/threads/hello.123/", fixture: "xenforo.html", wantMatch: true, want: []string{"https://www.example.net/forums/-/index.rss"}},
+		{name: "Not a forum", url: "https://blog.example.org/", fixture: "plain_blog.html", wantMatch: false},
+	})
+}
+
+// TestDomainsFromHTML tests that linked URLs are grouped by domain for resolvers
+func TestDomainsFromHTML(t *testing.T) {
+	body := []byte(`<html><body>
+		<a href="https://www.reddit.com/r/golang/">Go subreddit</a>
+		<a href="https://www.reddit.com/r/selfhosted/#top">Selfhosted</a>
+		<a href="https://www.reddit.com/r/golang/">Go again</a>
+		<a href="https://blog.example.org/post">A post</a>
+		<a href="/relative/link">Relative</a>
+	</body></html>`)
+
+	domains := domainsFromHTML(body)
+
+	var keys []string
+	for domain := range domains {
+		keys = append(keys, domain)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"blog.example.org", "www.reddit.com"}) {
+		t.Fatalf("Unexpected domains: %v", keys)
+	}
+
+	expected := []string{"https://www.reddit.com/r/golang/", "https://www.reddit.com/r/selfhosted/"}
+	if !reflect.DeepEqual(domains["www.reddit.com"], expected) {
+		t.Errorf("Expected reddit links %v, got %v", expected, domains["www.reddit.com"])
+	}
+}
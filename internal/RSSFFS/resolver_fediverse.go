@@ -0,0 +1,240 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// handlePattern matches fediverse handles such as @user@host. The leading @
+// is required, so that e-mail addresses such as user@host aren't mistaken for
+// handles and skip URL validation.
+var handlePattern = regexp.MustCompile(`^@([A-Za-z0-9_.\-]+)@([A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)+)$`)
+
+// nodeInfoCache remembers the software name reported by each host's NodeInfo
+// document so traversal doesn't query the same server repeatedly. Hosts without
+// NodeInfo are cached with an empty name.
+var nodeInfoCache sync.Map
+
+// nodeInfoDiscovery is the /.well-known/nodeinfo document listing schema links
+type nodeInfoDiscovery struct {
+	Links []struct {
+		Rel  string `json:"rel"`
+		Href string `json:"href"`
+	} `json:"links"`
+}
+
+// nodeInfo is the subset of a NodeInfo document that identifies the server software
+type nodeInfo struct {
+	Software struct {
+		Name string `json:"name"`
+	} `json:"software"`
+}
+
+// webFingerResponse is the subset of a WebFinger JRD document RSSFFS needs
+type webFingerResponse struct {
+	Links []struct {
+		Rel  string `json:"rel"`
+		Type string `json:"type"`
+		Href string `json:"href"`
+	} `json:"links"`
+}
+
+// fediverseResolver maps profiles, tags and communities on Mastodon-compatible
+// servers, Pixelfed and Lemmy to their feeds. Servers are recognised by the
+// software they report via NodeInfo rather than a fixed host list.
+type fediverseResolver struct{}
+
+// blueskyResolver maps Bluesky profiles to their RSS feeds
+type blueskyResolver struct{}
+
+func init() {
+	registerResolver(fediverseResolver{})
+	registerResolver(blueskyResolver{})
+}
+
+func (fediverseResolver) Name() string { return "fediverse" }
+
+func (fediverseResolver) Priority() int { return priorityPage }
+
+func (fediverseResolver) Match(t *Target) bool {
+	return fediverseSoftware(t) != ""
+}
+
+func (fediverseResolver) Candidates(t *Target) []string {
+	origin := t.origin()
+	segments := t.PathSegments()
+
+	switch fediverseSoftware(t) {
+	case "mastodon", "hometown", "glitchcafe", "fedibird":
+		return mastodonCandidates(origin, segments, "/@%s.rss")
+	case "gotosocial":
+		return mastodonCandidates(origin, segments, "/@%s/feed.rss")
+	case "misskey", "sharkey", "firefish", "iceshrimp", "calckey", "foundkey":
+		return mastodonCandidates(origin, segments, "/@%s.atom")
+	case "pleroma", "akkoma":
+		if user := fediverseUser(segments); user != "" {
+			return []string{origin + "/users/" + user + "/feed.atom"}
+		}
+		if len(segments) >= 2 && segments[0] == "users" {
+			return []string{origin + "/users/" + segments[1] + "/feed.atom"}
+		}
+		if len(segments) >= 2 && segments[0] == "tag" {
+			return []string{origin + "/tags/" + segments[1] + ".rss"}
+		}
+	case "pixelfed":
+		user := fediverseUser(segments)
+		if user == "" && len(segments) == 1 {
+			// Pixelfed profiles also live at the top level, e.g. /alice
+			user = segments[0]
+		}
+		if user == "" && len(segments) >= 2 && segments[0] == "users" {
+			user = segments[1]
+		}
+		if user != "" {
+			return []string{origin + "/users/" + user + ".atom"}
+		}
+	case "lemmy":
+		if len(segments) == 0 {
+			return []string{origin + "/feeds/local.xml"}
+		}
+		if len(segments) >= 2 && (segments[0] == "c" || segments[0] == "u") {
+			return []string{origin + "/feeds/" + segments[0] + "/" + segments[1] + ".xml"}
+		}
+	}
+
+	return nil
+}
+
+// mastodonCandidates maps Mastodon-style /@user profiles and /tags/x pages to
+// feeds, using profileFormat to build the profile feed path
+func mastodonCandidates(origin string, segments []string, profileFormat string) []string {
+	if user := fediverseUser(segments); user != "" {
+		return []string{origin + fmt.Sprintf(profileFormat, user)}
+	}
+	if len(segments) >= 2 && (segments[0] == "tags" || segments[0] == "tag") {
+		return []string{origin + "/tags/" + segments[1] + ".rss"}
+	}
+	return nil
+}
+
+// fediverseUser returns the local username from a /@user path, or an empty
+// string if the path isn't a local profile. Profiles of remote users
+// (/@user@elsewhere) are skipped since their feeds live on another server.
+func fediverseUser(segments []string) string {
+	if len(segments) == 0 || !strings.HasPrefix(segments[0], "@") {
+		return ""
+	}
+	user := strings.TrimPrefix(segments[0], "@")
+	if user == "" || strings.Contains(user, "@") {
+		return ""
+	}
+	return user
+}
+
+// fediverseSoftware returns the lowercased software name the target's host
+// reports via NodeInfo, or an empty string if the host doesn't publish NodeInfo
+func fediverseSoftware(t *Target) string {
+	host := t.Host()
+	if cached, ok := nodeInfoCache.Load(host); ok {
+		return cached.(string)
+	}
+	if t.offline {
+		return ""
+	}
+
+	software := ""
+	var discovery nodeInfoDiscovery
+	if err := fetchJSON("https://"+host+"/.well-known/nodeinfo", &discovery); err != nil {
+		log.Debugf("No NodeInfo for %s: %v", host, err)
+	} else if href := nodeInfoHref(discovery); href != "" {
+		var info nodeInfo
+		if err := fetchJSON(href, &info); err != nil {
+			log.Debugf("Could not read NodeInfo document %s: %v", href, err)
+		} else {
+			software = strings.ToLower(info.Software.Name)
+			log.Debugf("NodeInfo reports %s runs %s", host, software)
+		}
+	}
+
+	nodeInfoCache.Store(host, software)
+	return software
+}
+
+// nodeInfoHref picks the newest NodeInfo schema document advertised by a server
+func nodeInfoHref(discovery nodeInfoDiscovery) string {
+	best, bestRel := "", ""
+	for _, link := range discovery.Links {
+		if !strings.HasPrefix(link.Rel, "http://nodeinfo.diaspora.software/ns/schema/") {
+			continue
+		}
+		// Schema versions sort lexically (1.0 < 2.0 < 2.1)
+		if link.Rel > bestRel {
+			best, bestRel = link.Href, link.Rel
+		}
+	}
+	return best
+}
+
+func (blueskyResolver) Name() string { return "bluesky" }
+
+func (blueskyResolver) Priority() int { return priorityHost }
+
+func (blueskyResolver) Match(t *Target) bool {
+	return t.Host() == "bsky.app"
+}
+
+func (blueskyResolver) Candidates(t *Target) []string {
+	segments := t.PathSegments()
+	if len(segments) >= 2 && segments[0] == "profile" {
+		return []string{"https://bsky.app/profile/" + segments[1] + "/rss"}
+	}
+	return nil
+}
+
+// IsHandle reports whether input is a fediverse handle such as @user@host
+func IsHandle(input string) bool {
+	return handlePattern.MatchString(strings.TrimSpace(input))
+}
+
+// ResolveHandle looks up a fediverse handle via WebFinger and returns the URL of
+// the account's profile page
+func ResolveHandle(handle string) (string, error) {
+	m := handlePattern.FindStringSubmatch(strings.TrimSpace(handle))
+	if m == nil {
+		return "", fmt.Errorf("invalid handle %q, expected @user@host", handle)
+	}
+	user, host := m[1], strings.ToLower(m[2])
+
+	webFingerURL := fmt.Sprintf("https://%s/.well-known/webfinger?resource=%s", host, url.QueryEscape("acct:"+user+"@"+host))
+	var jrd webFingerResponse
+	if err := fetchJSON(webFingerURL, &jrd); err != nil {
+		return "", fmt.Errorf("WebFinger lookup for %s failed: %w", handle, err)
+	}
+
+	profile := webFingerProfile(jrd)
+	if profile == "" {
+		return "", fmt.Errorf("WebFinger response for %s has no profile link", handle)
+	}
+	log.Infof("Resolved %s to profile %s", handle, profile)
+	return profile, nil
+}
+
+// webFingerProfile returns the HTML profile page from a WebFinger response,
+// falling back to the ActivityPub actor URL
+func webFingerProfile(jrd webFingerResponse) string {
+	fallback := ""
+	for _, link := range jrd.Links {
+		switch {
+		case link.Rel == "http://webfinger.net/rel/profile-page" && link.Href != "":
+			return link.Href
+		case link.Rel == "self" && link.Href != "" && fallback == "":
+			fallback = link.Href
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,129 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// withNodeInfo seeds the NodeInfo cache so fediverse resolvers can be tested offline
+func withNodeInfo(t *testing.T, software map[string]string) {
+	t.Helper()
+	for host, name := range software {
+		nodeInfoCache.Store(host, name)
+	}
+	t.Cleanup(func() {
+		for host := range software {
+			nodeInfoCache.Delete(host)
+		}
+	})
+}
+
+// TestFediverseResolver tests NodeInfo-based detection and profile, tag and community feed mapping
+func TestFediverseResolver(t *testing.T) {
+	withNodeInfo(t, map[string]string{
+		"mastodon.example":   "mastodon",
+		"gts.example":        "gotosocial",
+		"akkoma.example":     "akkoma",
+		"misskey.example":    "misskey",
+		"pixelfed.example":   "pixelfed",
+		"lemmy.example":      "lemmy",
+		"plain.example.org":  "",
+		"wordpress.example":  "wordpress",
+		"mastodon2.example":  "hometown",
+		"pixelfed2.example":  "pixelfed",
+		"lemmy2.example.net": "lemmy",
+	})
+
+	runResolverTests(t, fediverseResolver{}, []resolverTestCase{
+		{name: "Mastodon profile", url: "https://mastodon.example/@alice", wantMatch: true, want: []string{"https://mastodon.example/@alice.rss"}},
+		{name: "Mastodon post", url: "https://mastodon.example/@alice/111222333", wantMatch: true, want: []string{"https://mastodon.example/@alice.rss"}},
+		{name: "Mastodon tag", url: "https://mastodon.example/tags/golang", wantMatch: true, want: []string{"https://mastodon.example/tags/golang.rss"}},
+		{name: "Mastodon remote profile is skipped", url: "https://mastodon.example/@bob@elsewhere.example", wantMatch: true, want: nil},
+		{name: "Hometown fork", url: "https://mastodon2.example/@carol", wantMatch: true, want: []string{"https://mastodon2.example/@carol.rss"}},
+		{name: "GoToSocial profile", url: "https://gts.example/@dave", wantMatch: true, want: []string{"https://gts.example/@dave/feed.rss"}},
+		{name: "Akkoma profile", url: "https://akkoma.example/users/erin", wantMatch: true, want: []string{"https://akkoma.example/users/erin/feed.atom"}},
+		{name: "Misskey profile", url: "https://misskey.example/@frank", wantMatch: true, want: []string{"https://misskey.example/@frank.atom"}},
+		{name: "Pixelfed top-level profile", url: "https://pixelfed.example/grace", wantMatch: true, want: []string{"https://pixelfed.example/users/grace.atom"}},
+		{name: "Pixelfed @ profile", url: "https://pixelfed2.example/@heidi", wantMatch: true, want: []string{"https://pixelfed2.example/users/heidi.atom"}},
+		{name: "Lemmy community", url: "https://lemmy.example/c/selfhosted", wantMatch: true, want: []string{"https://lemmy.example/feeds/c/selfhosted.xml"}},
+		{name: "Lemmy user", url: "https://lemmy2.example.net/u/ivan", wantMatch: true, want: []string{"https://lemmy2.example.net/feeds/u/ivan.xml"}},
+		{name: "Lemmy front page", url: "https://lemmy.example/", wantMatch: true, want: []string{"https://lemmy.example/feeds/local.xml"}},
+		{name: "Other NodeInfo software", url: "https://wordpress.example/@someone", wantMatch: true, want: nil},
+		{name: "No NodeInfo", url: "https://plain.example.org/@alice", fixture: "plain_blog.html", wantMatch: false},
+		{name: "Unknown host is not looked up offline", url: "https://unknown.example/@alice", wantMatch: false},
+	})
+}
+
+// TestBlueskyResolver tests Bluesky profile feed mapping
+func TestBlueskyResolver(t *testing.T) {
+	runResolverTests(t, blueskyResolver{}, []resolverTestCase{
+		{name: "Profile", url: "https://bsky.app/profile/alice.example.com", wantMatch: true, want: []string{"https://bsky.app/profile/alice.example.com/rss"}},
+		{name: "Post", url: "https://bsky.app/profile/alice.bsky.social/post/3kabc", wantMatch: true, want: []string{"https://bsky.app/profile/alice.bsky.social/rss"}},
+		{name: "Home", url: "https://bsky.app/", wantMatch: true, want: nil},
+		{name: "Other site", url: "https://example.com/profile/alice", wantMatch: false},
+	})
+}
+
+// TestNodeInfoHref tests that the newest NodeInfo schema is preferred
+func TestNodeInfoHref(t *testing.T) {
+	var discovery nodeInfoDiscovery
+	body := `{"links":[
+		{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.0","href":"https://social.example/nodeinfo/2.0"},
+		{"rel":"https://www.w3.org/ns/activitystreams#Application","href":"https://social.example/actor"},
+		{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.1","href":"https://social.example/nodeinfo/2.1"}
+	]}`
+	if err := json.Unmarshal([]byte(body), &discovery); err != nil {
+		t.Fatalf("Failed to unmarshal discovery document: %v", err)
+	}
+
+	if got := nodeInfoHref(discovery); got != "https://social.example/nodeinfo/2.1" {
+		t.Errorf("Expected NodeInfo 2.1 document, got %q", got)
+	}
+	if got := nodeInfoHref(nodeInfoDiscovery{}); got != "" {
+		t.Errorf("Expected empty href for empty discovery document, got %q", got)
+	}
+}
+
+// TestIsHandle tests fediverse handle detection
+func TestIsHandle(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "@alice@mastodon.example", expected: true},
+		{input: "alice@mastodon.example", expected: false},
+		{input: " @alice_b.c@social.example.co.uk ", expected: true},
+		{input: "https://mastodon.example/@alice", expected: false},
+		{input: "@alice", expected: false},
+		{input: "@alice@localhost", expected: false},
+		{input: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := IsHandle(tt.input); got != tt.expected {
+				t.Errorf("IsHandle(%q): expected %t, got %t", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestWebFingerProfile tests profile page selection from WebFinger responses
+func TestWebFingerProfile(t *testing.T) {
+	var withProfile webFingerResponse
+	body := `{"subject":"acct:alice@mastodon.example","links":[
+		{"rel":"self","type":"application/activity+json","href":"https://mastodon.example/users/alice"},
+		{"rel":"http://webfinger.net/rel/profile-page","type":"text/html","href":"https://mastodon.example/@alice"}
+	]}`
+	if err := json.Unmarshal([]byte(body), &withProfile); err != nil {
+		t.Fatalf("Failed to unmarshal WebFinger response: %v", err)
+	}
+	if got := webFingerProfile(withProfile); got != "https://mastodon.example/@alice" {
+		t.Errorf("Expected profile page, got %q", got)
+	}
+
+	selfOnly := webFingerResponse{Links: withProfile.Links[:1]}
+	if got := webFingerProfile(selfOnly); got != "https://mastodon.example/users/alice" {
+		t.Errorf("Expected actor URL fallback, got %q", got)
+	}
+}
@@ -0,0 +1,237 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Forge feed kinds selectable with --forge-feed / RSSFFS_FORGE_FEED
+const (
+	ForgeFeedReleases = "releases"
+	ForgeFeedTags     = "tags"
+	ForgeFeedCommits  = "commits"
+)
+
+// githubReservedPaths are top-level github.com paths that are neither users nor organisations
+var githubReservedPaths = map[string]bool{
+	"about": true, "apps": true, "collections": true, "enterprise": true, "explore": true,
+	"features": true, "issues": true, "login": true, "marketplace": true, "notifications": true,
+	"orgs": true, "pricing": true, "pulls": true, "search": true, "security": true,
+	"settings": true, "site": true, "sponsors": true, "topics": true, "trending": true,
+}
+
+// giteaReservedPaths and gitlabReservedPaths are top-level paths on those forges that aren't users
+var (
+	giteaReservedPaths = map[string]bool{
+		"admin": true, "api": true, "assets": true, "explore": true, "notifications": true,
+		"org": true, "repo": true, "user": true,
+	}
+	gitlabReservedPaths = map[string]bool{
+		"admin": true, "api": true, "dashboard": true, "explore": true, "groups": true,
+		"help": true, "projects": true, "search": true, "users": true,
+	}
+)
+
+// ValidateForgeFeed checks that kind is a supported forge feed kind
+func ValidateForgeFeed(kind string) error {
+	switch kind {
+	case "", ForgeFeedReleases, ForgeFeedTags, ForgeFeedCommits:
+		return nil
+	}
+	return fmt.Errorf("invalid forge feed %q (must be one of %s, %s, %s)", kind, ForgeFeedReleases, ForgeFeedTags, ForgeFeedCommits)
+}
+
+// forgeResolver maps repository URLs on GitHub, GitLab, Gitea, Forgejo, Codeberg
+// and sourcehut to their releases, tags or commits feeds, and user profiles to
+// activity feeds. Self-hosted GitLab, Gitea and Forgejo instances are detected
+// from page metadata.
+type forgeResolver struct{}
+
+func init() {
+	registerResolver(forgeResolver{})
+}
+
+func (forgeResolver) Name() string { return "forge" }
+
+func (forgeResolver) Priority() int { return priorityHost }
+
+func (forgeResolver) Match(t *Target) bool {
+	return forgeType(t) != ""
+}
+
+func (forgeResolver) Candidates(t *Target) []string {
+	segments := t.PathSegments()
+	if len(segments) == 0 {
+		return nil
+	}
+
+	origin := t.origin()
+	kind := t.settings().forgeFeed
+	if kind == "" {
+		kind = ForgeFeedReleases
+	}
+
+	switch forgeType(t) {
+	case "github":
+		return githubCandidates(segments, kind)
+	case "gitlab":
+		return gitlabCandidates(t, origin, segments, kind)
+	case "gitea":
+		return giteaCandidates(t, origin, segments, kind)
+	case "sourcehut":
+		return sourcehutCandidates(origin, segments, kind)
+	}
+	return nil
+}
+
+// forgeType identifies the forge software behind a target, from its host for
+// the well-known public forges and from page metadata for self-hosted ones
+func forgeType(t *Target) string {
+	switch host := t.Host(); {
+	case host == "github.com" || host == "www.github.com":
+		return "github"
+	case host == "gitlab.com":
+		return "gitlab"
+	case host == "codeberg.org" || host == "gitea.com":
+		return "gitea"
+	case host == "git.sr.ht" || host == "hg.sr.ht":
+		return "sourcehut"
+	}
+
+	meta := parseHeadMeta(t.HTML())
+	switch {
+	case meta.metaContains("keywords", "gitea") || meta.metaContains("keywords", "forgejo") ||
+		meta.metaContains("author", "gitea") || meta.metaContains("generator", "forgejo"):
+		return "gitea"
+	case strings.EqualFold(meta.Meta["og:site_name"], "GitLab"):
+		return "gitlab"
+	}
+	return ""
+}
+
+// githubCandidates maps github.com/owner/repo and github.com/user URLs to Atom feeds
+func githubCandidates(segments []string, kind string) []string {
+	if githubReservedPaths[strings.ToLower(segments[0])] {
+		return nil
+	}
+	if len(segments) == 1 {
+		return []string{"https://github.com/" + segments[0] + ".atom"}
+	}
+
+	repo := "https://github.com/" + segments[0] + "/" + strings.TrimSuffix(segments[1], ".git")
+	switch kind {
+	case ForgeFeedTags:
+		return []string{repo + "/tags.atom"}
+	case ForgeFeedCommits:
+		// Follow the branch being browsed; without one, commits.atom follows the default branch
+		if len(segments) >= 4 && segments[2] == "tree" {
+			return []string{repo + "/commits/" + segments[3] + ".atom"}
+		}
+		return []string{repo + "/commits.atom"}
+	default:
+		return []string{repo + "/releases.atom"}
+	}
+}
+
+// gitlabCandidates maps GitLab project and user URLs to Atom feeds. Projects may
+// live in nested groups; everything before "/-/" is the project path.
+func gitlabCandidates(t *Target, origin string, segments []string, kind string) []string {
+	projectSegments := segments
+	for i, segment := range segments {
+		if segment == "-" {
+			projectSegments = segments[:i]
+			break
+		}
+	}
+	if len(projectSegments) == 0 || gitlabReservedPaths[strings.ToLower(projectSegments[0])] {
+		return nil
+	}
+	if len(projectSegments) == 1 {
+		return []string{origin + "/" + projectSegments[0] + ".atom"}
+	}
+
+	projectPath := strings.TrimSuffix(strings.Join(projectSegments, "/"), ".git")
+	project := origin + "/" + projectPath
+	switch kind {
+	case ForgeFeedTags:
+		return []string{project + "/-/tags?format=atom"}
+	case ForgeFeedCommits:
+		branch := forgeDefaultBranch(t, origin+"/api/v4/projects/"+url.PathEscape(projectPath))
+		var candidates []string
+		for _, b := range branchCandidates(branch) {
+			candidates = append(candidates, project+"/-/commits/"+url.PathEscape(b)+"?format=atom")
+		}
+		return candidates
+	default:
+		// Not every GitLab version publishes a releases feed, so fall back to tags
+		return []string{project + "/-/releases.atom", project + "/-/tags?format=atom"}
+	}
+}
+
+// giteaCandidates maps Gitea, Forgejo and Codeberg repository and user URLs to feeds
+func giteaCandidates(t *Target, origin string, segments []string, kind string) []string {
+	if giteaReservedPaths[strings.ToLower(segments[0])] {
+		return nil
+	}
+	if len(segments) == 1 {
+		return []string{origin + "/" + segments[0] + ".rss"}
+	}
+
+	repoPath := segments[0] + "/" + strings.TrimSuffix(segments[1], ".git")
+	repo := origin + "/" + repoPath
+	switch kind {
+	case ForgeFeedTags:
+		return []string{repo + "/tags.rss"}
+	case ForgeFeedCommits:
+		branch := forgeDefaultBranch(t, origin+"/api/v1/repos/"+repoPath)
+		var candidates []string
+		for _, b := range branchCandidates(branch) {
+			candidates = append(candidates, repo+"/rss/branch/"+url.PathEscape(b))
+		}
+		return candidates
+	default:
+		return []string{repo + "/releases.rss"}
+	}
+}
+
+// sourcehutCandidates maps sourcehut repositories to their log and refs feeds.
+// sourcehut has no separate releases feed; tags and releases share the refs feed.
+func sourcehutCandidates(origin string, segments []string, kind string) []string {
+	if len(segments) < 2 || !strings.HasPrefix(segments[0], "~") {
+		return nil
+	}
+
+	repo := origin + "/" + segments[0] + "/" + segments[1]
+	if kind == ForgeFeedCommits {
+		return []string{repo + "/log/rss.xml"}
+	}
+	return []string{repo + "/refs/rss.xml"}
+}
+
+// forgeDefaultBranch asks a GitLab or Gitea repository API for the repository's
+// default branch. It returns an empty string for offline targets or when the
+// API can't be reached.
+func forgeDefaultBranch(t *Target, apiURL string) string {
+	if t.offline {
+		return ""
+	}
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := fetchJSON(apiURL, &repo); err != nil {
+		log.Debugf("Could not look up default branch at %s: %v", apiURL, err)
+		return ""
+	}
+	return repo.DefaultBranch
+}
+
+// branchCandidates returns branch to try, or the usual default branch names when it's unknown
+func branchCandidates(branch string) []string {
+	if branch != "" {
+		return []string{branch}
+	}
+	return []string{"main", "master"}
+}
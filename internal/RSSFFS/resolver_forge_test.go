@@ -0,0 +1,70 @@
+package RSSFFS
+
+import "testing"
+
+// TestForgeResolverReleases tests the default releases feeds and user activity feeds
+func TestForgeResolverReleases(t *testing.T) {
+	runResolverTests(t, forgeResolver{}, []resolverTestCase{
+		{name: "GitHub repository", url: "https://github.com/spf13/cobra", wantMatch: true, want: []string{"https://github.com/spf13/cobra/releases.atom"}},
+		{name: "GitHub repository subpage", url: "https://github.com/spf13/cobra/issues/42", wantMatch: true, want: []string{"https://github.com/spf13/cobra/releases.atom"}},
+		{name: "GitHub clone URL", url: "https://github.com/spf13/cobra.git", wantMatch: true, want: []string{"https://github.com/spf13/cobra/releases.atom"}},
+		{name: "GitHub user", url: "https://github.com/toozej", wantMatch: true, want: []string{"https://github.com/toozej.atom"}},
+		{name: "GitHub reserved path", url: "https://github.com/marketplace/actions", wantMatch: true, want: nil},
+		{name: "GitLab.com nested project", url: "https://gitlab.com/gitlab-org/cli/-/tree/main", wantMatch: true, want: []string{
+			"https://gitlab.com/gitlab-org/cli/-/releases.atom",
+			"https://gitlab.com/gitlab-org/cli/-/tags?format=atom",
+		}},
+		{name: "GitLab.com user", url: "https://gitlab.com/alice", wantMatch: true, want: []string{"https://gitlab.com/alice.atom"}},
+		{name: "Self-hosted GitLab", url: "https://gitlab.example.org/platform/api-server", fixture: "gitlab_project.html", wantMatch: true, want: []string{
+			"https://gitlab.example.org/platform/api-server/-/releases.atom",
+			"https://gitlab.example.org/platform/api-server/-/tags?format=atom",
+		}},
+		{name: "Codeberg repository", url: "https://codeberg.org/forgejo/forgejo", wantMatch: true, want: []string{"https://codeberg.org/forgejo/forgejo/releases.rss"}},
+		{name: "Codeberg user", url: "https://codeberg.org/alice", wantMatch: true, want: []string{"https://codeberg.org/alice.rss"}},
+		{name: "Self-hosted Gitea", url: "https://git.example.net/tools/widget", fixture: "gitea_repo.html", wantMatch: true, want: []string{"https://git.example.net/tools/widget/releases.rss"}},
+		{name: "Self-hosted Forgejo", url: "https://forge.example.com/alice/dotfiles/src/branch/main", fixture: "forgejo_repo.html", wantMatch: true, want: []string{"https://forge.example.com/alice/dotfiles/releases.rss"}},
+		{name: "Gitea reserved path", url: "https://codeberg.org/explore/repos", wantMatch: true, want: nil},
+		{name: "sourcehut repository", url: "https://git.sr.ht/~sircmpwn/aerc", wantMatch: true, want: []string{"https://git.sr.ht/~sircmpwn/aerc/refs/rss.xml"}},
+		{name: "sourcehut user", url: "https://git.sr.ht/~sircmpwn", wantMatch: true, want: nil},
+		{name: "Not a forge", url: "https://blog.example.org/alice/posts", fixture: "plain_blog.html", wantMatch: false},
+	})
+}
+
+// TestForgeResolverTags tests tag feeds on each forge
+func TestForgeResolverTags(t *testing.T) {
+	runResolverTestsWith(t, &settings{forgeFeed: ForgeFeedTags}, forgeResolver{}, []resolverTestCase{
+		{name: "GitHub", url: "https://github.com/spf13/cobra", wantMatch: true, want: []string{"https://github.com/spf13/cobra/tags.atom"}},
+		{name: "GitLab", url: "https://gitlab.com/gitlab-org/cli", wantMatch: true, want: []string{"https://gitlab.com/gitlab-org/cli/-/tags?format=atom"}},
+		{name: "Gitea", url: "https://git.example.net/tools/widget", fixture: "gitea_repo.html", wantMatch: true, want: []string{"https://git.example.net/tools/widget/tags.rss"}},
+		{name: "sourcehut", url: "https://git.sr.ht/~sircmpwn/aerc", wantMatch: true, want: []string{"https://git.sr.ht/~sircmpwn/aerc/refs/rss.xml"}},
+	})
+}
+
+// TestForgeResolverCommits tests default-branch commit feeds on each forge
+func TestForgeResolverCommits(t *testing.T) {
+	runResolverTestsWith(t, &settings{forgeFeed: ForgeFeedCommits}, forgeResolver{}, []resolverTestCase{
+		{name: "GitHub default branch", url: "https://github.com/spf13/cobra", wantMatch: true, want: []string{"https://github.com/spf13/cobra/commits.atom"}},
+		{name: "GitHub browsed branch", url: "https://github.com/spf13/cobra/tree/v2", wantMatch: true, want: []string{"https://github.com/spf13/cobra/commits/v2.atom"}},
+		{name: "GitLab without API access", url: "https://gitlab.com/gitlab-org/cli", wantMatch: true, want: []string{
+			"https://gitlab.com/gitlab-org/cli/-/commits/main?format=atom",
+			"https://gitlab.com/gitlab-org/cli/-/commits/master?format=atom",
+		}},
+		{name: "Gitea without API access", url: "https://codeberg.org/forgejo/forgejo", wantMatch: true, want: []string{
+			"https://codeberg.org/forgejo/forgejo/rss/branch/main",
+			"https://codeberg.org/forgejo/forgejo/rss/branch/master",
+		}},
+		{name: "sourcehut", url: "https://git.sr.ht/~sircmpwn/aerc", wantMatch: true, want: []string{"https://git.sr.ht/~sircmpwn/aerc/log/rss.xml"}},
+	})
+}
+
+// TestValidateForgeFeed tests forge feed kind validation
+func TestValidateForgeFeed(t *testing.T) {
+	for _, kind := range []string{"", ForgeFeedReleases, ForgeFeedTags, ForgeFeedCommits} {
+		if err := ValidateForgeFeed(kind); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", kind, err)
+		}
+	}
+	if err := ValidateForgeFeed("branches"); err == nil {
+		t.Error("Expected error for invalid forge feed kind, got none")
+	}
+}
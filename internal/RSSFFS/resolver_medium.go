@@ -0,0 +1,69 @@
+package RSSFFS
+
+import (
+	"strings"
+)
+
+// mediumReservedPaths are top-level medium.com paths that are neither users nor publications
+var mediumReservedPaths = map[string]bool{
+	"about": true, "creators": true, "jobs": true, "m": true, "me": true, "membership": true,
+	"p": true, "plans": true, "search": true, "topics": true, "verified-authors": true,
+}
+
+// mediumResolver handles medium.com users, publications and tags, *.medium.com
+// subdomain blogs and Medium publications served from a custom domain
+type mediumResolver struct{}
+
+func init() {
+	registerResolver(mediumResolver{})
+}
+
+func (mediumResolver) Name() string { return "medium" }
+
+func (mediumResolver) Priority() int { return priorityHost }
+
+func (mediumResolver) Match(t *Target) bool {
+	host := t.Host()
+	if host == "medium.com" || strings.HasSuffix(host, ".medium.com") {
+		return true
+	}
+	return isMediumPage(parseHeadMeta(t.HTML()))
+}
+
+func (mediumResolver) Candidates(t *Target) []string {
+	host := t.Host()
+	if host == "www.medium.com" {
+		host = "medium.com"
+	}
+
+	// Subdomain blogs (user.medium.com) and custom domains publish at /feed
+	if host != "medium.com" {
+		return []string{t.origin() + "/feed"}
+	}
+
+	segments := t.PathSegments()
+	if len(segments) == 0 {
+		return nil
+	}
+
+	first := segments[0]
+	switch {
+	case first == "feed":
+		// Already a feed URL
+		return []string{"https://medium.com" + t.URL.Path}
+	case first == "tag" && len(segments) > 1:
+		return []string{"https://medium.com/feed/tag/" + segments[1]}
+	case mediumReservedPaths[strings.ToLower(first)]:
+		return nil
+	default:
+		// @user handles and publications, optionally followed by a post slug
+		return []string{"https://medium.com/feed/" + first}
+	}
+}
+
+// isMediumPage reports whether a page's metadata identifies it as being served by
+// Medium, which is how publications on custom domains are recognised
+func isMediumPage(meta headMeta) bool {
+	return strings.EqualFold(meta.Meta["al:ios:app_name"], "Medium") ||
+		strings.EqualFold(meta.Meta["twitter:app:name:iphone"], "Medium")
+}
@@ -0,0 +1,77 @@
+package RSSFFS
+
+import "testing"
+
+// TestMediumResolver tests Medium feed resolution for handles, publications, tags and custom domains
+func TestMediumResolver(t *testing.T) {
+	runResolverTests(t, mediumResolver{}, []resolverTestCase{
+		{
+			name:      "Handle",
+			url:       "https://medium.com/@janedoe",
+			fixture:   "medium_profile.html",
+			wantMatch: true,
+			want:      []string{"https://medium.com/feed/@janedoe"},
+		},
+		{
+			name:      "Handle with post slug",
+			url:       "https://medium.com/@janedoe/my-first-post-1a2b3c",
+			wantMatch: true,
+			want:      []string{"https://medium.com/feed/@janedoe"},
+		},
+		{
+			name:      "Publication",
+			url:       "https://medium.com/rokkorxblog",
+			wantMatch: true,
+			want:      []string{"https://medium.com/feed/rokkorxblog"},
+		},
+		{
+			name:      "Publication post",
+			url:       "https://medium.com/better-programming/some-article-123",
+			wantMatch: true,
+			want:      []string{"https://medium.com/feed/better-programming"},
+		},
+		{
+			name:      "Tag",
+			url:       "https://medium.com/tag/technology",
+			wantMatch: true,
+			want:      []string{"https://medium.com/feed/tag/technology"},
+		},
+		{
+			name:      "Already a feed",
+			url:       "https://medium.com/feed/@janedoe",
+			wantMatch: true,
+			want:      []string{"https://medium.com/feed/@janedoe"},
+		},
+		{
+			name:      "Root",
+			url:       "https://medium.com",
+			wantMatch: true,
+			want:      nil,
+		},
+		{
+			name:      "Reserved path",
+			url:       "https://medium.com/search?q=go",
+			wantMatch: true,
+			want:      nil,
+		},
+		{
+			name:      "Subdomain blog",
+			url:       "https://janedoe.medium.com/some-post-abc",
+			wantMatch: true,
+			want:      []string{"https://janedoe.medium.com/feed"},
+		},
+		{
+			name:      "Custom domain detected from page metadata",
+			url:       "https://engineering.example.com/scaling-things-42",
+			fixture:   "medium_custom_domain.html",
+			wantMatch: true,
+			want:      []string{"https://engineering.example.com/feed"},
+		},
+		{
+			name:      "Unrelated site",
+			url:       "https://blog.example.org/",
+			fixture:   "plain_blog.html",
+			wantMatch: false,
+		},
+	})
+}
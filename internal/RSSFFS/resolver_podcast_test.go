@@ -0,0 +1,125 @@
+package RSSFFS
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestPodcastDirectoryID tests show ID extraction from podcast directory links
+func TestPodcastDirectoryID(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://podcasts.apple.com/us/podcast/some-show/id123456789", "123456789"},
+		{"https://podcasts.apple.com/gb/podcast/id987654?i=1000600000000", "987654"},
+		{"https://itunes.apple.com/us/podcast/some-show/id123456789?mt=2", "123456789"},
+		{"https://itunes.apple.com/WebObjects/MZStore.woa/wa/viewPodcast?id=555", "555"},
+		{"https://overcast.fm/itunes123456789/some-show", "123456789"},
+		{"https://castro.fm/itunes/123456789", "123456789"},
+		{"https://pca.st/itunes/123456789", "123456789"},
+		{"https://podcasts.apple.com/us/browse", ""},
+		{"https://overcast.fm/+AbCdEf", ""},
+		{"https://example.com/podcast/id123", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			target, err := newTarget(tt.url)
+			if err != nil {
+				t.Fatalf("Invalid test URL %q: %v", tt.url, err)
+			}
+			if got := podcastDirectoryID(target); got != tt.expected {
+				t.Errorf("Expected ID %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestPodcastResolverLookup tests resolving a show ID against an iTunes-lookup-compatible stand-in
+func TestPodcastResolverLookup(t *testing.T) {
+	var gotQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/lookup" {
+			http.NotFound(w, r)
+			return
+		}
+		gotQuery = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("id") == "404" {
+			_, _ = w.Write([]byte(`{"resultCount":0,"results":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"resultCount":1,"results":[{"wrapperType":"track","kind":"podcast","feedUrl":"https://feeds.example.com/show.xml"}]}`))
+	}))
+	defer server.Close()
+
+	target, _ := newTarget("https://podcasts.apple.com/us/podcast/some-show/id123456789")
+	target.run = &settings{itunesLookupBaseURL: server.URL + "/"}
+	if !(podcastResolver{}).Match(target) {
+		t.Fatal("Expected podcast resolver to match Apple Podcasts URL")
+	}
+	candidates := podcastResolver{}.Candidates(target)
+	if len(candidates) != 1 || candidates[0] != "https://feeds.example.com/show.xml" {
+		t.Errorf("Expected feed from lookup, got %v", candidates)
+	}
+	if gotQuery.Get("id") != "123456789" {
+		t.Errorf("Expected lookup for id 123456789, got %q", gotQuery.Get("id"))
+	}
+
+	if _, err := lookupPodcastFeed(server.URL+"/", "404"); err == nil {
+		t.Error("Expected error for podcast without a feed URL, got none")
+	}
+}
+
+// TestPodcastMetadata tests podcast detection from the itunes namespace and podcast:guid parsing
+func TestPodcastMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantPodcast bool
+		wantGUID    string
+	}{
+		{
+			name: "Podcast with guid",
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:podcast="https://podcastindex.org/namespace/1.0">
+  <channel>
+    <title>Some Show</title>
+    <itunes:author>Alice</itunes:author>
+    <podcast:guid> 917393e3-1b1e-5cef-ace4-edaa54e1f810 </podcast:guid>
+    <item><title>Episode 1</title><podcast:guid>not-the-show-guid</podcast:guid></item>
+  </channel>
+</rss>`,
+			wantPodcast: true,
+			wantGUID:    "917393e3-1b1e-5cef-ace4-edaa54e1f810",
+		},
+		{
+			name:        "Podcast without guid",
+			body:        `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel><title>Show</title><item/></channel></rss>`,
+			wantPodcast: true,
+		},
+		{
+			name: "Blog feed",
+			body: `<rss version="2.0"><channel><title>Blog</title><item><title>Post</title></item></channel></rss>`,
+		},
+		{
+			name: "Atom feed",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title><entry><title>Post</title></entry></feed>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podcast, guid := podcastMetadata([]byte(tt.body))
+			if podcast != tt.wantPodcast {
+				t.Errorf("Expected podcast %t, got %t", tt.wantPodcast, podcast)
+			}
+			if guid != tt.wantGUID {
+				t.Errorf("Expected guid %q, got %q", tt.wantGUID, guid)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package RSSFFS
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// resolverTestCase describes one resolver expectation against a URL and an optional saved HTML fixture
+type resolverTestCase struct {
+	name      string
+	url       string
+	fixture   string // file name under testdata/resolvers; empty means the page has no HTML
+	wantMatch bool
+	want      []string
+}
+
+// loadResolverFixture reads a saved HTML page from testdata/resolvers
+func loadResolverFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	if name == "" {
+		return nil
+	}
+	body, err := os.ReadFile(filepath.Join("testdata", "resolvers", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s: %v", name, err)
+	}
+	return body
+}
+
+// runResolverTests runs table-driven Match and Candidates checks for a resolver
+func runResolverTests(t *testing.T, r Resolver, tests []resolverTestCase) {
+	t.Helper()
+	runResolverTestsWith(t, nil, r, tests)
+}
+
+// runResolverTestsWith runs resolver tests for targets resolved for a run with settings s
+func runResolverTestsWith(t *testing.T, s *settings, r Resolver, tests []resolverTestCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("Invalid test URL %q: %v", tt.url, err)
+			}
+			target := newTargetWithHTML(u, loadResolverFixture(t, tt.fixture))
+			target.run = s
+
+			if got := r.Match(target); got != tt.wantMatch {
+				t.Fatalf("Match(%s): expected %t, got %t", tt.url, tt.wantMatch, got)
+			}
+			if !tt.wantMatch {
+				return
+			}
+			if got := r.Candidates(target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Candidates(%s): expected %v, got %v", tt.url, tt.want, got)
+			}
+		})
+	}
+}
+
+// stubResolver is a Resolver with fixed behaviour for registry tests
+type stubResolver struct {
+	name       string
+	priority   int
+	match      bool
+	candidates []string
+}
+
+func (s stubResolver) Name() string                  { return s.name }
+func (s stubResolver) Priority() int                 { return s.priority }
+func (s stubResolver) Match(t *Target) bool          { return s.match }
+func (s stubResolver) Candidates(t *Target) []string { return s.candidates }
+
+// TestResolverRegistryOrdering tests that resolvers are consulted by priority and candidates are deduplicated
+func TestResolverRegistryOrdering(t *testing.T) {
+	resolversMu.Lock()
+	saved := resolvers
+	resolvers = nil
+	resolversMu.Unlock()
+	defer func() {
+		resolversMu.Lock()
+		resolvers = saved
+		resolversMu.Unlock()
+	}()
+
+	registerResolver(stubResolver{name: "late", priority: 90, match: true, candidates: []string{"https://example.com/c", "https://example.com/a"}})
+	registerResolver(stubResolver{name: "never", priority: 1, match: false, candidates: []string{"https://example.com/never"}})
+	registerResolver(stubResolver{name: "early", priority: 5, match: true, candidates: []string{"https://example.com/a", "https://example.com/b"}})
+
+	u, _ := url.Parse("https://example.com/page")
+	target := newTargetWithHTML(u, nil)
+
+	matched := matchingResolvers(target)
+	if len(matched) != 2 || matched[0].Name() != "early" || matched[1].Name() != "late" {
+		t.Fatalf("Expected [early late], got %v", matched)
+	}
+
+	expected := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
+	if got := resolveCandidates(target); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected candidates %v, got %v", expected, got)
+	}
+}
+
+// TestTargetForDomain tests that resolver targets are only built for URLs on the checked domain
+func TestTargetForDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		domain      string
+		originalURL string
+		expectNil   bool
+	}{
+		{name: "Same domain", domain: "medium.com", originalURL: "https://medium.com/@user", expectNil: false},
+		{name: "Case-insensitive host", domain: "medium.com", originalURL: "https://Medium.com/@user", expectNil: false},
+		{name: "No scheme", domain: "example.com", originalURL: "example.com/blog", expectNil: false},
+		{name: "Different domain", domain: "example.com", originalURL: "https://medium.com/@user", expectNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := targetForDomain(tt.domain, tt.originalURL)
+			if (target == nil) != tt.expectNil {
+				t.Errorf("Expected nil=%t, got %v", tt.expectNil, target)
+			}
+		})
+	}
+}
+
+// TestURLOnlyTargets tests that targets resolved from their URL alone never
+// fetch their page, and that hosts resolvers know by name are recognised
+func TestURLOnlyTargets(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://www.youtube.com/@alice", true},
+		{"https://github.com/alice/project", true},
+		{"https://medium.com/@alice", true},
+		{"https://bsky.app/profile/alice.example.org", true},
+		{"http://192.168.0.1/blog/post", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			target, err := newTarget(tt.url)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := matchesHost(target); got != tt.want {
+				t.Errorf("Expected matchesHost %t, got %t", tt.want, got)
+			}
+		})
+	}
+
+	u, _ := url.Parse("https://example.com/page")
+	target := &Target{URL: u, urlOnly: true, html: []byte("<html></html>")}
+	if body := target.HTML(); body != nil {
+		t.Errorf("Expected no HTML for a URL-only target, got %s", body)
+	}
+}
@@ -0,0 +1,156 @@
+package RSSFFS
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// youtubeChannelURLPattern matches channel IDs in canonical and og:url links
+	youtubeChannelURLPattern = regexp.MustCompile(`/channel/(UC[0-9A-Za-z_-]{22})`)
+	// youtubeChannelJSONPattern matches channel IDs embedded in the page's inline JSON
+	youtubeChannelJSONPattern = regexp.MustCompile(`"(?:channelId|externalId|browseId)"\s*:\s*"(UC[0-9A-Za-z_-]{22})"`)
+	// youtubeChannelMetaPattern matches <meta itemprop="channelId">, which YouTube places in <body>
+	youtubeChannelMetaPattern = regexp.MustCompile(`itemprop="(?:channelId|identifier)"\s+content="(UC[0-9A-Za-z_-]{22})"`)
+)
+
+// youtubeFeedBase is the base URL of every YouTube channel and playlist feed
+const youtubeFeedBase = "https://www.youtube.com/feeds/videos.xml"
+
+// youtubeResolver maps YouTube channel, @handle, /c/, /user/, video and playlist
+// URLs to YouTube's videos.xml feeds
+type youtubeResolver struct{}
+
+// peertubeResolver maps PeerTube accounts and channels to their /feeds/videos.xml endpoints
+type peertubeResolver struct{}
+
+// vimeoResolver maps Vimeo users, channels and groups to their video feeds
+type vimeoResolver struct{}
+
+func init() {
+	registerResolver(youtubeResolver{})
+	registerResolver(peertubeResolver{})
+	registerResolver(vimeoResolver{})
+}
+
+func (youtubeResolver) Name() string { return "youtube" }
+
+func (youtubeResolver) Priority() int { return priorityHost }
+
+func (youtubeResolver) Match(t *Target) bool {
+	host := t.Host()
+	return host == "youtube.com" || host == "www.youtube.com" || host == "m.youtube.com"
+}
+
+func (youtubeResolver) Candidates(t *Target) []string {
+	// Playlists, including videos watched as part of one
+	if playlistID := t.URL.Query().Get("list"); playlistID != "" {
+		return []string{youtubeFeedBase + "?playlist_id=" + url.QueryEscape(playlistID)}
+	}
+
+	segments := t.PathSegments()
+	if len(segments) >= 2 && segments[0] == "channel" {
+		return []string{youtubeFeedBase + "?channel_id=" + url.QueryEscape(segments[1])}
+	}
+
+	var candidates []string
+	if channelID := youtubeChannelIDFromHTML(t.HTML()); channelID != "" {
+		candidates = append(candidates, youtubeFeedBase+"?channel_id="+channelID)
+	}
+
+	// Legacy /user/ URLs still have a username-based feed
+	if len(segments) >= 2 && segments[0] == "user" {
+		candidates = append(candidates, youtubeFeedBase+"?user="+url.QueryEscape(segments[1]))
+	}
+
+	return candidates
+}
+
+// youtubeChannelIDFromHTML extracts a channel ID from a YouTube page, preferring
+// the canonical and og:url metadata over IDs embedded in the page's scripts
+func youtubeChannelIDFromHTML(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	meta := parseHeadMeta(body)
+	for _, candidate := range []string{meta.link("canonical"), meta.Meta["og:url"]} {
+		if m := youtubeChannelURLPattern.FindStringSubmatch(candidate); m != nil {
+			return m[1]
+		}
+	}
+
+	for _, pattern := range []*regexp.Regexp{youtubeChannelMetaPattern, youtubeChannelJSONPattern} {
+		if m := pattern.FindSubmatch(body); m != nil {
+			return string(m[1])
+		}
+	}
+
+	return ""
+}
+
+func (peertubeResolver) Name() string { return "peertube" }
+
+func (peertubeResolver) Priority() int { return priorityPage }
+
+func (peertubeResolver) Match(t *Target) bool {
+	return isPeerTubePage(parseHeadMeta(t.HTML()))
+}
+
+func (peertubeResolver) Candidates(t *Target) []string {
+	feedBase := t.origin() + "/feeds/videos.xml"
+	segments := t.PathSegments()
+	if len(segments) < 2 {
+		// Instance home page: recent local videos
+		return []string{feedBase + "?sort=-publishedAt&isLocal=true"}
+	}
+
+	name := segments[1]
+	switch segments[0] {
+	case "a", "accounts":
+		return []string{feedBase + "?accountName=" + url.QueryEscape(name)}
+	case "c", "video-channels":
+		return []string{feedBase + "?videoChannelName=" + url.QueryEscape(name)}
+	}
+	return nil
+}
+
+// isPeerTubePage reports whether a page's metadata identifies it as a PeerTube instance
+func isPeerTubePage(meta headMeta) bool {
+	return strings.EqualFold(meta.Meta["og:platform"], "PeerTube") ||
+		meta.metaContains("generator", "peertube")
+}
+
+func (vimeoResolver) Name() string { return "vimeo" }
+
+func (vimeoResolver) Priority() int { return priorityHost }
+
+func (vimeoResolver) Match(t *Target) bool {
+	host := t.Host()
+	return host == "vimeo.com" || host == "www.vimeo.com"
+}
+
+func (vimeoResolver) Candidates(t *Target) []string {
+	segments := t.PathSegments()
+	if len(segments) == 0 {
+		return nil
+	}
+
+	switch segments[0] {
+	case "channels", "groups":
+		if len(segments) >= 2 {
+			return []string{"https://vimeo.com/" + segments[0] + "/" + segments[1] + "/videos/rss"}
+		}
+		return nil
+	case "ondemand", "watch", "features", "search", "categories", "upload", "log_in", "join":
+		return nil
+	}
+
+	// Numeric paths are individual videos, which have no feed of their own
+	if strings.Trim(segments[0], "0123456789") == "" {
+		return nil
+	}
+
+	return []string{"https://vimeo.com/" + segments[0] + "/videos/rss"}
+}
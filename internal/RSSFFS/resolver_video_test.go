@@ -0,0 +1,138 @@
+package RSSFFS
+
+import "testing"
+
+// TestYouTubeResolver tests YouTube channel, handle, legacy and playlist URL resolution
+func TestYouTubeResolver(t *testing.T) {
+	runResolverTests(t, youtubeResolver{}, []resolverTestCase{
+		{
+			name:      "Channel ID URL",
+			url:       "https://www.youtube.com/channel/UCabcdefghijklmnopqrstuv",
+			wantMatch: true,
+			want:      []string{"https://www.youtube.com/feeds/videos.xml?channel_id=UCabcdefghijklmnopqrstuv"},
+		},
+		{
+			name:      "Handle resolved from canonical link",
+			url:       "https://www.youtube.com/@example",
+			fixture:   "youtube_handle.html",
+			wantMatch: true,
+			want:      []string{"https://www.youtube.com/feeds/videos.xml?channel_id=UCabcdefghijklmnopqrstuv"},
+		},
+		{
+			name:      "Custom /c/ URL",
+			url:       "https://youtube.com/c/ExampleChannel",
+			fixture:   "youtube_handle.html",
+			wantMatch: true,
+			want:      []string{"https://www.youtube.com/feeds/videos.xml?channel_id=UCabcdefghijklmnopqrstuv"},
+		},
+		{
+			name:      "Legacy /user/ URL",
+			url:       "https://www.youtube.com/user/example",
+			fixture:   "youtube_handle.html",
+			wantMatch: true,
+			want: []string{
+				"https://www.youtube.com/feeds/videos.xml?channel_id=UCabcdefghijklmnopqrstuv",
+				"https://www.youtube.com/feeds/videos.xml?user=example",
+			},
+		},
+		{
+			name:      "Legacy /user/ URL without page",
+			url:       "https://www.youtube.com/user/example",
+			wantMatch: true,
+			want:      []string{"https://www.youtube.com/feeds/videos.xml?user=example"},
+		},
+		{
+			name:      "Video page resolves to its channel",
+			url:       "https://m.youtube.com/watch?v=dQw4w9WgXcQ",
+			fixture:   "youtube_watch.html",
+			wantMatch: true,
+			want:      []string{"https://www.youtube.com/feeds/videos.xml?channel_id=UCzyxwvutsrqponmlkjihgfe"},
+		},
+		{
+			name:      "Playlist",
+			url:       "https://www.youtube.com/playlist?list=PL1234567890abcdef",
+			wantMatch: true,
+			want:      []string{"https://www.youtube.com/feeds/videos.xml?playlist_id=PL1234567890abcdef"},
+		},
+		{
+			name:      "Other site",
+			url:       "https://example.com/channel/UCabcdefghijklmnopqrstuv",
+			wantMatch: false,
+		},
+	})
+}
+
+// TestPeerTubeResolver tests PeerTube detection and account/channel feed mapping
+func TestPeerTubeResolver(t *testing.T) {
+	runResolverTests(t, peertubeResolver{}, []resolverTestCase{
+		{
+			name:      "Channel short URL",
+			url:       "https://videos.example.net/c/open_science/videos",
+			fixture:   "peertube_channel.html",
+			wantMatch: true,
+			want:      []string{"https://videos.example.net/feeds/videos.xml?videoChannelName=open_science"},
+		},
+		{
+			name:      "Channel long URL",
+			url:       "https://videos.example.net/video-channels/open_science",
+			fixture:   "peertube_channel.html",
+			wantMatch: true,
+			want:      []string{"https://videos.example.net/feeds/videos.xml?videoChannelName=open_science"},
+		},
+		{
+			name:      "Account",
+			url:       "https://videos.example.net/a/alice",
+			fixture:   "peertube_channel.html",
+			wantMatch: true,
+			want:      []string{"https://videos.example.net/feeds/videos.xml?accountName=alice"},
+		},
+		{
+			name:      "Instance home page",
+			url:       "https://videos.example.net/",
+			fixture:   "peertube_channel.html",
+			wantMatch: true,
+			want:      []string{"https://videos.example.net/feeds/videos.xml?sort=-publishedAt&isLocal=true"},
+		},
+		{
+			name:      "Not a PeerTube instance",
+			url:       "https://blog.example.org/c/something",
+			fixture:   "plain_blog.html",
+			wantMatch: false,
+		},
+	})
+}
+
+// TestVimeoResolver tests Vimeo user, channel and group feed mapping
+func TestVimeoResolver(t *testing.T) {
+	runResolverTests(t, vimeoResolver{}, []resolverTestCase{
+		{
+			name:      "User",
+			url:       "https://vimeo.com/someartist",
+			wantMatch: true,
+			want:      []string{"https://vimeo.com/someartist/videos/rss"},
+		},
+		{
+			name:      "Channel",
+			url:       "https://vimeo.com/channels/staffpicks/123456",
+			wantMatch: true,
+			want:      []string{"https://vimeo.com/channels/staffpicks/videos/rss"},
+		},
+		{
+			name:      "Group",
+			url:       "https://vimeo.com/groups/motion",
+			wantMatch: true,
+			want:      []string{"https://vimeo.com/groups/motion/videos/rss"},
+		},
+		{
+			name:      "Single video",
+			url:       "https://vimeo.com/76979871",
+			wantMatch: true,
+			want:      nil,
+		},
+		{
+			name:      "Other site",
+			url:       "https://example.com/someartist",
+			wantMatch: false,
+		},
+	})
+}
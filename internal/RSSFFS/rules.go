@@ -0,0 +1,240 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule actions. A rule without an action only sets the category or title of
+// the feeds it matches, which are still subject to the feed filter.
+const (
+	RuleAccept = "accept"
+	RuleReject = "reject"
+)
+
+// Rule decides what happens to the candidates its When expression matches:
+// they are accepted regardless of the feed filter, rejected, or subscribed to
+// in Category and renamed to the result of the Title expression.
+type Rule struct {
+	Name     string `yaml:"name"`
+	When     string `yaml:"when"`
+	Action   string `yaml:"action,omitempty"`
+	Category string `yaml:"category,omitempty"`
+	Title    string `yaml:"title,omitempty"`
+
+	when  *vm.Program
+	title *vm.Program
+}
+
+// RuleSet is an ordered list of rules read from a rules file. The first rule
+// whose When expression matches a candidate decides for it; Default is the
+// action for candidates no rule matches, "reject" to only subscribe to feeds
+// a rule accepts.
+type RuleSet struct {
+	Default string `yaml:"default,omitempty"`
+	Rules   []Rule `yaml:"rules"`
+}
+
+// RuleDecision is the outcome of evaluating a rule set against a candidate.
+// Rule is the name of the matching rule, empty when none matched.
+type RuleDecision struct {
+	Rule     string
+	Action   string
+	Category string
+	Title    string
+}
+
+// ruleEnv is what rule expressions are evaluated against: the candidate feed,
+// the metadata read from its document and the page the run started from
+type ruleEnv struct {
+	URL        string `expr:"url"`
+	Domain     string `expr:"domain"`
+	Path       string `expr:"path"`
+	Source     string `expr:"source"`
+	Page       string `expr:"page"`
+	PageDomain string `expr:"page_domain"`
+	Platform   string `expr:"platform"`
+	Bridge     string `expr:"bridge"`
+	Mentions   int    `expr:"mentions"`
+
+	Title       string `expr:"title"`
+	Description string `expr:"description"`
+	Link        string `expr:"link"`
+	Language    string `expr:"language"`
+	Generator   string `expr:"generator"`
+	Items       int    `expr:"items"`
+	// AgeDays is the number of days since the newest item, or -1 when unknown
+	AgeDays  int    `expr:"age_days"`
+	Podcast  bool   `expr:"podcast"`
+	FullText bool   `expr:"full_text"`
+	Hub      string `expr:"hub"`
+
+	Glob func(pattern string, s string) bool `expr:"glob"`
+}
+
+// LoadRules reads and compiles a YAML rules file
+func LoadRules(file string) (*RuleSet, error) {
+	data, err := os.ReadFile(file) // #nosec G304 -- file is from config
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules file %s: %w", file, err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("error in rules file %s: %w", file, err)
+	}
+	return rules, nil
+}
+
+// ParseRules parses and compiles YAML rules such as:
+//
+//	rules:
+//	  - name: newsletters
+//	    when: glob("*.substack.com", domain)
+//	    category: Newsletters
+//	  - name: no-jobs
+//	    when: title matches "(?i)jobs"
+//	    action: reject
+func ParseRules(data []byte) (*RuleSet, error) {
+	rules := &RuleSet{}
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, err
+	}
+	if rules.Default != "" && rules.Default != RuleAccept && rules.Default != RuleReject {
+		return nil, fmt.Errorf("invalid default action %q (use accept or reject)", rules.Default)
+	}
+
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if strings.TrimSpace(rule.When) == "" {
+			return nil, fmt.Errorf("%s: missing when expression", rule.Name)
+		}
+		switch rule.Action {
+		case "", RuleAccept:
+		case RuleReject:
+			if rule.Category != "" || rule.Title != "" {
+				return nil, fmt.Errorf("%s: a rejecting rule cannot set a category or title", rule.Name)
+			}
+		default:
+			return nil, fmt.Errorf("%s: invalid action %q (use accept or reject)", rule.Name, rule.Action)
+		}
+
+		var err error
+		if rule.when, err = expr.Compile(rule.When, expr.Env(ruleEnv{}), expr.AsBool()); err != nil {
+			return nil, fmt.Errorf("%s: invalid when expression: %w", rule.Name, err)
+		}
+		if rule.Title != "" {
+			if rule.title, err = expr.Compile(rule.Title, expr.Env(ruleEnv{}), expr.AsKind(reflect.String)); err != nil {
+				return nil, fmt.Errorf("%s: invalid title expression: %w", rule.Name, err)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// Evaluate returns the decision of the first rule matching candidate, found on
+// or linked from page. Rules whose expressions fail to evaluate are skipped.
+// A nil rule set decides nothing.
+func (rs *RuleSet) Evaluate(candidate Candidate, page string, now time.Time) RuleDecision {
+	if rs == nil {
+		return RuleDecision{}
+	}
+	env := newRuleEnv(candidate, page, now)
+	for _, rule := range rs.Rules {
+		matched, err := expr.Run(rule.when, env)
+		if err != nil {
+			log.Warnf("Rule %s failed for %s: %v", rule.Name, candidate.URL, err)
+			continue
+		}
+		if matched != true {
+			continue
+		}
+
+		decision := RuleDecision{Rule: rule.Name, Action: rule.Action, Category: rule.Category}
+		if rule.title != nil {
+			title, err := expr.Run(rule.title, env)
+			if err != nil {
+				log.Warnf("Rule %s could not set the title of %s: %v", rule.Name, candidate.URL, err)
+			} else {
+				decision.Title = strings.TrimSpace(title.(string))
+			}
+		}
+		return decision
+	}
+	return RuleDecision{Action: rs.Default}
+}
+
+// newRuleEnv describes a candidate to rule expressions
+func newRuleEnv(candidate Candidate, page string, now time.Time) ruleEnv {
+	env := ruleEnv{
+		URL:      candidate.URL,
+		Source:   candidate.Source,
+		Page:     page,
+		Platform: candidate.Platform,
+		Bridge:   candidate.Bridge,
+		Mentions: candidate.Mentions,
+		Language: candidate.Hreflang,
+		AgeDays:  -1,
+		Glob: func(pattern string, s string) bool {
+			matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(s))
+			return matched
+		},
+	}
+	if u, err := url.Parse(candidate.URL); err == nil {
+		env.Domain = strings.ToLower(u.Hostname())
+		env.Path = u.Path
+	}
+	if u, err := url.Parse(page); err == nil {
+		env.PageDomain = strings.ToLower(u.Hostname())
+	}
+	if info := candidate.Info; info != nil {
+		env.Title = info.Title
+		env.Description = info.Description
+		env.Link = info.Link
+		if info.Language != "" {
+			env.Language = info.Language
+		}
+		env.Generator = info.Generator
+		env.Items = info.ItemCount
+		if !info.Newest.IsZero() {
+			env.AgeDays = int(now.Sub(info.Newest) / (24 * time.Hour))
+		}
+		env.Podcast = info.Podcast
+		env.FullText = info.FullText
+		env.Hub = info.Hub
+	}
+	return env
+}
+
+// evaluateCandidate applies the run's rules and feed filter to a candidate,
+// returning a result that is skipped when a rule rejects it or, unless a rule
+// accepts it, when the feed filter leaves it out
+func (s *settings) evaluateCandidate(candidate Candidate, page string, now time.Time) Result {
+	decision := s.rules.Evaluate(candidate, page, now)
+	result := Result{Candidate: candidate, Rule: decision.Rule, Category: decision.Category, Title: decision.Title}
+	switch decision.Action {
+	case RuleReject:
+		if decision.Rule != "" {
+			result.Skipped = "rejected by rule " + decision.Rule
+		} else {
+			result.Skipped = "no rule accepted it"
+		}
+	case RuleAccept:
+		// Accepted feeds bypass the feed filter
+	default:
+		result.Skipped = s.filter.skipReason(candidate, now)
+	}
+	return result
+}
@@ -0,0 +1,213 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRules = `
+rules:
+  - name: newsletters
+    when: glob("*.substack.com", domain)
+    category: Newsletters
+  - name: podcasts
+    when: podcast
+    action: accept
+    category: Podcasts
+  - name: no-jobs
+    when: title matches "(?i)jobs"
+    action: reject
+  - name: releases
+    when: domain == "github.com"
+    category: Releases
+    title: '"Releases: " + split(path, "/")[2]'
+`
+
+// TestParseRulesErrors tests that invalid rules files are rejected
+func TestParseRulesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{name: "invalid YAML", rules: "rules: [name"},
+		{name: "invalid default", rules: "default: maybe"},
+		{name: "missing when", rules: "rules:\n  - name: empty\n"},
+		{name: "invalid action", rules: "rules:\n  - when: podcast\n    action: ignore\n"},
+		{name: "rejecting rule with category", rules: "rules:\n  - when: podcast\n    action: reject\n    category: Podcasts\n"},
+		{name: "unknown variable", rules: "rules:\n  - when: colour == \"red\"\n"},
+		{name: "non-boolean when", rules: "rules:\n  - when: title\n"},
+		{name: "non-string title", rules: "rules:\n  - when: podcast\n    title: items\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRules([]byte(tt.rules)); err == nil {
+				t.Errorf("Expected error for %q, got none", tt.rules)
+			}
+		})
+	}
+}
+
+// TestRuleSetEvaluate tests that the first matching rule decides for a candidate
+func TestRuleSetEvaluate(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		candidate Candidate
+		expected  RuleDecision
+	}{
+		{
+			name:      "Substack newsletter",
+			candidate: Candidate{URL: "https://alice.substack.com/feed"},
+			expected:  RuleDecision{Rule: "newsletters", Category: "Newsletters"},
+		},
+		{
+			name:      "Podcast",
+			candidate: Candidate{URL: "https://feeds.example.com/show.xml", Info: &FeedInfo{Podcast: true}},
+			expected:  RuleDecision{Rule: "podcasts", Action: RuleAccept, Category: "Podcasts"},
+		},
+		{
+			name:      "Job board",
+			candidate: Candidate{URL: "https://example.com/feed", Info: &FeedInfo{Title: "Remote JOBS weekly"}},
+			expected:  RuleDecision{Rule: "no-jobs", Action: RuleReject},
+		},
+		{
+			name:      "GitHub releases",
+			candidate: Candidate{URL: "https://github.com/spf13/cobra/releases.atom"},
+			expected:  RuleDecision{Rule: "releases", Category: "Releases", Title: "Releases: cobra"},
+		},
+		{
+			name:      "No rule matches",
+			candidate: Candidate{URL: "https://blog.example.com/feed", Info: &FeedInfo{Title: "Notes"}},
+			expected:  RuleDecision{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules.Evaluate(tt.candidate, "https://example.com/links", time.Now())
+			if got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	var none *RuleSet
+	if got := none.Evaluate(Candidate{URL: "https://example.com/feed"}, "", time.Now()); got != (RuleDecision{}) {
+		t.Errorf("Expected no decision from nil rule set, got %+v", got)
+	}
+}
+
+// TestRuleEnv tests the values rule expressions can use
+func TestRuleEnv(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	rules, err := ParseRules([]byte(`
+rules:
+  - when: >
+      page_domain == "links.example.com" && source == "linked from links.example.com" &&
+      age_days == 10 && items == 4 && language == "en-GB" && platform == "wordpress" && full_text
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	candidate := Candidate{URL: "https://blog.example.com/feed/", Source: "linked from links.example.com", Platform: "wordpress", Hreflang: "en",
+		Info: &FeedInfo{Language: "en-GB", ItemCount: 4, Newest: now.Add(-10 * 24 * time.Hour), FullText: true}}
+	if got := rules.Evaluate(candidate, "https://links.example.com/", now); got.Rule != "rule 1" {
+		t.Errorf("Expected rule 1 to match, got %+v", got)
+	}
+
+	candidate.Info = nil
+	if got := rules.Evaluate(candidate, "https://links.example.com/", now); got.Rule != "" {
+		t.Errorf("Expected no match without feed metadata, got %+v", got)
+	}
+}
+
+// TestEvaluateCandidate tests how rules combine with the feed filter
+func TestEvaluateCandidate(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules + "default: reject\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := &settings{filter: FeedFilter{MinItems: 5}, rules: rules}
+
+	tests := []struct {
+		name      string
+		candidate Candidate
+		skipped   string
+	}{
+		{name: "Accepting rule bypasses the filter", candidate: Candidate{URL: "https://example.com/show.xml", Info: &FeedInfo{Podcast: true, ItemCount: 1}}},
+		{name: "Categorising rule keeps the filter", candidate: Candidate{URL: "https://alice.substack.com/feed", Info: &FeedInfo{ItemCount: 1}}, skipped: "1 items, fewer than 5"},
+		{name: "Rejecting rule", candidate: Candidate{URL: "https://example.com/jobs", Info: &FeedInfo{Title: "Jobs", ItemCount: 9}}, skipped: "rejected by rule no-jobs"},
+		{name: "Default action", candidate: Candidate{URL: "https://example.com/feed", Info: &FeedInfo{ItemCount: 9}}, skipped: "no rule accepted it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.evaluateCandidate(tt.candidate, "", time.Now()); got.Skipped != tt.skipped {
+				t.Errorf("Expected skip reason %q, got %q", tt.skipped, got.Skipped)
+			}
+		})
+	}
+}
+
+// TestSubscribeCandidatesWithRules tests that rules choose the category and title feeds are subscribed with
+func TestSubscribeCandidatesWithRules(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	subscribed := make(map[string]int)
+	renamed := make(map[int]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/categories":
+			_, _ = fmt.Fprint(w, `[{"id": 1, "title": "Blogs"}, {"id": 2, "title": "Releases"}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/feeds":
+			var body struct {
+				FeedURL    string `json:"feed_url"`
+				CategoryID int    `json:"category_id"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			subscribed[body.FeedURL] = body.CategoryID
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"feed_id": %d}`, 40+len(subscribed))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v1/feeds/"):
+			var body struct {
+				Title string `json:"title"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			renamed[len(renamed)] = r.URL.Path + " " + body.Title
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	s := &settings{reader: NewReaderClient(server.URL, ""), rules: rules}
+
+	report := s.subscribeCandidates([]Candidate{
+		{URL: "https://github.com/spf13/cobra/releases.atom", Info: &FeedInfo{}},
+		{URL: "https://blog.example.com/feed", Info: &FeedInfo{}},
+		{URL: "https://example.com/jobs.xml", Info: &FeedInfo{Title: "Jobs"}},
+	}, "https://example.com/links", 1, false, "Test")
+
+	if subscribed["https://github.com/spf13/cobra/releases.atom"] != 2 || subscribed["https://blog.example.com/feed"] != 1 || len(subscribed) != 2 {
+		t.Errorf("Expected rule and default categories, got %v", subscribed)
+	}
+	if len(renamed) != 1 || renamed[0] != "/v1/feeds/41 Releases: cobra" {
+		t.Errorf("Expected the releases feed renamed, got %v", renamed)
+	}
+	if report.SubscribedCount() != 2 || report.SkippedCount() != 1 || report.Results[0].Rule != "releases" {
+		t.Errorf("Unexpected report %+v", report.Results)
+	}
+}
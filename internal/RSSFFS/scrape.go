@@ -0,0 +1,469 @@
+package RSSFFS
+
+import (
+	"bytes"
+	"crypto/sha1" // #nosec G505 -- used for stable entry IDs, not security
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// ScrapeRule describes how to build a feed from a page that publishes none,
+// using CSS selectors. Item selects each entry's container; the other
+// selectors are matched within it.
+type ScrapeRule struct {
+	// ID names the generated feed, served at /generated/<id>.xml
+	ID string `json:"id"`
+	// URL is the page to scrape
+	URL string `json:"url"`
+	// Name is the feed title; the page title is used when empty
+	Name string `json:"name,omitempty"`
+
+	Item    string `json:"item"`
+	Title   string `json:"title"`
+	Link    string `json:"link,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// ScrapedItem is one entry extracted from a page by a ScrapeRule
+type ScrapedItem struct {
+	Title     string    `json:"title"`
+	Link      string    `json:"link,omitempty"`
+	Published time.Time `json:"published,omitzero"`
+	Summary   string    `json:"summary,omitempty"`
+}
+
+// ScrapedFeed is the result of applying a ScrapeRule to its page
+type ScrapedFeed struct {
+	Title string        `json:"title"`
+	Link  string        `json:"link"`
+	Items []ScrapedItem `json:"items"`
+}
+
+// scrapeRuleID restricts rule IDs to characters that are safe in URLs and file names
+var scrapeRuleID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// scrapeDateLayouts are the date formats tried, in order, when reading item dates
+var scrapeDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02 Jan 2006",
+	"Monday, January 2, 2006",
+}
+
+// Validate checks that the rule has a usable ID, an http(s) URL and valid selectors
+func (r ScrapeRule) Validate() error {
+	if !scrapeRuleID.MatchString(r.ID) {
+		return fmt.Errorf("invalid rule ID %q (use lowercase letters, digits, '-' and '_')", r.ID)
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q for rule %s", r.URL, r.ID)
+	}
+	if r.Item == "" || r.Title == "" {
+		return fmt.Errorf("rule %s needs both an item and a title selector", r.ID)
+	}
+	for name, selector := range map[string]string{"item": r.Item, "title": r.Title, "link": r.Link, "date": r.Date, "summary": r.Summary} {
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(selector); err != nil {
+			return fmt.Errorf("invalid %s selector %q for rule %s: %v", name, selector, r.ID, err)
+		}
+	}
+	return nil
+}
+
+// Scrape fetches the rule's page and extracts its items
+func Scrape(rule ScrapeRule) (*ScrapedFeed, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	page, err := fetchPage(rule.URL)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, err
+	}
+	return scrapeHTML(rule, page.Body, base)
+}
+
+// scrapeHTML applies a rule to a page's HTML, resolving links against base
+func scrapeHTML(rule ScrapeRule, body []byte, base *url.URL) (*ScrapedFeed, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	itemSelector, err := cascadia.ParseGroup(rule.Item)
+	if err != nil {
+		return nil, err
+	}
+	titleSelector, err := cascadia.ParseGroup(rule.Title)
+	if err != nil {
+		return nil, err
+	}
+	linkSelector := optionalSelector(rule.Link)
+	dateSelector := optionalSelector(rule.Date)
+	summarySelector := optionalSelector(rule.Summary)
+
+	feed := &ScrapedFeed{Title: rule.Name, Link: base.String()}
+	if feed.Title == "" {
+		if title := cascadia.Query(doc, cascadia.MustCompile("title")); title != nil {
+			feed.Title = nodeText(title)
+		}
+	}
+	if feed.Title == "" {
+		feed.Title = base.Hostname()
+	}
+
+	for _, itemNode := range cascadia.QueryAll(doc, itemSelector) {
+		titleNode := cascadia.Query(itemNode, titleSelector)
+		if titleNode == nil {
+			continue
+		}
+		item := ScrapedItem{Title: nodeText(titleNode)}
+		if item.Title == "" {
+			continue
+		}
+
+		// Without a link selector, use the title's link or the item's first link
+		var linkNode *html.Node
+		if linkSelector != nil {
+			linkNode = cascadia.Query(itemNode, linkSelector)
+		} else if linkNode = closestLink(titleNode); linkNode == nil {
+			linkNode = itemNode
+		}
+		if href := nodeHref(linkNode); href != "" {
+			if resolved, err := base.Parse(href); err == nil {
+				item.Link = resolved.String()
+			}
+		}
+
+		if dateSelector != nil {
+			if dateNode := cascadia.Query(itemNode, dateSelector); dateNode != nil {
+				item.Published = parseScrapedDate(dateNode)
+			}
+		}
+		if summarySelector != nil {
+			if summaryNode := cascadia.Query(itemNode, summarySelector); summaryNode != nil {
+				item.Summary = nodeText(summaryNode)
+			}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// optionalSelector compiles a selector that may be left empty in a rule
+func optionalSelector(selector string) cascadia.Matcher {
+	if selector == "" {
+		return nil
+	}
+	compiled, err := cascadia.ParseGroup(selector)
+	if err != nil {
+		return nil
+	}
+	return compiled
+}
+
+// nodeText returns the whitespace-normalised text content of a node
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// nodeAttr returns the value of a node's attribute, or an empty string
+func nodeAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// closestLink returns n or its nearest <a href> ancestor, for titles wrapped in links
+func closestLink(n *html.Node) *html.Node {
+	for ; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && n.Data == "a" && nodeAttr(n, "href") != "" {
+			return n
+		}
+	}
+	return nil
+}
+
+// nodeHref returns the href of n, or of the first link inside it
+func nodeHref(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if href := nodeAttr(n, "href"); href != "" {
+		return href
+	}
+	if link := cascadia.Query(n, cascadia.MustCompile("a[href]")); link != nil {
+		return nodeAttr(link, "href")
+	}
+	return ""
+}
+
+// parseScrapedDate reads a date from a node's datetime or content attribute,
+// falling back to its text
+func parseScrapedDate(n *html.Node) time.Time {
+	for _, value := range []string{nodeAttr(n, "datetime"), nodeAttr(n, "content"), nodeText(n)} {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		for _, layout := range scrapeDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC()
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// atomFeed and atomEntry are the subset of Atom that generated feeds use
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string     `xml:"title"`
+	ID      string     `xml:"id"`
+	Updated string     `xml:"updated"`
+	Links   []atomLink `xml:"link,omitempty"`
+	Summary string     `xml:"summary,omitempty"`
+}
+
+// RenderAtom renders a scraped feed as an Atom document served at selfURL.
+// Items without a date are stamped with fallback, normally the time the
+// scraped content last changed, so unchanged content renders identically.
+func RenderAtom(rule ScrapeRule, feed *ScrapedFeed, selfURL string, fallback time.Time) ([]byte, error) {
+	updated := time.Time{}
+	entries := make([]atomEntry, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		published := item.Published
+		if published.IsZero() {
+			published = fallback
+		}
+		if published.After(updated) {
+			updated = published
+		}
+
+		entry := atomEntry{
+			Title:   item.Title,
+			ID:      scrapedEntryID(rule, item),
+			Updated: published.UTC().Format(time.RFC3339),
+			Summary: item.Summary,
+		}
+		if item.Link != "" {
+			entry.Links = []atomLink{{Href: item.Link, Rel: "alternate"}}
+		}
+		entries = append(entries, entry)
+	}
+	if updated.IsZero() {
+		updated = fallback
+	}
+
+	doc := atomFeed{
+		Title:   feed.Title,
+		ID:      selfURL,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: selfURL, Rel: "self"},
+			{Href: feed.Link, Rel: "alternate"},
+		},
+		Author:  atomAuthor{Name: feed.Title},
+		Entries: entries,
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// scrapedEntryID identifies an entry by its link, or by a hash of its title when it has none
+func scrapedEntryID(rule ScrapeRule, item ScrapedItem) string {
+	if item.Link != "" {
+		return item.Link
+	}
+	sum := sha1.Sum([]byte(item.Title)) // #nosec G401 -- not used for security
+	return "urn:rssffs:" + rule.ID + ":" + hex.EncodeToString(sum[:])
+}
+
+// ScrapeRuleStore holds scraping rules, persisting them to a JSON file when
+// one is configured and keeping them in memory otherwise
+type ScrapeRuleStore struct {
+	mu    sync.RWMutex
+	path  string
+	rules map[string]ScrapeRule
+}
+
+// NewScrapeRuleStore loads the rules saved in path. An empty path gives an
+// in-memory store, and a missing file an empty one that is created on first save.
+func NewScrapeRuleStore(path string) (*ScrapeRuleStore, error) {
+	store := &ScrapeRuleStore{path: path, rules: make(map[string]ScrapeRule)}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading scrape rules file %s: %w", path, err)
+	}
+
+	var rules []ScrapeRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing scrape rules file %s: %w", path, err)
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("error in scrape rules file %s: %w", path, err)
+		}
+		store.rules[rule.ID] = rule
+	}
+	return store, nil
+}
+
+// List returns every rule, sorted by ID
+func (s *ScrapeRuleStore) List() []ScrapeRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]ScrapeRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// Get returns the rule with the given ID
+func (s *ScrapeRuleStore) Get(id string) (ScrapeRule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rule, ok := s.rules[id]
+	return rule, ok
+}
+
+// Put validates and saves a rule, replacing any rule with the same ID
+func (s *ScrapeRuleStore) Put(rule ScrapeRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, existed := s.rules[rule.ID]
+	s.rules[rule.ID] = rule
+	if err := s.save(); err != nil {
+		if existed {
+			s.rules[rule.ID] = previous
+		} else {
+			delete(s.rules, rule.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the rule with the given ID
+func (s *ScrapeRuleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, existed := s.rules[id]
+	if !existed {
+		return fmt.Errorf("scrape rule %s not found", id)
+	}
+	delete(s.rules, id)
+	if err := s.save(); err != nil {
+		s.rules[id] = previous
+		return err
+	}
+	return nil
+}
+
+// save writes the rules to the store's file, replacing it atomically. Callers must hold s.mu.
+func (s *ScrapeRuleStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	rules := make([]ScrapeRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("error writing scrape rules file %s: %w", s.path, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error writing scrape rules file %s: %w", s.path, err)
+	}
+	return nil
+}
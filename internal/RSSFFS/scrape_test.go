@@ -0,0 +1,189 @@
+package RSSFFS
+
+import (
+	"encoding/xml"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrapeFixture is a news listing page without a feed
+const scrapeFixture = `<!DOCTYPE html>
+<html>
+<head><title>Example News</title></head>
+<body>
+  <article class="post">
+    <h2><a href="/news/first">First   post</a></h2>
+    <time datetime="2024-05-02T10:00:00Z">2 May 2024</time>
+    <p class="excerpt">The first <b>excerpt</b>.</p>
+  </article>
+  <article class="post">
+    <h2>Second post</h2>
+    <span class="date">Apr 30, 2024</span>
+    <a class="more" href="https://other.example.com/second">Read more</a>
+  </article>
+  <article class="post">
+    <h2></h2>
+    <a href="/news/untitled">Untitled</a>
+  </article>
+  <article class="post">
+    <h2>Undated post</h2>
+  </article>
+</body>
+</html>`
+
+// TestScrapeHTML tests extracting items from a page with CSS selectors
+func TestScrapeHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/news/")
+	rule := ScrapeRule{ID: "news", URL: base.String(), Item: "article.post", Title: "h2", Date: "time, .date", Summary: ".excerpt"}
+
+	feed, err := scrapeHTML(rule, []byte(scrapeFixture), base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if feed.Title != "Example News" || feed.Link != "https://example.com/news/" {
+		t.Errorf("Expected title and link from the page, got %q and %q", feed.Title, feed.Link)
+	}
+
+	expected := []ScrapedItem{
+		{Title: "First post", Link: "https://example.com/news/first", Published: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC), Summary: "The first excerpt ."},
+		{Title: "Second post", Link: "https://other.example.com/second", Published: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)},
+		{Title: "Undated post"},
+	}
+	if !reflect.DeepEqual(feed.Items, expected) {
+		t.Errorf("Expected items %+v, got %+v", expected, feed.Items)
+	}
+
+	// An explicit link selector and feed name take precedence
+	rule.Name = "Named"
+	rule.Link = "a.more"
+	feed, err = scrapeHTML(rule, []byte(scrapeFixture), base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if feed.Title != "Named" {
+		t.Errorf("Expected rule name as title, got %q", feed.Title)
+	}
+	if feed.Items[0].Link != "" || feed.Items[1].Link != "https://other.example.com/second" {
+		t.Errorf("Expected links only from the link selector, got %q and %q", feed.Items[0].Link, feed.Items[1].Link)
+	}
+}
+
+// TestScrapeRuleValidate tests scraping rule validation
+func TestScrapeRuleValidate(t *testing.T) {
+	valid := ScrapeRule{ID: "news_2", URL: "https://example.com/", Item: "li", Title: "a"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid rule, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*ScrapeRule)
+	}{
+		{"Uppercase ID", func(r *ScrapeRule) { r.ID = "News" }},
+		{"ID with path", func(r *ScrapeRule) { r.ID = "../news" }},
+		{"Missing URL scheme", func(r *ScrapeRule) { r.URL = "example.com" }},
+		{"Missing item selector", func(r *ScrapeRule) { r.Item = "" }},
+		{"Missing title selector", func(r *ScrapeRule) { r.Title = "" }},
+		{"Invalid date selector", func(r *ScrapeRule) { r.Date = "time[" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.modify(&rule)
+			if err := rule.Validate(); err == nil {
+				t.Errorf("Expected error for %+v, got none", rule)
+			}
+		})
+	}
+}
+
+// TestRenderAtom tests rendering scraped items as an Atom feed
+func TestRenderAtom(t *testing.T) {
+	rule := ScrapeRule{ID: "news"}
+	fallback := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	feed := &ScrapedFeed{
+		Title: "Example News",
+		Link:  "https://example.com/news/",
+		Items: []ScrapedItem{
+			{Title: "First", Link: "https://example.com/news/first", Published: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)},
+			{Title: "Undated & unlinked"},
+		},
+	}
+
+	body, err := RenderAtom(rule, feed, "https://rssffs.lan/generated/news.xml", fallback)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc atomFeed
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("Expected valid Atom, got %v:\n%s", err, body)
+	}
+	if doc.ID != "https://rssffs.lan/generated/news.xml" || doc.Updated != "2024-06-01T12:00:00Z" {
+		t.Errorf("Expected self URL as ID and newest date as updated, got %q and %q", doc.ID, doc.Updated)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(doc.Entries))
+	}
+	if doc.Entries[0].ID != "https://example.com/news/first" || doc.Entries[0].Updated != "2024-05-02T10:00:00Z" {
+		t.Errorf("Expected first entry identified by link with its own date, got %+v", doc.Entries[0])
+	}
+	if !strings.HasPrefix(doc.Entries[1].ID, "urn:rssffs:news:") || doc.Entries[1].Title != "Undated & unlinked" {
+		t.Errorf("Expected undated entry identified by title hash, got %+v", doc.Entries[1])
+	}
+
+	again, _ := RenderAtom(rule, feed, "https://rssffs.lan/generated/news.xml", fallback)
+	if string(again) != string(body) {
+		t.Error("Expected identical output for unchanged items")
+	}
+}
+
+// TestScrapeRuleStore tests saving, reloading and deleting scraping rules
+func TestScrapeRuleStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+
+	store, err := NewScrapeRuleStore(path)
+	if err != nil {
+		t.Fatalf("Expected missing file to give an empty store, got %v", err)
+	}
+	if err := store.Put(ScrapeRule{ID: "b", URL: "https://b.example.com/", Item: "li", Title: "a"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Put(ScrapeRule{ID: "a", URL: "https://a.example.com/", Item: "li", Title: "a"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Put(ScrapeRule{ID: "Bad", URL: "https://a.example.com/", Item: "li", Title: "a"}); err == nil {
+		t.Error("Expected invalid rule to be rejected, got no error")
+	}
+
+	reloaded, err := NewScrapeRuleStore(path)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading rules: %v", err)
+	}
+	rules := reloaded.List()
+	if len(rules) != 2 || rules[0].ID != "a" || rules[1].ID != "b" {
+		t.Errorf("Expected rules a and b in order, got %+v", rules)
+	}
+
+	if err := reloaded.Delete("a"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := reloaded.Get("a"); ok {
+		t.Error("Expected rule a to be deleted")
+	}
+	if err := reloaded.Delete("a"); err == nil {
+		t.Error("Expected error deleting a missing rule, got none")
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewScrapeRuleStore(path); err == nil {
+		t.Error("Expected error for a corrupt rules file, got none")
+	}
+}
@@ -0,0 +1,99 @@
+package RSSFFS
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// settings are what a run is configured with. Each run builds its own from
+// its config.Config and passes them along, so that runs started at the same
+// time, e.g. by the web server and its discovery queue, don't interfere.
+type settings struct {
+	// reader is the RSS reader subscribed to
+	reader *ReaderClient
+	// createCategories is set to create categories that don't exist yet rather
+	// than fail, when subscribing
+	createCategories bool
+	// podcastCategoryID is the category podcast feeds are routed to, or 0 to
+	// keep them in the category chosen for the run
+	podcastCategoryID int
+	// backupDir is the directory category backups are written to before
+	// clearing a category, if any
+	backupDir string
+	// journalFile is the run journal subscriptions are recorded in, if any
+	journalFile string
+
+	// filter and rules decide which feeds are subscribed to; rules is nil
+	// when there are none
+	filter FeedFilter
+	rules  *RuleSet
+	// minMentions is how many feed items must link to a domain for its feeds
+	// to be looked for when traversing a feed
+	minMentions int
+
+	// forgeFeed selects which repository feed the forge resolver proposes
+	forgeFeed string
+	// hnrssBaseURL is the hnrss-compatible service Hacker News URLs are mapped to
+	hnrssBaseURL string
+	// itunesLookupBaseURL is the iTunes-lookup-compatible service podcast
+	// directory IDs are resolved with
+	itunesLookupBaseURL string
+	// bridges are the configured bridge instances, in order of preference, and
+	// bridgeLookups counts the pages they were asked about, up to maxBridgeLookups
+	bridges       []Bridge
+	bridgeLookups atomic.Int32
+}
+
+// newSettings builds the settings for a run from its configuration
+func newSettings(conf config.Config) (*settings, error) {
+	if err := ValidateForgeFeed(conf.ForgeFeed); err != nil {
+		return nil, err
+	}
+	configuredBridges, err := ParseBridges(conf.Bridges)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := NewFeedFilter(conf.MaxAge, conf.MinItems, conf.Languages)
+	if err != nil {
+		return nil, err
+	}
+	if conf.MinMentions < 0 {
+		return nil, fmt.Errorf("invalid minimum mention count %d", conf.MinMentions)
+	}
+	var rules *RuleSet
+	if conf.RulesFile != "" {
+		if rules, err = LoadRules(conf.RulesFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &settings{
+		reader:              NewReaderClient(conf.RSSReaderEndpoint, conf.RSSReaderAPIKey),
+		createCategories:    conf.CreateCategory,
+		backupDir:           conf.BackupDir,
+		journalFile:         conf.JournalFile,
+		filter:              filter,
+		rules:               rules,
+		minMentions:         conf.MinMentions,
+		forgeFeed:           conf.ForgeFeed,
+		hnrssBaseURL:        conf.HNRSSBaseURL,
+		itunesLookupBaseURL: conf.ITunesLookupBaseURL,
+		bridges:             configuredBridges,
+	}, nil
+}
+
+// resolvePodcastCategory looks up the category podcast feeds are routed to,
+// when one is configured, for runs that subscribe to feeds
+func (s *settings) resolvePodcastCategory(category string) error {
+	if category == "" {
+		return nil
+	}
+	id, err := s.reader.ResolveCategoryID(category, s.createCategories)
+	if err != nil {
+		return fmt.Errorf("error getting categoryId from podcast category %s: %w", category, err)
+	}
+	s.podcastCategoryID = id
+	return nil
+}
@@ -0,0 +1,97 @@
+package RSSFFS
+
+import (
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Subscription states of a candidate, found by comparing it with the feeds
+// already subscribed to in the RSS reader before subscribing
+const (
+	// SubscriptionNew is a feed not yet subscribed to
+	SubscriptionNew = "new"
+	// SubscriptionExisting is a feed already subscribed to at the same URL
+	SubscriptionExisting = "existing"
+	// SubscriptionOtherURL is a feed not yet subscribed to, whose site is
+	// already subscribed to under another feed URL
+	SubscriptionOtherURL = "other_url"
+)
+
+// subscriptions indexes the feeds already subscribed to in the RSS reader by
+// canonical feed URL and site URL
+type subscriptions struct {
+	byURL  map[string]Feed
+	bySite map[string]Feed
+}
+
+// newSubscriptions indexes feeds by their canonical feed and site URLs
+func newSubscriptions(feeds []Feed) *subscriptions {
+	s := &subscriptions{byURL: make(map[string]Feed), bySite: make(map[string]Feed)}
+	for _, feed := range feeds {
+		s.byURL[canonicalURL(feed.FeedURL)] = feed
+		if site := canonicalURL(feed.SiteURL); site != "" {
+			s.bySite[site] = feed
+		}
+	}
+	return s
+}
+
+// loadSubscriptions fetches the feeds subscribed to in the RSS reader once,
+// for a run to compare its candidates with. When they can't be fetched every
+// candidate is treated as new, leaving the RSS reader to refuse duplicates.
+func (s *settings) loadSubscriptions(mode string) *subscriptions {
+	feeds, err := s.reader.Feeds()
+	if err != nil {
+		log.Warnf("%s: Could not fetch subscribed feeds to skip duplicates: %v", mode, err)
+		return newSubscriptions(nil)
+	}
+	return newSubscriptions(feeds)
+}
+
+// match classifies a candidate as new, already subscribed or subscribed under
+// another URL, returning the existing feed it matched. Candidates are matched
+// by feed URL and, once their feed has been read, by the site it links to.
+func (s *subscriptions) match(candidate Candidate) (string, Feed) {
+	if feed, ok := s.byURL[canonicalURL(candidate.URL)]; ok {
+		return SubscriptionExisting, feed
+	}
+	if candidate.Info != nil {
+		if site := canonicalURL(candidate.Info.Link); site != "" {
+			if feed, ok := s.bySite[site]; ok {
+				return SubscriptionOtherURL, feed
+			}
+		}
+	}
+	return SubscriptionNew, Feed{}
+}
+
+// add records a feed subscribed to during the run, so it isn't subscribed to twice
+func (s *subscriptions) add(feedURL string) {
+	s.byURL[canonicalURL(feedURL)] = Feed{FeedURL: feedURL}
+}
+
+// canonicalURL reduces a URL to the parts that identify a feed or site,
+// ignoring the scheme, a leading "www.", default ports, a trailing slash and
+// any fragment, so that e.g. http://www.example.com/feed/ and
+// https://example.com/feed compare equal. Unparseable URLs are only lowercased.
+func canonicalURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(raw)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	canonical := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		canonical += "?" + u.RawQuery
+	}
+	return canonical
+}
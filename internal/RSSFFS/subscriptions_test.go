@@ -0,0 +1,113 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestCanonicalURL tests that URLs differing only in ways that don't identify
+// a feed compare equal
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"http://www.example.com/feed/", "https://example.com/feed", true},
+		{"https://Example.com:443/feed#top", "https://example.com/feed", true},
+		{"https://example.com/", "https://example.com", true},
+		{"https://example.com/feed?format=atom", "https://example.com/feed", false},
+		{"https://example.com:8443/feed", "https://example.com/feed", false},
+		{"https://blog.example.com/feed", "https://example.com/feed", false},
+	}
+	for _, tt := range tests {
+		if got := canonicalURL(tt.a) == canonicalURL(tt.b); got != tt.equal {
+			t.Errorf("canonicalURL(%q) == canonicalURL(%q) is %t, expected %t", tt.a, tt.b, got, tt.equal)
+		}
+	}
+	if canonicalURL("") != "" {
+		t.Error("Expected empty canonical URL for empty input")
+	}
+}
+
+// TestSubscriptionsMatch tests classifying candidates against existing subscriptions
+func TestSubscriptionsMatch(t *testing.T) {
+	existing := newSubscriptions([]Feed{
+		{FeedURL: "https://alice.example.org/feed.xml", SiteURL: "https://alice.example.org/", Category: Category{Title: "Blogs"}},
+	})
+
+	tests := []struct {
+		name      string
+		candidate Candidate
+		expected  string
+	}{
+		{"same feed", Candidate{URL: "http://www.alice.example.org/feed.xml"}, SubscriptionExisting},
+		{"same site", Candidate{URL: "https://alice.example.org/comments.xml", Info: &FeedInfo{Link: "https://alice.example.org"}}, SubscriptionOtherURL},
+		{"other site", Candidate{URL: "https://bob.example.net/feed.xml", Info: &FeedInfo{Link: "https://bob.example.net/"}}, SubscriptionNew},
+		{"unread feed", Candidate{URL: "https://alice.example.org/comments.xml"}, SubscriptionNew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscription, feed := existing.match(tt.candidate)
+			if subscription != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, subscription)
+			}
+			if subscription != SubscriptionNew && feed.Category.Title != "Blogs" {
+				t.Errorf("Expected the existing feed, got %+v", feed)
+			}
+		})
+	}
+}
+
+// TestSubscribeCandidatesSkipsDuplicates tests that feeds already subscribed
+// to are reported as such instead of being subscribed to again, while other
+// feeds of a site already subscribed to are still subscribed to
+func TestSubscribeCandidatesSkipsDuplicates(t *testing.T) {
+	var subscribed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/feeds":
+			_, _ = fmt.Fprint(w, `[{"id": 3, "feed_url": "https://alice.example.org/feed.xml", "site_url": "https://alice.example.org/", "category": {"id": 2, "title": "Blogs"}}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/feeds":
+			var body struct {
+				FeedURL string `json:"feed_url"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			subscribed = append(subscribed, body.FeedURL)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"feed_id": 42}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	s := &settings{reader: NewReaderClient(server.URL, "")}
+
+	report := s.subscribeCandidates([]Candidate{
+		{URL: "https://alice.example.org/feed.xml", Info: &FeedInfo{}},
+		{URL: "https://alice.example.org/comments.xml", Info: &FeedInfo{Link: "https://alice.example.org/"}},
+		{URL: "https://alice.example.org/podcast.xml", Info: &FeedInfo{Link: "https://alice.example.org/"}},
+		{URL: "https://bob.example.net/feed.xml", Info: &FeedInfo{}},
+		{URL: "https://bob.example.net/feed.xml/", Info: &FeedInfo{}},
+	}, "", 1, false, "Test")
+
+	expectedSubscribed := []string{"https://alice.example.org/comments.xml", "https://alice.example.org/podcast.xml", "https://bob.example.net/feed.xml"}
+	if !reflect.DeepEqual(subscribed, expectedSubscribed) {
+		t.Errorf("Expected %v subscribed to, once each, got %v", expectedSubscribed, subscribed)
+	}
+	expected := []string{SubscriptionExisting, SubscriptionOtherURL, SubscriptionOtherURL, SubscriptionNew, SubscriptionExisting}
+	for i, result := range report.Results {
+		if result.Subscription != expected[i] || result.Error != "" {
+			t.Errorf("Expected result %d to be %q without error, got %+v", i, expected[i], result)
+		}
+	}
+	if report.Results[0].Category != "Blogs" || report.Results[1].SubscribedAs != "https://alice.example.org/feed.xml" || report.Results[2].SubscribedAs != "https://alice.example.org/feed.xml" {
+		t.Errorf("Expected the existing subscription described, got %+v", report.Results[:3])
+	}
+	if report.SubscribedCount() != 3 || report.AlreadySubscribedCount() != 2 {
+		t.Errorf("Expected 3 subscribed and 2 already subscribed, got %d and %d", report.SubscribedCount(), report.AlreadySubscribedCount())
+	}
+}
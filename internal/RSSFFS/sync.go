@@ -0,0 +1,428 @@
+package RSSFFS
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Sync actions. A plan creates categories before adding or moving feeds to
+// them, and removes feeds last.
+const (
+	// SyncCreateCategory creates a declared category missing from the RSS reader
+	SyncCreateCategory = "create category"
+	// SyncAdd subscribes to a declared feed not yet subscribed to
+	SyncAdd = "add"
+	// SyncMove moves a declared feed subscribed to in another category
+	SyncMove = "move"
+	// SyncRemove unsubscribes from a feed in a declared category that is no
+	// longer declared, when pruning
+	SyncRemove = "remove"
+)
+
+// SyncFile declares the categories to keep the RSS reader's subscriptions in
+// line with, read from a feeds file
+type SyncFile struct {
+	Categories []SyncCategory `yaml:"categories"`
+}
+
+// SyncCategory declares the feeds of a category: those found on its Sources
+// pages as RSSFFS would in traversal mode, those found on its Sites in single
+// URL mode, and its Feeds, subscribed to as they are. Sources and Sites may
+// also be fediverse handles.
+type SyncCategory struct {
+	Name    string   `yaml:"name"`
+	Sources []string `yaml:"sources,omitempty"`
+	Sites   []string `yaml:"sites,omitempty"`
+	Feeds   []string `yaml:"feeds,omitempty"`
+}
+
+// SyncChange is a change ApplySync makes to bring the RSS reader in line with
+// a feeds file. URL is the feed's URL and Source how it was declared or
+// found. From is the category a feed is moved or removed from, and FeedID
+// the RSS reader's ID for it. Error records why applying the change failed.
+type SyncChange struct {
+	Action   string `json:"action"`
+	URL      string `json:"url,omitempty"`
+	Category string `json:"category"`
+	From     string `json:"from,omitempty"`
+	FeedID   int    `json:"feed_id,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SyncPlan is what it takes to bring the RSS reader in line with a feeds
+// file. Unchanged counts the declared feeds already subscribed to in their
+// category. Warnings note sources that couldn't be read and feeds left out by
+// rules and the feed filter; a category with a source that couldn't be read
+// or had no feeds is never pruned. Backups lists the OPML files categories
+// were backed up to before pruning, once the plan is applied.
+type SyncPlan struct {
+	File      string       `json:"file"`
+	Changes   []SyncChange `json:"changes"`
+	Unchanged int          `json:"unchanged"`
+	Warnings  []string     `json:"warnings,omitempty"`
+	RunID     string       `json:"run_id,omitempty"`
+	Backups   []string     `json:"backups,omitempty"`
+}
+
+// Count returns the number of changes in the plan making action
+func (p *SyncPlan) Count(action string) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// LoadSyncFile reads and checks a YAML feeds file
+func LoadSyncFile(file string) (*SyncFile, error) {
+	data, err := os.ReadFile(file) // #nosec G304 -- file is given by the user
+	if err != nil {
+		return nil, fmt.Errorf("error reading feeds file %s: %w", file, err)
+	}
+	feeds, err := ParseSyncFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("error in feeds file %s: %w", file, err)
+	}
+	return feeds, nil
+}
+
+// ParseSyncFile parses and checks YAML feeds declarations such as:
+//
+//	categories:
+//	  - name: Link Blogs
+//	    sources:
+//	      - https://example.com/blogroll
+//	    sites:
+//	      - https://alice.example.org
+//	      - "@bob@social.example"
+//	    feeds:
+//	      - https://carol.example.net/feed.xml
+func ParseSyncFile(data []byte) (*SyncFile, error) {
+	feeds := &SyncFile{}
+	if err := yaml.Unmarshal(data, feeds); err != nil {
+		return nil, err
+	}
+	if len(feeds.Categories) == 0 {
+		return nil, errors.New("no categories declared")
+	}
+
+	seen := make(map[string]bool)
+	for i, category := range feeds.Categories {
+		name := strings.TrimSpace(category.Name)
+		if name == "" {
+			return nil, fmt.Errorf("category %d: missing name", i+1)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("category %s is declared twice", name)
+		}
+		seen[strings.ToLower(name)] = true
+		feeds.Categories[i].Name = name
+
+		// Pages may be fediverse handles, but feeds must be URLs
+		for _, page := range append(append([]string{}, category.Sources...), category.Sites...) {
+			if !IsHandle(page) && !isAbsoluteURL(page) {
+				return nil, fmt.Errorf("category %s: invalid URL or handle %q", name, page)
+			}
+		}
+		for _, feed := range category.Feeds {
+			if !isAbsoluteURL(feed) {
+				return nil, fmt.Errorf("category %s: invalid feed URL %q", name, feed)
+			}
+		}
+	}
+	return feeds, nil
+}
+
+// isAbsoluteURL reports whether raw is an absolute URL with a host
+func isAbsoluteURL(raw string) bool {
+	u, err := url.ParseRequestURI(raw)
+	return err == nil && u.Host != ""
+}
+
+// syncFeed is a feed a feeds file declares, found or listed in category
+type syncFeed struct {
+	Candidate
+	category string
+}
+
+// PlanSync discovers the feeds declared in feeds and compares them with the
+// RSS reader's subscriptions, returning the changes that would bring it in
+// line: categories to create, feeds to subscribe to, and feeds subscribed to
+// in another category to move. With prune, feeds in a declared category that
+// are no longer declared are removed too. Discovered feeds go through the
+// run's rules and feed filter, and a rule's category takes precedence over
+// the declared one; feeds listed in the file are taken as they are. Nothing
+// is changed in the RSS reader. file names the feeds file in the plan.
+func PlanSync(feeds *SyncFile, file string, prune bool, conf config.Config) (*SyncPlan, error) {
+	s, err := newSettings(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed, err := s.reader.Feeds()
+	if err != nil {
+		return nil, fmt.Errorf("sync: Error getting subscribed feeds: %w", err)
+	}
+	categories, err := s.reader.Categories()
+	if err != nil {
+		return nil, fmt.Errorf("sync: Error getting categories: %w", err)
+	}
+
+	plan := &SyncPlan{File: file}
+	declared, incomplete := s.discoverSyncFeeds(feeds, plan)
+	planSync(plan, feeds, declared, incomplete, subscribed, categories, prune)
+	return plan, nil
+}
+
+// discoverSyncFeeds returns every feed declared in feeds, found on its
+// sources and sites or listed, once each, along with the categories with a
+// source that couldn't be read or had no feeds. Feeds left out by rules or
+// the feed filter are noted in the plan's warnings.
+func (s *settings) discoverSyncFeeds(feeds *SyncFile, plan *SyncPlan) ([]syncFeed, map[string]bool) {
+	var declared []syncFeed
+	incomplete := make(map[string]bool)
+	seen := make(map[string]bool)
+	now := time.Now()
+	for _, category := range feeds.Categories {
+		found := 0
+		pages := append(append([]string{}, category.Sources...), category.Sites...)
+		for i, page := range pages {
+			candidates, err := s.syncCandidates(page, i >= len(category.Sources))
+			if err == nil && len(candidates) == 0 {
+				// A page that is down often looks like one without feeds
+				err = errors.New("no feeds found")
+			}
+			if err != nil {
+				log.Errorf("Sync: Error discovering feeds on %s: %v", page, err)
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: not pruning the category, since discovering feeds on %s failed: %v", category.Name, page, err))
+				incomplete[strings.ToLower(category.Name)] = true
+				continue
+			}
+			inspectFeeds(candidates)
+			for _, candidate := range candidates {
+				result := s.evaluateCandidate(candidate, page, now)
+				if result.Skipped != "" {
+					log.Infof("Sync: Skipping RSS feed %s: %s", candidate.URL, result.Skipped)
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: skipping %s: %s", category.Name, candidate.URL, result.Skipped))
+					continue
+				}
+				target := category.Name
+				if result.Category != "" {
+					target = result.Category
+				}
+				found++
+				declared = appendSyncFeed(declared, seen, syncFeed{Candidate: candidate, category: target})
+			}
+		}
+		for _, listed := range candidatesFromURLs(category.Feeds, "listed in feeds file") {
+			declared = appendSyncFeed(declared, seen, syncFeed{Candidate: listed, category: category.Name})
+		}
+		log.Infof("Sync: Found %d feeds and listed %d for category %s", found, len(category.Feeds), category.Name)
+	}
+	return declared, incomplete
+}
+
+// appendSyncFeed adds feed to declared unless a feed with the same URL was
+// declared before it, in which case the first declaration wins
+func appendSyncFeed(declared []syncFeed, seen map[string]bool, feed syncFeed) []syncFeed {
+	key := canonicalURL(feed.URL)
+	if seen[key] {
+		log.Debugf("Sync: RSS feed %s is already declared", feed.URL)
+		return declared
+	}
+	seen[key] = true
+	return append(declared, feed)
+}
+
+// syncCandidates discovers the feeds on page in single URL or traversal
+// mode, resolving fediverse handles to their profile's feed
+func (s *settings) syncCandidates(page string, single bool) ([]Candidate, error) {
+	if IsHandle(page) {
+		profileURL, err := ResolveHandle(page)
+		if err != nil {
+			return nil, err
+		}
+		page, single = profileURL, true
+	}
+	if single {
+		return s.singleURLCandidates(page, false)
+	}
+	return s.traversalCandidates(page)
+}
+
+// planSync fills plan with the changes that bring the subscribed feeds in
+// line with the declared ones, pruning declared categories unless they are
+// incomplete. Declared feeds are matched with subscriptions by feed URL only.
+func planSync(plan *SyncPlan, feeds *SyncFile, declared []syncFeed, incomplete map[string]bool, subscribed []Feed, categories []Category, prune bool) {
+	existingCategories := make(map[string]bool)
+	for _, category := range categories {
+		existingCategories[strings.ToLower(category.Title)] = true
+	}
+	createCategory := func(name string) {
+		if !existingCategories[strings.ToLower(name)] {
+			existingCategories[strings.ToLower(name)] = true
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncCreateCategory, Category: name})
+		}
+	}
+	for _, category := range feeds.Categories {
+		createCategory(category.Name)
+	}
+
+	existing := newSubscriptions(subscribed)
+	kept := make(map[int]bool)
+	for _, feed := range declared {
+		subscription, match := existing.match(feed.Candidate)
+		if subscription == SubscriptionOtherURL {
+			// A site can publish several feeds, e.g. its posts, comments and a
+			// podcast, so only a feed's own URL decides which subscription it is
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: %s is from a site already subscribed to as %s in %s", feed.category, feed.URL, match.FeedURL, match.Category.Title))
+			subscription = SubscriptionNew
+		}
+		if subscription == SubscriptionNew {
+			createCategory(feed.category)
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncAdd, URL: feed.URL, Category: feed.category, Source: feed.Source})
+			existing.add(feed.URL)
+			continue
+		}
+		if match.ID == 0 || kept[match.ID] {
+			// Subscribed to during this plan, or already matched by an earlier declaration
+			continue
+		}
+		kept[match.ID] = true
+		if strings.EqualFold(match.Category.Title, feed.category) {
+			plan.Unchanged++
+			continue
+		}
+		createCategory(feed.category)
+		plan.Changes = append(plan.Changes, SyncChange{Action: SyncMove, URL: match.FeedURL, Category: feed.category, From: match.Category.Title, FeedID: match.ID, Source: feed.Source})
+	}
+
+	if !prune {
+		return
+	}
+	for _, category := range feeds.Categories {
+		if incomplete[strings.ToLower(category.Name)] {
+			continue
+		}
+		for _, feed := range subscribed {
+			if kept[feed.ID] || !strings.EqualFold(feed.Category.Title, category.Name) {
+				continue
+			}
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncRemove, URL: feed.FeedURL, Category: category.Name, From: feed.Category.Title, FeedID: feed.ID})
+		}
+	}
+}
+
+// ApplySync makes the changes in plan, recording the feeds it subscribes to
+// in the run journal so the sync can be undone. Before removing any feeds
+// from a category, the whole category is backed up to an OPML file, and
+// nothing is removed from it if that fails. Changes that fail are marked
+// with their error and the rest still made, and an error counting the
+// failures is returned.
+func ApplySync(plan *SyncPlan, conf config.Config) error {
+	s, err := newSettings(conf)
+	if err != nil {
+		return err
+	}
+
+	categoryIds := make(map[string]int)
+	categoryID := func(name string) (int, error) {
+		key := strings.ToLower(name)
+		if id, ok := categoryIds[key]; ok {
+			return id, nil
+		}
+		id, err := s.reader.ResolveCategoryID(name, true)
+		if err == nil {
+			categoryIds[key] = id
+		}
+		return id, err
+	}
+
+	var journal []JournalFeed
+	var failed []error
+	backedUp := make(map[string]error)
+	for i := range plan.Changes {
+		change := &plan.Changes[i]
+		var err error
+		switch change.Action {
+		case SyncCreateCategory:
+			_, err = categoryID(change.Category)
+		case SyncAdd:
+			var id, feedId int
+			if id, err = categoryID(change.Category); err == nil {
+				if feedId, err = s.reader.Subscribe(id, change.URL); err == nil {
+					change.FeedID = feedId
+					journal = append(journal, JournalFeed{ID: feedId, URL: change.URL, CategoryID: id, Category: change.Category})
+				}
+			}
+		case SyncMove:
+			var id int
+			if id, err = categoryID(change.Category); err == nil {
+				err = s.reader.MoveFeed(change.FeedID, id)
+			}
+		case SyncRemove:
+			backupErr, ok := backedUp[strings.ToLower(change.Category)]
+			if !ok {
+				backupErr = s.backupSyncCategory(plan, change.Category, categoryID)
+				backedUp[strings.ToLower(change.Category)] = backupErr
+			}
+			if err = backupErr; err == nil {
+				err = s.reader.DeleteFeed(change.FeedID)
+			}
+		}
+
+		if err != nil {
+			log.Errorf("Sync: Error applying %s %s: %v", change.Action, orCategory(change), err)
+			change.Error = err.Error()
+			failed = append(failed, fmt.Errorf("%s %s: %w", change.Action, orCategory(change), err))
+			continue
+		}
+		log.Infof("Sync: Applied %s %s", change.Action, orCategory(change))
+	}
+
+	plan.RunID = recordRun(s.journalFile, "Sync", plan.File, journal)
+	if len(failed) > 0 {
+		return fmt.Errorf("could not apply %d of %d changes: %w", len(failed), len(plan.Changes), errors.Join(failed...))
+	}
+	return nil
+}
+
+// backupSyncCategory backs up every feed in the category named name before
+// feeds are removed from it, recording the backup in plan
+func (s *settings) backupSyncCategory(plan *SyncPlan, name string, categoryID func(string) (int, error)) error {
+	id, err := categoryID(name)
+	if err != nil {
+		return err
+	}
+	feeds, err := s.reader.CategoryFeeds(id)
+	if err != nil {
+		return fmt.Errorf("not removing feeds from category %s: %w", name, err)
+	}
+	backup, err := backupCategory(s.backupDir, name, feeds)
+	if err != nil {
+		return fmt.Errorf("not removing feeds from category %s: %w", name, err)
+	}
+	log.Infof("Sync: Backed up the %d feeds in category %s to %s", len(feeds), name, backup)
+	plan.Backups = append(plan.Backups, backup)
+	return nil
+}
+
+// orCategory returns the feed URL a change is about, or its category when it
+// is about a category, for log messages
+func orCategory(change *SyncChange) string {
+	if change.URL != "" {
+		return change.URL
+	}
+	return change.Category
+}
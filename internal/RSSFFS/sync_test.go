@@ -0,0 +1,203 @@
+package RSSFFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// TestParseSyncFile tests reading and checking feeds files
+func TestParseSyncFile(t *testing.T) {
+	feeds, err := ParseSyncFile([]byte(`
+categories:
+  - name: " Link Blogs "
+    sources: [https://example.com/blogroll]
+    sites: [https://alice.example.org, "@bob@social.example"]
+    feeds: [https://carol.example.net/feed.xml]
+  - name: News
+    feeds: [https://news.example.com/rss]
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(feeds.Categories) != 2 || feeds.Categories[0].Name != "Link Blogs" || len(feeds.Categories[0].Sites) != 2 {
+		t.Errorf("Unexpected feeds file %+v", feeds)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid YAML", "categories: ["},
+		{"no categories", "categories: []"},
+		{"missing name", "categories: [{feeds: [https://example.com/feed]}]"},
+		{"duplicate category", "categories: [{name: News}, {name: news}]"},
+		{"invalid source", "categories: [{name: News, sources: [example.com]}]"},
+		{"handle as feed", `categories: [{name: News, feeds: ["@bob@social.example"]}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSyncFile([]byte(tt.data)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// TestPlanSync tests computing the changes that bring subscriptions in line
+// with the declared feeds
+func TestPlanSync(t *testing.T) {
+	feeds := &SyncFile{Categories: []SyncCategory{{Name: "Link Blogs"}, {Name: "News"}, {Name: "Podcasts"}}}
+	linkBlogs, news, other := Category{ID: 7, Title: "Link Blogs"}, Category{ID: 8, Title: "News"}, Category{ID: 9, Title: "Other"}
+	subscribed := []Feed{
+		{ID: 1, FeedURL: "https://alice.example.org/feed.xml", Category: linkBlogs},
+		{ID: 2, FeedURL: "https://bob.example.net/rss", Category: other},
+		{ID: 3, FeedURL: "https://old.example.org/feed", Category: linkBlogs},
+		{ID: 4, FeedURL: "https://stale.example.com/rss", Category: news},
+		{ID: 5, FeedURL: "https://elsewhere.example.com/rss", Category: other},
+	}
+	declared := []syncFeed{
+		{Candidate: Candidate{URL: "http://www.alice.example.org/feed.xml"}, category: "link blogs"},
+		{Candidate: Candidate{URL: "https://bob.example.net/rss", Source: "listed in feeds file"}, category: "Link Blogs"},
+		{Candidate: Candidate{URL: "https://carol.example.com/atom.xml"}, category: "Podcasts"},
+		{Candidate: Candidate{URL: "https://dave.example.org/feed"}, category: "Newsletters"},
+	}
+
+	plan := &SyncPlan{}
+	planSync(plan, feeds, declared, map[string]bool{"news": true}, subscribed, []Category{linkBlogs, news, other}, true)
+	expected := []SyncChange{
+		{Action: SyncCreateCategory, Category: "Podcasts"},
+		{Action: SyncMove, URL: "https://bob.example.net/rss", Category: "Link Blogs", From: "Other", FeedID: 2, Source: "listed in feeds file"},
+		{Action: SyncAdd, URL: "https://carol.example.com/atom.xml", Category: "Podcasts"},
+		{Action: SyncCreateCategory, Category: "Newsletters"},
+		{Action: SyncAdd, URL: "https://dave.example.org/feed", Category: "Newsletters"},
+		// News is incomplete, and Other isn't declared, so neither is pruned
+		{Action: SyncRemove, URL: "https://old.example.org/feed", Category: "Link Blogs", From: "Link Blogs", FeedID: 3},
+	}
+	if !reflect.DeepEqual(plan.Changes, expected) {
+		t.Errorf("Expected changes\n%+v\ngot\n%+v", expected, plan.Changes)
+	}
+	if plan.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged feed, got %d", plan.Unchanged)
+	}
+	if plan.Count(SyncAdd) != 2 || plan.Count(SyncRemove) != 1 {
+		t.Errorf("Unexpected counts of changes %d added, %d removed", plan.Count(SyncAdd), plan.Count(SyncRemove))
+	}
+
+	plan = &SyncPlan{}
+	planSync(plan, feeds, declared, nil, subscribed, []Category{linkBlogs, news, other}, false)
+	if plan.Count(SyncRemove) != 0 {
+		t.Errorf("Expected nothing removed without pruning, got %+v", plan.Changes)
+	}
+
+	// Other feeds of a subscribed site are added alongside it, not moved in its place
+	blogs, podcasts := Category{ID: 10, Title: "Blogs"}, Category{ID: 11, Title: "Podcasts"}
+	site := &FeedInfo{Link: "https://a.example/"}
+	plan = &SyncPlan{}
+	planSync(plan, &SyncFile{Categories: []SyncCategory{{Name: "Podcasts"}}}, []syncFeed{
+		{Candidate: Candidate{URL: "https://a.example/podcast.xml", Info: site}, category: "Podcasts"},
+		{Candidate: Candidate{URL: "https://a.example/comments/feed", Info: site}, category: "Podcasts"},
+	}, nil, []Feed{{ID: 1, FeedURL: "https://a.example/feed", SiteURL: "https://a.example/", Category: blogs}}, []Category{blogs, podcasts}, true)
+	expected = []SyncChange{
+		{Action: SyncAdd, URL: "https://a.example/podcast.xml", Category: "Podcasts"},
+		{Action: SyncAdd, URL: "https://a.example/comments/feed", Category: "Podcasts"},
+	}
+	if !reflect.DeepEqual(plan.Changes, expected) {
+		t.Errorf("Expected changes\n%+v\ngot\n%+v", expected, plan.Changes)
+	}
+	if len(plan.Warnings) != 2 || !strings.Contains(plan.Warnings[0], "https://a.example/feed") {
+		t.Errorf("Expected the site's other subscription to be noted, got %v", plan.Warnings)
+	}
+}
+
+// TestSyncFeedsFile tests planning and applying a sync against an RSS reader
+func TestSyncFeedsFile(t *testing.T) {
+	withoutRateLimit(t)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/feeds":
+			_, _ = fmt.Fprint(w, `[{"id": 3, "feed_url": "https://old.example.org/feed", "category": {"id": 7, "title": "Link Blogs"}},
+				{"id": 4, "feed_url": "https://bob.example.net/rss", "category": {"id": 8, "title": "Other"}}]`)
+		case "GET /v1/categories":
+			_, _ = fmt.Fprint(w, `[{"id": 7, "title": "Link Blogs"}, {"id": 8, "title": "Other"}]`)
+		case "GET /v1/categories/7/feeds":
+			_, _ = fmt.Fprint(w, `[{"id": 3, "feed_url": "https://old.example.org/feed"}]`)
+		case "POST /v1/feeds":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprint(w, `{"feed_id": 42}`)
+		case "PUT /v1/feeds/4", "DELETE /v1/feeds/3":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error_message": "not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal.json")
+	conf := config.Config{RSSReaderEndpoint: server.URL, BackupDir: dir, JournalFile: journal}
+	feeds := &SyncFile{Categories: []SyncCategory{{
+		Name:  "Link Blogs",
+		Feeds: []string{"https://carol.example.com/atom.xml", "https://bob.example.net/rss"},
+	}}}
+
+	plan, err := PlanSync(feeds, "feeds.yaml", true, conf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(plan.Changes) != 3 || plan.Count(SyncAdd) != 1 || plan.Count(SyncMove) != 1 || plan.Count(SyncRemove) != 1 {
+		t.Fatalf("Expected a feed added, moved and removed, got %+v", plan.Changes)
+	}
+	for _, request := range requests {
+		if !strings.HasPrefix(request, "GET ") {
+			t.Errorf("Expected planning to change nothing, got %s", request)
+		}
+	}
+
+	if err := ApplySync(plan, conf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	applied := strings.Join(requests, ", ")
+	for _, expected := range []string{"POST /v1/feeds", "PUT /v1/feeds/4", "GET /v1/categories/7/feeds, DELETE /v1/feeds/3"} {
+		if !strings.Contains(applied, expected) {
+			t.Errorf("Expected %s to be requested, got %s", expected, applied)
+		}
+	}
+	if plan.Changes[0].FeedID != 42 {
+		t.Errorf("Expected the added feed's ID to be recorded, got %+v", plan.Changes[0])
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "link-blogs-*.opml")); len(backups) != 1 || !reflect.DeepEqual(plan.Backups, backups) {
+		t.Errorf("Expected the pruned category to be backed up, got %v, %v", plan.Backups, backups)
+	}
+	runs, err := readJournal(journal)
+	if err != nil || len(runs) != 1 || runs[0].ID != plan.RunID || runs[0].Mode != "Sync" || runs[0].Feeds[0].ID != 42 {
+		t.Errorf("Expected the added feed to be recorded for undo, got %+v, %v", runs, err)
+	}
+
+	// Changes that fail are recorded and the rest still made
+	plan = &SyncPlan{Changes: []SyncChange{
+		{Action: SyncMove, URL: "https://missing.example.org/feed", Category: "Link Blogs", FeedID: 99},
+		{Action: SyncMove, URL: "https://bob.example.net/rss", Category: "Link Blogs", FeedID: 4},
+	}}
+	err = ApplySync(plan, conf)
+	if err == nil || !strings.Contains(err.Error(), "could not apply 1 of 2 changes") {
+		t.Errorf("Expected the failed change to be reported, got %v", err)
+	}
+	if plan.Changes[0].Error == "" || plan.Changes[1].Error != "" {
+		t.Errorf("Expected only the first change to fail, got %+v", plan.Changes)
+	}
+	if data, _ := json.Marshal(plan.Changes[0]); !strings.Contains(string(data), `"error":`) {
+		t.Errorf("Expected the error in the plan's JSON, got %s", data)
+	}
+}
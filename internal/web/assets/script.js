@@ -2,6 +2,7 @@
 const form = document.getElementById('rss-form');
 const urlInput = document.getElementById('url');
 const categorySelect = document.getElementById('category');
+const newCategoryInput = document.getElementById('new-category');
 const submitBtn = document.getElementById('submit-btn');
 const urlError = document.getElementById('url-error');
 const categoryError = document.getElementById('category-error');
@@ -40,6 +41,7 @@ function setupFormValidation() {
 function validateURL() {
     const url = urlInput.value.trim();
     const urlPattern = /^https?:\/\/.+\..+/i;
+    const handlePattern = /^@?[\w.\-]+@[\w\-]+(\.[\w\-]+)+$/;
     
     clearError(urlError);
     
@@ -48,8 +50,8 @@ function validateURL() {
         return false;
     }
     
-    if (!urlPattern.test(url)) {
-        showError(urlError, 'Please enter a valid URL (e.g., https://example.com)');
+    if (!urlPattern.test(url) && !handlePattern.test(url)) {
+        showError(urlError, 'Please enter a valid URL (e.g., https://example.com) or handle (e.g., @user@example.social)');
         return false;
     }
     
@@ -64,6 +66,11 @@ function validateURL() {
 // Category validation function
 function validateCategory() {
     clearError(categoryError);
+    const newCategory = newCategoryInput ? newCategoryInput.value.trim() : '';
+    if (newCategory.length > 100) {
+        showError(categoryError, 'New category name must be 100 characters or fewer');
+        return false;
+    }
     // Category is optional and pre-validated from server, so always valid
     return true;
 }
@@ -176,9 +183,11 @@ async function handleFormSubmit(event) {
     
     try {
         const singleUrlModeCheckbox = document.getElementById('single-url-mode');
+        const newCategory = newCategoryInput ? newCategoryInput.value.trim() : '';
         const formData = {
             url: urlInput.value.trim(),
-            category: categorySelect.value.trim(),
+            category: newCategory || categorySelect.value.trim(),
+            create_category: newCategory !== '',
             single_url_mode: singleUrlModeCheckbox ? singleUrlModeCheckbox.checked : false
         };
         
@@ -199,6 +208,9 @@ async function submitForm(formData) {
     body.append('url', formData.url);
     body.append('category', formData.category);
     body.append('single_url_mode', formData.single_url_mode ? 'true' : 'false');
+    if (formData.create_category) {
+        body.append('create_category', 'true');
+    }
 
     const csrfToken = getCookie('csrf_token');
     if (!csrfToken) {
@@ -241,6 +253,7 @@ function handleSubmissionResponse(response) {
             : 'Success! RSS feed processing completed';
         
         showToast(message, 'success');
+        displayFeedResults(response.feeds || []);
         
         // Clear form on success
         form.reset();
@@ -253,6 +266,61 @@ function handleSubmissionResponse(response) {
     }
 }
 
+// Display the feeds handled by a submission along with their metadata
+function displayFeedResults(feeds) {
+    const container = document.getElementById('feed-results');
+    const list = document.getElementById('feed-results-list');
+    if (!container || !list) {
+        return;
+    }
+
+    list.textContent = '';
+    container.style.display = feeds.length > 0 ? 'block' : 'none';
+
+    feeds.forEach(feed => {
+        const info = feed.info || {};
+        const entry = document.createElement('div');
+        const existing = feed.subscription === 'existing' || feed.subscription === 'other_url';
+        entry.className = `log-entry ${feed.subscribed || existing ? 'level-info' : feed.skipped ? 'level-warn' : 'level-error'}`;
+
+        const title = document.createElement('span');
+        title.className = 'log-level';
+        title.textContent = feed.title || info.title || feed.url;
+
+        const details = [feed.url];
+        if (info.language) details.push(info.language);
+        if (info.item_count) details.push(`${info.item_count} items`);
+        if (info.newest) details.push(`last post ${new Date(info.newest).toLocaleDateString()}`);
+        if (info.average_interval) details.push(`every ${formatInterval(info.average_interval)}`);
+        if (info.podcast) details.push('podcast');
+        if (info.full_text) details.push('full text');
+        if (feed.subscription === 'existing') details.push('already subscribed');
+        if (feed.subscription === 'other_url') details.push(`already subscribed as ${feed.subscribed_as}`);
+        if (feed.category) details.push(`in ${feed.category}`);
+        if (feed.rule) details.push(`rule ${feed.rule}`);
+        if (feed.skipped) details.push(`skipped: ${feed.skipped}`);
+        if (feed.error) details.push(`error: ${feed.error}`);
+
+        const message = document.createElement('span');
+        message.className = 'log-message';
+        message.textContent = details.join(' · ');
+
+        entry.appendChild(title);
+        entry.appendChild(message);
+        list.appendChild(entry);
+    });
+}
+
+// Describe a posting interval given in nanoseconds, e.g. "~3 days"
+function formatInterval(nanoseconds) {
+    const hours = nanoseconds / 3.6e12;
+    if (hours < 24) return `~${Math.max(1, Math.round(hours))} hours`;
+    const days = hours / 24;
+    if (days < 14) return `~${Math.round(days)} days`;
+    if (days < 60) return `~${Math.round(days / 7)} weeks`;
+    return `~${Math.round(days / 30)} months`;
+}
+
 // Set form submitting state
 function setSubmittingState(submitting) {
     isSubmitting = submitting;
@@ -599,4 +667,291 @@ showToast = function(message, type = 'info', duration = 5000) {
 // Cleanup on page unload
 window.addEventListener('beforeunload', function() {
     stopLogsPolling();
-});
\ No newline at end of file
+});
+// Generated feeds functionality
+document.addEventListener('DOMContentLoaded', function() {
+    setupGeneratedPanel();
+});
+
+// Setup generated feeds panel functionality
+function setupGeneratedPanel() {
+    const toggleBtn = document.getElementById('toggle-generated');
+    const content = document.getElementById('generated-content');
+    const scrapeForm = document.getElementById('scrape-form');
+    const previewBtn = document.getElementById('scrape-preview');
+
+    if (!toggleBtn || !content || !scrapeForm || !previewBtn) {
+        console.warn('Generated feeds panel elements not found');
+        return;
+    }
+
+    // Toggle panel visibility, loading saved rules when shown
+    toggleBtn.addEventListener('click', function() {
+        const visible = content.style.display !== 'none';
+        content.style.display = visible ? 'none' : 'block';
+        toggleBtn.textContent = visible ? 'Show' : 'Hide';
+        toggleBtn.classList.toggle('active', !visible);
+        if (!visible) {
+            loadScrapeRules();
+        }
+    });
+
+    previewBtn.addEventListener('click', previewScrapeRule);
+    scrapeForm.addEventListener('submit', saveScrapeRule);
+}
+
+// Collect the scraping rule form as URL-encoded parameters
+function scrapeFormBody() {
+    const body = new URLSearchParams();
+    ['id', 'url', 'name', 'item', 'title', 'link', 'date', 'summary'].forEach(field => {
+        body.append(field, document.getElementById(`scrape-${field}`).value.trim());
+    });
+    return body;
+}
+
+// Send a CSRF-protected request for generated feeds and return the parsed response
+async function sendScrapeRequest(path, method, body) {
+    const csrfToken = getCookie('csrf_token');
+    if (!csrfToken) {
+        showToast('Security token missing. Please refresh the page.', 'error');
+        throw new Error('CSRF token not found');
+    }
+
+    const response = await fetch(path, {
+        method: method,
+        body: body,
+        headers: {
+            'Content-Type': 'application/x-www-form-urlencoded',
+            'X-CSRF-Token': csrfToken
+        }
+    });
+    if (response.status === 403) {
+        showToast('Security token invalid. Please refresh the page and try again.', 'error');
+    }
+    return await response.json();
+}
+
+// Preview the items a scraping rule finds without saving it
+async function previewScrapeRule() {
+    try {
+        const data = await sendScrapeRequest('/generated/preview', 'POST', scrapeFormBody());
+        if (!data.success) {
+            showToast(data.message || data.error || 'Could not preview feed', 'error');
+            return;
+        }
+        showToast(data.message, 'info', 3000);
+        displayScrapedItems(data.feed.items || []);
+    } catch (error) {
+        console.error('Error previewing scrape rule:', error);
+        showToast('Network error. Please check your connection and try again.', 'error');
+    }
+}
+
+// Save a scraping rule, optionally subscribing to its feed
+async function saveScrapeRule(event) {
+    event.preventDefault();
+
+    const body = scrapeFormBody();
+    if (document.getElementById('scrape-subscribe').checked) {
+        body.append('subscribe', 'true');
+        body.append('category', categorySelect.value.trim());
+    }
+
+    try {
+        const data = await sendScrapeRequest('/generated/rules', 'POST', body);
+        if (!data.success) {
+            showToast(data.message || data.error || 'Could not save rule', 'error');
+            return;
+        }
+        showToast(data.message, 'success');
+        loadScrapeRules();
+    } catch (error) {
+        console.error('Error saving scrape rule:', error);
+        showToast('Network error. Please check your connection and try again.', 'error');
+    }
+}
+
+// Delete a saved scraping rule
+async function deleteScrapeRule(id) {
+    try {
+        const data = await sendScrapeRequest(`/generated/rules?id=${encodeURIComponent(id)}`, 'DELETE', null);
+        showToast(data.message || data.error, data.success ? 'success' : 'error');
+        loadScrapeRules();
+    } catch (error) {
+        console.error('Error deleting scrape rule:', error);
+        showToast('Network error. Please check your connection and try again.', 'error');
+    }
+}
+
+// Display the items found by a preview
+function displayScrapedItems(items) {
+    const list = document.getElementById('scrape-preview-list');
+    list.textContent = '';
+
+    if (items.length === 0) {
+        const emptyDiv = document.createElement('div');
+        emptyDiv.className = 'logs-empty';
+        emptyDiv.textContent = 'No items found';
+        list.appendChild(emptyDiv);
+        return;
+    }
+
+    items.forEach(item => {
+        const entry = document.createElement('div');
+        entry.className = 'log-entry';
+
+        const date = document.createElement('span');
+        date.className = 'log-timestamp';
+        date.textContent = item.published ? new Date(item.published).toLocaleDateString() : 'no date';
+
+        const title = document.createElement('span');
+        title.className = 'log-message';
+        title.textContent = item.link ? `${item.title} — ${item.link}` : item.title;
+
+        entry.appendChild(date);
+        entry.appendChild(title);
+        list.appendChild(entry);
+    });
+}
+
+// Load and display saved scraping rules with their feed URLs
+async function loadScrapeRules() {
+    const list = document.getElementById('scrape-rules-list');
+
+    try {
+        const response = await fetch('/generated/rules', { method: 'GET' });
+        const data = await response.json();
+        list.textContent = '';
+
+        (data.rules || []).forEach(rule => {
+            const entry = document.createElement('div');
+            entry.className = 'log-entry';
+
+            const id = document.createElement('span');
+            id.className = 'log-level';
+            id.textContent = rule.id;
+
+            const message = document.createElement('span');
+            message.className = 'log-message';
+            const link = document.createElement('a');
+            link.href = rule.feed_url;
+            link.textContent = rule.feed_url;
+            message.appendChild(link);
+
+            const deleteBtn = document.createElement('button');
+            deleteBtn.type = 'button';
+            deleteBtn.className = 'logs-clear-btn';
+            deleteBtn.textContent = 'Delete';
+            deleteBtn.addEventListener('click', () => deleteScrapeRule(rule.id));
+
+            entry.appendChild(id);
+            entry.appendChild(message);
+            entry.appendChild(deleteBtn);
+            list.appendChild(entry);
+        });
+    } catch (error) {
+        console.error('Error loading scrape rules:', error);
+    }
+}
+
+// Review queue functionality
+document.addEventListener('DOMContentLoaded', function() {
+    setupReviewPanel();
+});
+
+// Setup the review panel for feeds discovered from webhook entries
+function setupReviewPanel() {
+    const toggleBtn = document.getElementById('toggle-review');
+    const content = document.getElementById('review-content');
+
+    if (!toggleBtn || !content) {
+        console.warn('Review panel elements not found');
+        return;
+    }
+
+    // Toggle panel visibility, loading the queue when shown
+    toggleBtn.addEventListener('click', function() {
+        const visible = content.style.display !== 'none';
+        content.style.display = visible ? 'none' : 'block';
+        toggleBtn.textContent = visible ? 'Show' : 'Hide';
+        toggleBtn.classList.toggle('active', !visible);
+        if (!visible) {
+            loadReviewQueue();
+        }
+    });
+}
+
+// Load and display the feeds awaiting review
+async function loadReviewQueue() {
+    const list = document.getElementById('review-list');
+
+    try {
+        const response = await fetch('/webhooks/review', { method: 'GET' });
+        const data = await response.json();
+        list.textContent = '';
+
+        const feeds = data.feeds || [];
+        if (feeds.length === 0) {
+            const empty = document.createElement('div');
+            empty.className = 'log-entry';
+            empty.textContent = 'No feeds awaiting review';
+            list.appendChild(empty);
+            return;
+        }
+
+        feeds.forEach(feed => {
+            const entry = document.createElement('div');
+            entry.className = 'log-entry';
+
+            const source = document.createElement('span');
+            source.className = 'log-level';
+            source.textContent = feed.source || '';
+
+            const message = document.createElement('span');
+            message.className = 'log-message';
+            const link = document.createElement('a');
+            link.href = feed.url;
+            link.textContent = feed.title || (feed.info && feed.info.title) || feed.url;
+            message.appendChild(link);
+
+            const subscribeBtn = document.createElement('button');
+            subscribeBtn.type = 'button';
+            subscribeBtn.className = 'logs-toggle-btn';
+            subscribeBtn.textContent = 'Subscribe';
+            subscribeBtn.addEventListener('click', () => reviewFeed(feed.url, 'subscribe'));
+
+            const dismissBtn = document.createElement('button');
+            dismissBtn.type = 'button';
+            dismissBtn.className = 'logs-clear-btn';
+            dismissBtn.textContent = 'Dismiss';
+            dismissBtn.addEventListener('click', () => reviewFeed(feed.url, 'dismiss'));
+
+            entry.appendChild(source);
+            entry.appendChild(message);
+            entry.appendChild(subscribeBtn);
+            entry.appendChild(dismissBtn);
+            list.appendChild(entry);
+        });
+    } catch (error) {
+        console.error('Error loading review queue:', error);
+    }
+}
+
+// Subscribe to or dismiss a feed awaiting review
+async function reviewFeed(url, action) {
+    const body = new URLSearchParams();
+    body.append('url', url);
+    body.append('action', action);
+    if (action === 'subscribe') {
+        body.append('category', categorySelect.value.trim());
+    }
+
+    try {
+        const data = await sendScrapeRequest('/webhooks/review', 'POST', body);
+        showToast(data.message || data.error, data.success ? 'success' : 'error');
+        loadReviewQueue();
+    } catch (error) {
+        console.error('Error reviewing feed:', error);
+        showToast('Network error. Please check your connection and try again.', 'error');
+    }
+}
@@ -0,0 +1,370 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+)
+
+// ScrapeResponse represents the JSON response for scraping rule previews and changes
+type ScrapeResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message,omitempty"`
+	Error   string               `json:"error,omitempty"`
+	Feed    *RSSFFS.ScrapedFeed  `json:"feed,omitempty"`
+	FeedURL string               `json:"feed_url,omitempty"`
+	Rules   []ScrapeRuleListItem `json:"rules,omitempty"`
+}
+
+// ScrapeRuleListItem is a saved scraping rule along with the URL its feed is served at
+type ScrapeRuleListItem struct {
+	RSSFFS.ScrapeRule
+	FeedURL string `json:"feed_url"`
+}
+
+// generatedFeed is a rendered feed along with what's needed to revalidate it
+type generatedFeed struct {
+	body    []byte
+	etag    string
+	rule    RSSFFS.ScrapeRule   // the rule the feed was scraped with
+	feed    *RSSFFS.ScrapedFeed // the scraped content, to render it again at another URL
+	selfURL string              // the URL the feed was rendered to be served at
+	hash    string              // hash of the scraped content, to detect changes
+	changed time.Time           // when the scraped content last changed
+	fetched time.Time           // when the page was last scraped
+}
+
+// generatedFeedCache keeps rendered feeds for ttl so frequent polling by the
+// RSS reader doesn't scrape the source page each time. Each rule's page is
+// scraped by one request at a time, without holding up requests for other
+// rules' feeds.
+type generatedFeedCache struct {
+	mu    sync.Mutex // guards feeds and locks
+	ttl   time.Duration
+	feeds map[string]*generatedFeed
+	locks map[string]*sync.Mutex
+}
+
+// newGeneratedFeedCache creates an empty cache keeping feeds for ttl
+func newGeneratedFeedCache(ttl time.Duration) *generatedFeedCache {
+	return &generatedFeedCache{ttl: ttl, feeds: make(map[string]*generatedFeed), locks: make(map[string]*sync.Mutex)}
+}
+
+// ruleLock returns the lock serialising scrapes for the rule with id
+func (c *generatedFeedCache) ruleLock(id string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lock, ok := c.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.locks[id] = lock
+	}
+	return lock
+}
+
+// cached returns the cached feed for the rule with id, if any
+func (c *generatedFeedCache) cached(id string) *generatedFeed {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.feeds[id]
+}
+
+// store caches feed for the rule with id
+func (c *generatedFeedCache) store(id string, feed *generatedFeed) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.feeds[id] = feed
+}
+
+// get returns the rendered feed for rule served at selfURL, scraping the
+// page again once the cached copy is older than the TTL or was scraped with
+// another version of the rule. When the scraped content hasn't changed the
+// previous rendering and ETag are kept, and when scraping fails a stale copy
+// is served if there is one. selfURL isn't part of the content, so a request
+// under another host renders the same content again at its URL without
+// stamping undated items as changed.
+func (c *generatedFeedCache) get(rule RSSFFS.ScrapeRule, selfURL string, scrape func(RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error)) (*generatedFeed, error) {
+	lock := c.ruleLock(rule.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	now := time.Now()
+	cached := c.cached(rule.ID)
+	if cached != nil && cached.rule != rule {
+		cached = nil
+	}
+	if cached != nil && now.Sub(cached.fetched) < c.ttl {
+		if cached.selfURL == selfURL {
+			return cached, nil
+		}
+		return c.render(rule, cached.feed, selfURL, cached.hash, cached.changed, cached.fetched)
+	}
+
+	feed, err := scrape(rule)
+	if err != nil {
+		if cached != nil {
+			log.Warnf("Serving stale generated feed %s: %v", rule.ID, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	content, err := json.Marshal(struct {
+		Rule RSSFFS.ScrapeRule
+		Feed *RSSFFS.ScrapedFeed
+	}{rule, feed})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	changed := now
+	if cached != nil && cached.hash == hash {
+		changed = cached.changed
+		if cached.selfURL == selfURL {
+			unchanged := *cached
+			unchanged.fetched = now
+			c.store(rule.ID, &unchanged)
+			return &unchanged, nil
+		}
+	}
+	return c.render(rule, feed, selfURL, hash, changed, now)
+}
+
+// render renders feed as scraped with rule at fetched to be served at
+// selfURL, stamping undated items with changed, and caches the rendering
+func (c *generatedFeedCache) render(rule RSSFFS.ScrapeRule, feed *RSSFFS.ScrapedFeed, selfURL string, hash string, changed time.Time, fetched time.Time) (*generatedFeed, error) {
+	body, err := RSSFFS.RenderAtom(rule, feed, selfURL, changed)
+	if err != nil {
+		return nil, err
+	}
+	bodySum := sha256.Sum256(body)
+	generated := &generatedFeed{
+		body:    body,
+		etag:    `"` + hex.EncodeToString(bodySum[:16]) + `"`,
+		rule:    rule,
+		feed:    feed,
+		selfURL: selfURL,
+		hash:    hash,
+		changed: changed,
+		fetched: fetched,
+	}
+	c.store(rule.ID, generated)
+	return generated, nil
+}
+
+// invalidate drops the cached feed for a rule after it changes
+func (c *generatedFeedCache) invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.feeds, id)
+}
+
+// handleGenerated serves feeds generated from scraping rules at /generated/<id>.xml
+func (s *Server) handleGenerated(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/generated/")
+	id, ok := strings.CutSuffix(name, ".xml")
+	if !ok || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	rule, ok := s.scrapeRules.Get(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	feed, err := s.generatedFeeds.get(rule, s.generatedFeedURL(r, id), s.scrape)
+	if err != nil {
+		log.Errorf("Error generating feed %s: %v", id, err)
+		http.Error(w, "Could not generate feed", http.StatusBadGateway)
+		return
+	}
+
+	// Let the reader cache the feed for as long as it is cached here
+	w.Header().Del("Pragma")
+	w.Header().Del("Expires")
+	w.Header().Set("Cache-Control", "public, max-age="+formatSeconds(s.generatedFeeds.ttl))
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("ETag", feed.etag)
+	http.ServeContent(w, r, name, feed.changed, bytes.NewReader(feed.body))
+}
+
+// handleScrapePreview applies a scraping rule without saving it and returns the items found
+func (s *Server) handleScrapePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	rule, ok := s.parseScrapeRule(w, r)
+	if !ok {
+		return
+	}
+
+	feed, err := s.scrape(rule)
+	if err != nil {
+		log.Warnf("Error previewing scrape rule for %s: %v", rule.URL, err)
+		s.sendScrapeResponse(w, http.StatusBadGateway, ScrapeResponse{Error: "Scrape Error", Message: err.Error()})
+		return
+	}
+	s.sendScrapeResponse(w, http.StatusOK, ScrapeResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d items found", len(feed.Items)),
+		Feed:    feed,
+	})
+}
+
+// handleScrapeRules lists (GET), saves (POST) and deletes (DELETE ?id=) scraping rules.
+// Saving with subscribe=true also subscribes the RSS reader to the generated feed.
+func (s *Server) handleScrapeRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		var items []ScrapeRuleListItem
+		for _, rule := range s.scrapeRules.List() {
+			items = append(items, ScrapeRuleListItem{ScrapeRule: rule, FeedURL: s.generatedFeedURL(r, rule.ID)})
+		}
+		s.sendScrapeResponse(w, http.StatusOK, ScrapeResponse{Success: true, Rules: items})
+
+	case http.MethodPost:
+		rule, ok := s.parseScrapeRule(w, r)
+		if !ok {
+			return
+		}
+		category := s.sanitizeInput(r.FormValue("category"))
+		if err := s.validateCategory(category); err != nil {
+			s.sendValidationErrorResponse(w, ValidationErrors{Errors: []ValidationError{{Field: "category", Message: err.Error()}}})
+			return
+		}
+		if err := s.scrapeRules.Put(rule); err != nil {
+			log.Errorf("Error saving scrape rule %s: %v", rule.ID, err)
+			s.sendScrapeResponse(w, http.StatusInternalServerError, ScrapeResponse{Error: "Save Error", Message: err.Error()})
+			return
+		}
+		s.generatedFeeds.invalidate(rule.ID)
+
+		feedURL := s.generatedFeedURL(r, rule.ID)
+		message := "Saved rule " + rule.ID
+		if r.FormValue("subscribe") == "true" {
+			if err := s.subscribeFeed(feedURL, category); err != nil {
+				log.Errorf("Error subscribing to generated feed %s: %v", feedURL, err)
+				s.sendScrapeResponse(w, http.StatusBadGateway, ScrapeResponse{Error: "Subscription Error", Message: "Saved rule " + rule.ID + " but could not subscribe: " + err.Error(), FeedURL: feedURL})
+				return
+			}
+			message += " and subscribed to its feed"
+		}
+		s.sendScrapeResponse(w, http.StatusOK, ScrapeResponse{Success: true, Message: message, FeedURL: feedURL})
+
+	case http.MethodDelete:
+		if !s.checkCSRF(w, r) {
+			return
+		}
+		id := r.URL.Query().Get("id")
+		if err := s.scrapeRules.Delete(id); err != nil {
+			s.sendScrapeResponse(w, http.StatusNotFound, ScrapeResponse{Error: "Delete Error", Message: err.Error()})
+			return
+		}
+		s.generatedFeeds.invalidate(id)
+		s.sendScrapeResponse(w, http.StatusOK, ScrapeResponse{Success: true, Message: "Deleted rule " + id})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// parseScrapeRule reads and validates a scraping rule from a CSRF-protected form
+// submission, writing an error response and returning false when it is invalid
+func (s *Server) parseScrapeRule(w http.ResponseWriter, r *http.Request) (RSSFFS.ScrapeRule, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1024*1024) // 1MB limit
+	if err := r.ParseForm(); err != nil {
+		s.sendErrorResponse(w, "Invalid form data", "Request too large or malformed", http.StatusBadRequest)
+		return RSSFFS.ScrapeRule{}, false
+	}
+	if !s.checkCSRF(w, r) {
+		return RSSFFS.ScrapeRule{}, false
+	}
+
+	// Selectors are used as given rather than HTML-escaped, since escaping would
+	// change their meaning; they are never rendered as HTML
+	rule := RSSFFS.ScrapeRule{
+		ID:      strings.ToLower(strings.TrimSpace(r.FormValue("id"))),
+		URL:     strings.TrimSpace(r.FormValue("url")),
+		Name:    s.sanitizeInput(r.FormValue("name")),
+		Item:    strings.TrimSpace(r.FormValue("item")),
+		Title:   strings.TrimSpace(r.FormValue("title")),
+		Link:    strings.TrimSpace(r.FormValue("link")),
+		Date:    strings.TrimSpace(r.FormValue("date")),
+		Summary: strings.TrimSpace(r.FormValue("summary")),
+	}
+
+	var errors []ValidationError
+	if err := s.validateURL(rule.URL); err != nil || RSSFFS.IsHandle(rule.URL) {
+		message := "a page URL is required"
+		if err != nil {
+			message = err.Error()
+		}
+		errors = append(errors, ValidationError{Field: "url", Message: message})
+	} else if err := rule.Validate(); err != nil {
+		errors = append(errors, ValidationError{Field: "rule", Message: err.Error()})
+	}
+	if len(errors) > 0 {
+		s.sendValidationErrorResponse(w, ValidationErrors{Errors: errors})
+		return RSSFFS.ScrapeRule{}, false
+	}
+	return rule, true
+}
+
+// subscribeFeed subscribes the RSS reader to a feed, such as a generated one
+func (s *Server) subscribeFeed(feedURL string, category string) error {
+	if strings.Contains(s.config.RSSReaderEndpoint, "test.example.com") {
+		log.Infof("Test mode: pretending to subscribe to feed %s", feedURL)
+		return nil
+	}
+	return RSSFFS.SubscribeFeed(feedURL, category, s.config)
+}
+
+// generatedFeedURL returns the absolute URL a generated feed is served at. The
+// configured public URL is used when set, since the RSS reader may reach this
+// server by a different address than the browser.
+func (s *Server) generatedFeedURL(r *http.Request, id string) string {
+	base := strings.TrimSuffix(s.config.PublicURL, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/generated/" + id + ".xml"
+}
+
+// sendScrapeResponse writes a scraping JSON response with the given status code
+func (s *Server) sendScrapeResponse(w http.ResponseWriter, statusCode int, response ScrapeResponse) {
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Errorf("Error encoding scrape response: %v", err)
+	}
+}
+
+// formatSeconds formats a duration as a whole number of seconds for Cache-Control
+func formatSeconds(d time.Duration) string {
+	return strconv.Itoa(int(d / time.Second))
+}
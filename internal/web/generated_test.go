@@ -0,0 +1,277 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// newGeneratedTestServer creates a server whose scraping returns items from the given function
+func newGeneratedTestServer(t *testing.T, ttl time.Duration, scrape func(RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error)) *Server {
+	t.Helper()
+	server := NewServer(config.Config{
+		RSSReaderEndpoint: "https://test.example.com",
+		RSSReaderAPIKey:   "test-key",
+		ScrapeCacheTTL:    ttl,
+		PublicURL:         "https://rssffs.example.com/",
+	}, false)
+	server.scrape = scrape
+	return server
+}
+
+// TestHandleGenerated tests serving, caching and revalidating generated feeds
+func TestHandleGenerated(t *testing.T) {
+	scrapes := 0
+	title := "First post"
+	server := newGeneratedTestServer(t, 0, func(rule RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error) {
+		scrapes++
+		if scrapes == 4 {
+			return nil, errors.New("page unavailable")
+		}
+		return &RSSFFS.ScrapedFeed{Title: "Example", Link: rule.URL, Items: []RSSFFS.ScrapedItem{{Title: title}}}, nil
+	})
+	if err := server.scrapeRules.Put(RSSFFS.ScrapeRule{ID: "news", URL: "https://example.com/news", Item: "li", Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	mux := server.SetupRoutes()
+
+	get := func(path string, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/generated/news.xml", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/atom+xml; charset=utf-8" {
+		t.Errorf("Expected Atom content type, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "https://rssffs.example.com/generated/news.xml") {
+		t.Errorf("Expected self link using the public URL, got:\n%s", w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag header")
+	}
+
+	// Unchanged content keeps its ETag, so the reader gets a 304
+	w = get("/generated/news.xml", etag)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304 for unchanged feed, got %d", w.Code)
+	}
+
+	// Changed content gets a new ETag
+	title = "Second post"
+	w = get("/generated/news.xml", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("Expected new content with a new ETag, got %d with %q", w.Code, w.Header().Get("ETag"))
+	}
+	etag = w.Header().Get("ETag")
+
+	// Scrape failures fall back to the last good feed
+	w = get("/generated/news.xml", "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != etag {
+		t.Errorf("Expected stale feed when scraping fails, got %d with %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	for _, path := range []string{"/generated/missing.xml", "/generated/news", "/generated/news.xml/extra"} {
+		if w := get(path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %s, got %d", path, w.Code)
+		}
+	}
+}
+
+// TestGeneratedFeedCacheTTL tests that feeds aren't scraped again within the TTL
+func TestGeneratedFeedCacheTTL(t *testing.T) {
+	scrapes := 0
+	cache := newGeneratedFeedCache(time.Hour)
+	scrape := func(rule RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error) {
+		scrapes++
+		return &RSSFFS.ScrapedFeed{Title: "Example"}, nil
+	}
+	rule := RSSFFS.ScrapeRule{ID: "news"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.get(rule, "https://rssffs.example.com/generated/news.xml", scrape); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if scrapes != 1 {
+		t.Errorf("Expected 1 scrape within the TTL, got %d", scrapes)
+	}
+
+	cache.invalidate("news")
+	if _, err := cache.get(rule, "https://rssffs.example.com/generated/news.xml", scrape); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if scrapes != 2 {
+		t.Errorf("Expected a new scrape after invalidation, got %d scrapes", scrapes)
+	}
+	if got := formatSeconds(cache.ttl); got != "3600" {
+		t.Errorf("Expected max-age 3600, got %q", got)
+	}
+}
+
+// TestGeneratedFeedCacheSelfURL tests that serving a feed under another URL
+// neither scrapes the page again nor marks its content as changed
+func TestGeneratedFeedCacheSelfURL(t *testing.T) {
+	scrapes := 0
+	cache := newGeneratedFeedCache(time.Nanosecond)
+	scrape := func(rule RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error) {
+		scrapes++
+		return &RSSFFS.ScrapedFeed{Title: "Example", Items: []RSSFFS.ScrapedItem{{Title: "Undated"}}}, nil
+	}
+	rule := RSSFFS.ScrapeRule{ID: "news"}
+
+	first, err := cache.get(rule, "https://rssffs.example.com/generated/news.xml", scrape)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	other, err := cache.get(rule, "http://10.0.0.5:8080/generated/news.xml", scrape)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !other.changed.Equal(first.changed) {
+		t.Errorf("Expected the content to keep its change time %v under another URL, got %v", first.changed, other.changed)
+	}
+	if !strings.Contains(string(other.body), "http://10.0.0.5:8080/generated/news.xml") {
+		t.Errorf("Expected the feed rendered at the other URL, got:\n%s", other.body)
+	}
+
+	cache.ttl = time.Hour
+	if _, err := cache.get(rule, "https://rssffs.example.com/generated/news.xml", scrape); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if scrapes != 2 {
+		t.Errorf("Expected rendering at another URL within the TTL not to scrape, got %d scrapes", scrapes)
+	}
+
+	changedRule := RSSFFS.ScrapeRule{ID: "news", Name: "Renamed"}
+	if _, err := cache.get(changedRule, "https://rssffs.example.com/generated/news.xml", scrape); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if scrapes != 3 {
+		t.Errorf("Expected a changed rule to be scraped again within the TTL, got %d scrapes", scrapes)
+	}
+}
+
+// TestGeneratedFeedCacheConcurrency tests that a slow page only holds up
+// requests for its own feed
+func TestGeneratedFeedCacheConcurrency(t *testing.T) {
+	cache := newGeneratedFeedCache(time.Hour)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	scrape := func(rule RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error) {
+		if rule.ID == "slow" {
+			close(started)
+			<-release
+		}
+		return &RSSFFS.ScrapedFeed{Title: rule.ID}, nil
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := cache.get(RSSFFS.ScrapeRule{ID: "slow"}, "https://rssffs.example.com/generated/slow.xml", scrape)
+		done <- err
+	}()
+	<-started
+
+	fast := make(chan error)
+	go func() {
+		_, err := cache.get(RSSFFS.ScrapeRule{ID: "fast"}, "https://rssffs.example.com/generated/fast.xml", scrape)
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected a feed to be served while another page is being scraped")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestScrapePreviewAndRules tests previewing, saving, listing and deleting scraping rules
+func TestScrapePreviewAndRules(t *testing.T) {
+	server := newGeneratedTestServer(t, time.Minute, func(rule RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error) {
+		return &RSSFFS.ScrapedFeed{Title: "Example", Items: []RSSFFS.ScrapedItem{{Title: "One"}, {Title: "Two"}}}, nil
+	})
+	mux := server.SetupRoutes()
+
+	form := url.Values{
+		"id":    {"news"},
+		"url":   {"https://example.com/news"},
+		"item":  {"article > h2"},
+		"title": {"h2"},
+	}
+	send := func(method string, path string, values url.Values, token string) (*httptest.ResponseRecorder, ScrapeResponse) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, newCSRFRequest(method, path, values.Encode(), token))
+		var response ScrapeResponse
+		_ = json.NewDecoder(w.Body).Decode(&response)
+		return w, response
+	}
+
+	if w, _ := send(http.MethodPost, "/generated/preview", form, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without CSRF token, got %d", w.Code)
+	}
+
+	w, response := send(http.MethodPost, "/generated/preview", form, "token")
+	if w.Code != http.StatusOK || response.Feed == nil || len(response.Feed.Items) != 2 {
+		t.Fatalf("Expected preview with 2 items, got %d: %+v", w.Code, response)
+	}
+	if _, ok := server.scrapeRules.Get("news"); ok {
+		t.Error("Expected preview not to save the rule")
+	}
+
+	invalid := url.Values{"id": {"news"}, "url": {"https://example.com/news"}, "item": {"li["}, "title": {"h2"}}
+	if w, _ := send(http.MethodPost, "/generated/preview", invalid, "token"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid selector, got %d", w.Code)
+	}
+	local := url.Values{"id": {"news"}, "url": {"http://localhost/news"}, "item": {"li"}, "title": {"h2"}}
+	if w, _ := send(http.MethodPost, "/generated/preview", local, "token"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for local URL, got %d", w.Code)
+	}
+
+	form.Set("subscribe", "true")
+	form.Set("category", "News")
+	w, response = send(http.MethodPost, "/generated/rules", form, "token")
+	if w.Code != http.StatusOK || response.FeedURL != "https://rssffs.example.com/generated/news.xml" {
+		t.Fatalf("Expected rule saved with its feed URL, got %d: %+v", w.Code, response)
+	}
+	if rule, ok := server.scrapeRules.Get("news"); !ok || rule.Item != "article > h2" {
+		t.Errorf("Expected rule saved with selectors unescaped, got %+v", rule)
+	}
+
+	w, response = send(http.MethodGet, "/generated/rules", nil, "")
+	if w.Code != http.StatusOK || len(response.Rules) != 1 || response.Rules[0].ID != "news" {
+		t.Errorf("Expected saved rule listed, got %d: %+v", w.Code, response)
+	}
+
+	if w, _ := send(http.MethodDelete, "/generated/rules?id=news", nil, "token"); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 deleting rule, got %d", w.Code)
+	}
+	if w, _ := send(http.MethodDelete, "/generated/rules?id=news", nil, "token"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting missing rule, got %d", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"net/http"
@@ -20,14 +21,17 @@ type SubmitRequest struct {
 	URL           string `json:"url"`
 	Category      string `json:"category"`
 	SingleURLMode bool   `json:"single_url_mode"`
+	// CreateCategory creates Category when it doesn't exist yet
+	CreateCategory bool `json:"create_category"`
 }
 
 // SubmitResponse represents the JSON response sent back to the client
 type SubmitResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Count   int    `json:"count,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Count   int             `json:"count,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Feeds   []RSSFFS.Result `json:"feeds,omitempty"`
 }
 
 // CategoryResponse represents the JSON response for category list
@@ -78,18 +82,7 @@ func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate CSRF token using double submit cookie method
-	csrfCookie, err := r.Cookie("csrf_token")
-	if err != nil {
-		log.Warnf("CSRF cookie not found: %v", err)
-		s.sendErrorResponse(w, "Invalid security token", "Please refresh the page and try again", http.StatusForbidden)
-		return
-	}
-
-	csrfHeader := r.Header.Get("X-CSRF-Token")
-	if csrfHeader == "" || csrfHeader != csrfCookie.Value {
-		log.Warnf("Invalid CSRF token from IP: %s", getClientIP(r))
-		s.sendErrorResponse(w, "Invalid security token", "Please refresh the page and try again", http.StatusForbidden)
+	if !s.checkCSRF(w, r) {
 		return
 	}
 
@@ -99,9 +92,10 @@ func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
 	rawSingleURLMode := r.FormValue("single_url_mode")
 
 	req := SubmitRequest{
-		URL:           s.sanitizeInput(strings.TrimSpace(rawURL)),
-		Category:      s.sanitizeInput(strings.TrimSpace(rawCategory)),
-		SingleURLMode: rawSingleURLMode == "true",
+		URL:            s.sanitizeInput(strings.TrimSpace(rawURL)),
+		Category:       s.sanitizeInput(strings.TrimSpace(rawCategory)),
+		SingleURLMode:  rawSingleURLMode == "true",
+		CreateCategory: r.FormValue("create_category") == "true",
 	}
 
 	// Validate input
@@ -120,6 +114,25 @@ func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// checkCSRF validates the CSRF token using the double submit cookie method,
+// sending an error response and returning false when it doesn't match
+func (s *Server) checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	csrfCookie, err := r.Cookie("csrf_token")
+	if err != nil {
+		log.Warnf("CSRF cookie not found: %v", err)
+		s.sendErrorResponse(w, "Invalid security token", "Please refresh the page and try again", http.StatusForbidden)
+		return false
+	}
+
+	csrfHeader := r.Header.Get("X-CSRF-Token")
+	if csrfHeader == "" || csrfHeader != csrfCookie.Value {
+		log.Warnf("Invalid CSRF token from IP: %s", getClientIP(r))
+		s.sendErrorResponse(w, "Invalid security token", "Please refresh the page and try again", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 // sanitizeInput sanitizes user input to prevent XSS attacks
 func (s *Server) sanitizeInput(input string) string {
 	// HTML escape the input
@@ -178,6 +191,11 @@ func (s *Server) validateURL(urlStr string) error {
 		return fmt.Errorf("URL contains invalid characters")
 	}
 
+	// Fediverse handles (@user@host) are resolved via WebFinger by RSSFFS core
+	if RSSFFS.IsHandle(urlStr) {
+		return nil
+	}
+
 	// Parse and validate URL format
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -274,9 +292,28 @@ func (s *Server) processSubmission(req SubmitRequest) SubmitResponse {
 	}
 
 	// Call the RSSFFS core function
-	count, err := RSSFFS.Run(req.URL, req.Category, s.debug, false, req.SingleURLMode, s.config)
+	conf := s.config
+	conf.CreateCategory = conf.CreateCategory || req.CreateCategory
+	report, err := RSSFFS.RunReport(req.URL, req.Category, s.debug, RSSFFS.ClearNone, req.SingleURLMode, conf)
 	if err != nil {
 		log.Errorf("Error processing RSSFFS request: %v", err)
+		var notFound *RSSFFS.CategoryNotFoundError
+		if errors.As(err, &notFound) {
+			return SubmitResponse{
+				Success: false,
+				Error:   "Category Not Found",
+				Message: notFound.Error() + ". Enter it as a new category to create it.",
+			}
+		}
+		// The RSS reader's own messages, such as "This feed already exists", are safe to show
+		var readerErr *RSSFFS.ReaderError
+		if errors.As(err, &readerErr) {
+			return SubmitResponse{
+				Success: false,
+				Error:   "RSS Reader Error",
+				Message: readerErr.Error(),
+			}
+		}
 		return SubmitResponse{
 			Success: false,
 			Error:   "Processing Error",
@@ -285,17 +322,22 @@ func (s *Server) processSubmission(req SubmitRequest) SubmitResponse {
 	}
 
 	// Formulate a success message
+	count := report.SubscribedCount()
 	var successMessage string
 	if count > 0 {
 		successMessage = fmt.Sprintf("Successfully found and subscribed to %d feed(s).", count)
 	} else {
 		successMessage = "Processing complete. No new RSS feeds were subscribed."
 	}
+	if existing := report.AlreadySubscribedCount(); existing > 0 {
+		successMessage += fmt.Sprintf(" %d feed(s) were already subscribed.", existing)
+	}
 
 	return SubmitResponse{
 		Success: true,
 		Message: successMessage,
 		Count:   count,
+		Feeds:   report.Results,
 	}
 }
 
@@ -321,6 +363,10 @@ func (s *Server) processTestSubmission(req SubmitRequest) SubmitResponse {
 			Success: true,
 			Message: modePrefix + "successfully found and subscribed to 2 RSS feeds",
 			Count:   2,
+			Feeds: []RSSFFS.Result{
+				{Candidate: RSSFFS.Candidate{URL: "https://test-success.example.com/feed.xml", Info: &RSSFFS.FeedInfo{Title: "Test Blog", Language: "en", ItemCount: 10, AverageInterval: 7 * 24 * time.Hour}}, Subscribed: true},
+				{Candidate: RSSFFS.Candidate{URL: "https://test-success.example.com/podcast.xml", Info: &RSSFFS.FeedInfo{Title: "Test Podcast", ItemCount: 5, Podcast: true}}, Subscribed: true},
+			},
 		}
 	case "https://test-no-feeds.example.com":
 		var message string
@@ -430,34 +476,10 @@ func (s *Server) handleCategories(w http.ResponseWriter, r *http.Request) {
 
 // fetchCategoriesFromAPI fetches categories from the RSS reader API
 func (s *Server) fetchCategoriesFromAPI() ([]CategoryResponseItem, error) {
-	// Create HTTP request to fetch categories
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v1/categories", s.config.RSSReaderEndpoint), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("X-Auth-Token", s.config.RSSReaderAPIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req) // #nosec G704 -- URL is from config, not user input
+	apiCategories, err := RSSFFS.NewReaderClient(s.config.RSSReaderEndpoint, s.config.RSSReaderAPIKey).Categories()
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch categories: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status code: %d", resp.StatusCode)
-	}
-
-	// Parse the JSON response using the existing Category struct from RSSFFS package
-	var apiCategories []RSSFFS.Category
-	if err := json.NewDecoder(resp.Body).Decode(&apiCategories); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
 
 	// Convert to response format
 	categories := make([]CategoryResponseItem, len(apiCategories))
@@ -550,5 +572,11 @@ func (s *Server) getStatusCode(response SubmitResponse) int {
 	if response.Success {
 		return http.StatusOK
 	}
+	if response.Error == "RSS Reader Error" {
+		return http.StatusBadGateway
+	}
+	if response.Error == "Category Not Found" {
+		return http.StatusBadRequest
+	}
 	return http.StatusInternalServerError
 }
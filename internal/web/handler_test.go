@@ -182,6 +182,57 @@ func TestHandleSubmitValidation(t *testing.T) {
 	}
 }
 
+func TestHandleSubmitCreateCategory(t *testing.T) {
+	var created []string
+	reader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/categories" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var body struct {
+				Title string `json:"title"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body.Title)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 9, "title": "` + body.Title + `"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id": 7, "title": "Link Blogs"}]`))
+	}))
+	defer reader.Close()
+
+	server := NewServer(config.Config{RSSReaderEndpoint: reader.URL, RSSReaderAPIKey: "test-key"}, false)
+	token, _ := GenerateCSRFToken()
+
+	// A missing category is refused with close matches and a hint
+	formData := url.Values{"url": {"http://192.168.0.1"}, "category": {"Link Blog"}}
+	w := httptest.NewRecorder()
+	server.handleSubmit(w, newCSRFRequest("POST", "/submit", formData.Encode(), token))
+	var response SubmitResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || response.Error != "Category Not Found" || !strings.Contains(response.Message, `did you mean "Link Blogs"?`) {
+		t.Errorf("Expected a category not found response suggesting Link Blogs, got %d %+v", w.Code, response)
+	}
+	if len(created) != 0 {
+		t.Errorf("Expected no category to be created, got %v", created)
+	}
+
+	// With create_category it is created before the URL is processed
+	formData.Set("create_category", "true")
+	w = httptest.NewRecorder()
+	server.handleSubmit(w, newCSRFRequest("POST", "/submit", formData.Encode(), token))
+	if len(created) != 1 || created[0] != "Link Blog" {
+		t.Errorf("Expected Link Blog to be created, got %v", created)
+	}
+	if server.config.CreateCategory {
+		t.Error("Expected the server configuration to be left unchanged")
+	}
+}
+
 func TestHandleSubmitSingleURLMode(t *testing.T) {
 	conf := config.Config{
 		RSSReaderEndpoint: "https://test.example.com",
@@ -245,6 +296,10 @@ func TestHandleSubmitSingleURLMode(t *testing.T) {
 					t.Errorf("Expected traversal mode message, got: %s", response.Message)
 				}
 			}
+
+			if len(response.Feeds) != 2 || response.Feeds[0].Info == nil || response.Feeds[0].Info.Title != "Test Blog" {
+				t.Errorf("Expected feeds with metadata in response, got: %+v", response.Feeds)
+			}
 		})
 	}
 }
@@ -295,6 +350,9 @@ func TestValidateURL(t *testing.T) {
 		{"https://", true, "Missing host"},
 		{"example.com", true, "Missing protocol"},
 		{"https://example.com/path?query=value", false, "URL with path and query"},
+		{"@alice@mastodon.example", false, "Fediverse handle"},
+		{"@alice@localhost", true, "Handle without a domain"},
+		{"alice@example.com", true, "E-mail address"},
 	}
 
 	for _, tc := range testCases {
@@ -611,6 +669,8 @@ func TestGetStatusCode(t *testing.T) {
 	}{
 		{SubmitResponse{Success: true}, http.StatusOK},
 		{SubmitResponse{Success: false}, http.StatusInternalServerError},
+		{SubmitResponse{Success: false, Error: "RSS Reader Error"}, http.StatusBadGateway},
+		{SubmitResponse{Success: false, Error: "Category Not Found"}, http.StatusBadRequest},
 	}
 
 	for _, tc := range testCases {
@@ -75,6 +75,23 @@ func (rl *RateLimiter) IsAllowed(ip string) bool {
 	return true
 }
 
+// Exceeded reports whether the given IP has used up its requests for the
+// current window, without counting this check as a request
+func (rl *RateLimiter) Exceeded(ip string) bool {
+	now := time.Now()
+
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+
+	count := 0
+	for _, reqTime := range rl.requests[ip] {
+		if now.Sub(reqTime) < rl.window {
+			count++
+		}
+	}
+	return count >= rl.limit
+}
+
 // cleanupOldRequests periodically removes old request records
 func (rl *RateLimiter) cleanupOldRequests() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
 	"github.com/toozej/RSSFFS/pkg/config"
 	"github.com/toozej/RSSFFS/pkg/version"
 )
@@ -22,6 +23,12 @@ type Server struct {
 	server      *http.Server
 	rateLimiter *RateLimiter
 	logHook     *WebUIHook
+
+	scrapeRules    *RSSFFS.ScrapeRuleStore
+	generatedFeeds *generatedFeedCache
+	scrape         func(RSSFFS.ScrapeRule) (*RSSFFS.ScrapedFeed, error)
+
+	discovery *RSSFFS.DiscoveryQueue
 }
 
 // NewServer creates a new Server instance with the provided configuration
@@ -32,11 +39,22 @@ func NewServer(conf config.Config, debug bool) *Server {
 	// Add the hook to logrus
 	log.AddHook(logHook)
 
+	// Load scraping rules for generated feeds, carrying on without them if the file is unreadable
+	scrapeRules, err := RSSFFS.NewScrapeRuleStore(conf.ScrapeRulesFile)
+	if err != nil {
+		log.Errorf("Error loading scrape rules from %s: %v", conf.ScrapeRulesFile, err)
+		scrapeRules, _ = RSSFFS.NewScrapeRuleStore("")
+	}
+
 	return &Server{
-		config:      conf,
-		debug:       debug,
-		rateLimiter: NewRateLimiter(10, time.Minute), // 10 requests per minute
-		logHook:     logHook,
+		config:         conf,
+		debug:          debug,
+		rateLimiter:    NewRateLimiter(10, time.Minute), // 10 requests per minute
+		logHook:        logHook,
+		scrapeRules:    scrapeRules,
+		generatedFeeds: newGeneratedFeedCache(conf.ScrapeCacheTTL),
+		scrape:         RSSFFS.Scrape,
+		discovery:      RSSFFS.NewDiscoveryQueue(conf),
 	}
 }
 
@@ -51,6 +69,11 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 	mux.HandleFunc("/logs", s.withMiddleware(s.handleLogs))
 	mux.HandleFunc("/logs/stream", s.withMiddleware(s.handleLogsSSE))
 	mux.HandleFunc("/static/", s.withMiddleware(s.handleStatic))
+	mux.HandleFunc("/generated/", s.withMiddleware(s.handleGenerated))
+	mux.HandleFunc("/generated/preview", s.withMiddleware(s.handleScrapePreview))
+	mux.HandleFunc("/generated/rules", s.withMiddleware(s.handleScrapeRules))
+	mux.HandleFunc("/webhooks/miniflux", s.withMiddleware(s.handleMinifluxWebhook))
+	mux.HandleFunc("/webhooks/review", s.withMiddleware(s.handleReview))
 
 	// Direct routes for common assets (for backward compatibility and convenience)
 	mux.HandleFunc("/style.css", s.withMiddleware(s.handleDirectAsset))
@@ -69,8 +92,10 @@ func (s *Server) withMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			log.Debugf("Request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
 		}
 
-		// Rate limiting (only for POST requests to prevent abuse)
-		if r.Method == "POST" {
+		// Rate limiting (only for POST requests to prevent abuse). Webhook
+		// requests arrive as often as the reader fetches feeds, so only those
+		// failing their signature check count, in handleMinifluxWebhook.
+		if r.Method == "POST" && r.URL.Path != "/webhooks/miniflux" {
 			clientIP := getClientIP(r)
 			if !s.rateLimiter.IsAllowed(clientIP) {
 				log.Warnf("Rate limit exceeded for IP: %s", clientIP)
@@ -84,7 +109,7 @@ func (s *Server) withMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 		// CORS headers for local development (restrict in production)
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
 
 		// Handle preflight requests
@@ -251,6 +276,11 @@ func (s *Server) Start(host string, port int) error {
 
 	log.Infof("Starting web server on http://%s", addr)
 
+	// Look for feeds on sites linked from webhook entries in the background
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.discovery.Run(ctx)
+
 	// Start server in a goroutine
 	go func() {
 		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -0,0 +1,201 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+)
+
+// minifluxSignatureHeader carries the hex HMAC-SHA256 of a Miniflux webhook
+// request body, keyed with the shared webhook secret
+const minifluxSignatureHeader = "X-Miniflux-Signature"
+
+// minifluxWebhookEvent is the body of a Miniflux webhook request. new_entries
+// events list the entries just fetched for one feed, and save_entry events
+// carry the single entry saved.
+type minifluxWebhookEvent struct {
+	EventType string         `json:"event_type"`
+	Feed      RSSFFS.Feed    `json:"feed"`
+	Entries   []RSSFFS.Entry `json:"entries"`
+	Entry     *RSSFFS.Entry  `json:"entry"`
+}
+
+// WebhookResponse represents the JSON response to a webhook request
+type WebhookResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Queued  int    `json:"queued"`
+}
+
+// ReviewResponse represents the JSON response for the discovery review queue
+type ReviewResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Feeds   []RSSFFS.Result `json:"feeds"`
+}
+
+// handleMinifluxWebhook receives Miniflux webhook events, checking their
+// signature against the shared secret, and queues the sites linked from new
+// entries for discovery. It is disabled unless a webhook secret is configured.
+// Requests failing the signature check count against the client's rate limit,
+// and clients that exceeded it are refused before their request is read.
+func (s *Server) handleMinifluxWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.config.WebhookSecret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientIP := getClientIP(r)
+	if s.rateLimiter.Exceeded(clientIP) {
+		log.Warnf("Rate limit exceeded for IP: %s", clientIP)
+		http.Error(w, "Rate limit exceeded. Please try again later.", http.StatusTooManyRequests)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10*1024*1024)) // 10MB limit
+	if err != nil {
+		http.Error(w, "Request too large or malformed", http.StatusBadRequest)
+		return
+	}
+	if !validWebhookSignature(body, r.Header.Get(minifluxSignatureHeader), s.config.WebhookSecret) {
+		log.Warnf("Invalid webhook signature from IP: %s", clientIP)
+		s.rateLimiter.IsAllowed(clientIP)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event minifluxWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+
+	var entries []RSSFFS.Entry
+	switch event.EventType {
+	case "new_entries":
+		for _, entry := range event.Entries {
+			if entry.Feed.FeedURL == "" && entry.Feed.SiteURL == "" {
+				entry.Feed = event.Feed
+			}
+			entries = append(entries, entry)
+		}
+	case "save_entry":
+		if event.Entry != nil {
+			entries = append(entries, *event.Entry)
+		}
+	default:
+		log.Debugf("Ignoring webhook event %q", event.EventType)
+	}
+
+	queued := 0
+	if len(entries) > 0 {
+		queued = s.discovery.AddEntries(entries)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(WebhookResponse{
+		Success: true,
+		Message: fmt.Sprintf("Read %d entries, queued %d sites", len(entries), queued),
+		Queued:  queued,
+	}); err != nil {
+		log.Errorf("Error encoding webhook response: %v", err)
+	}
+}
+
+// validWebhookSignature reports whether signature is the hex HMAC-SHA256 of
+// body keyed with secret
+func validWebhookSignature(body []byte, signature string, secret string) bool {
+	expected, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// handleReview lists the feeds found from webhook entries that await review
+// (GET), and subscribes to (action=subscribe) or dismisses (action=dismiss)
+// one of them by url (POST). Subscribing uses the given category, falling
+// back to the one a rule chose for the feed.
+func (s *Server) handleReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		s.sendReviewResponse(w, http.StatusOK, ReviewResponse{Success: true, Feeds: s.discovery.Review()})
+
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, 1024*1024) // 1MB limit
+		if err := r.ParseForm(); err != nil {
+			s.sendErrorResponse(w, "Invalid form data", "Request too large or malformed", http.StatusBadRequest)
+			return
+		}
+		if !s.checkCSRF(w, r) {
+			return
+		}
+
+		feedURL := strings.TrimSpace(r.FormValue("url"))
+		var result RSSFFS.Result
+		found := false
+		for _, queued := range s.discovery.Review() {
+			if queued.URL == feedURL {
+				result, found = queued, true
+				break
+			}
+		}
+		if !found {
+			s.sendReviewResponse(w, http.StatusNotFound, ReviewResponse{Error: "Not Found", Message: "Feed is not awaiting review"})
+			return
+		}
+
+		switch r.FormValue("action") {
+		case "subscribe":
+			category := s.sanitizeInput(r.FormValue("category"))
+			if err := s.validateCategory(category); err != nil {
+				s.sendValidationErrorResponse(w, ValidationErrors{Errors: []ValidationError{{Field: "category", Message: err.Error()}}})
+				return
+			}
+			if category == "" {
+				category = result.Category
+			}
+			if err := s.subscribeFeed(feedURL, category); err != nil {
+				log.Errorf("Error subscribing to reviewed feed %s: %v", feedURL, err)
+				s.sendReviewResponse(w, http.StatusBadGateway, ReviewResponse{Error: "Subscription Error", Message: err.Error()})
+				return
+			}
+			s.discovery.Take(feedURL)
+			s.sendReviewResponse(w, http.StatusOK, ReviewResponse{Success: true, Message: "Subscribed to " + feedURL})
+
+		case "dismiss":
+			s.discovery.Take(feedURL)
+			s.sendReviewResponse(w, http.StatusOK, ReviewResponse{Success: true, Message: "Dismissed " + feedURL})
+
+		default:
+			s.sendValidationErrorResponse(w, ValidationErrors{Errors: []ValidationError{{Field: "action", Message: "action must be subscribe or dismiss"}}})
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// sendReviewResponse writes a review queue JSON response with the given status code
+func (s *Server) sendReviewResponse(w http.ResponseWriter, statusCode int, response ReviewResponse) {
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Errorf("Error encoding review response: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toozej/RSSFFS/internal/RSSFFS"
+	"github.com/toozej/RSSFFS/pkg/config"
+)
+
+// signWebhook returns the Miniflux signature of body for secret
+func signWebhook(body string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// TestHandleMinifluxWebhook tests that only signed webhook requests queue sites
+func TestHandleMinifluxWebhook(t *testing.T) {
+	server := NewServer(config.Config{
+		RSSReaderEndpoint: "https://test.example.com",
+		RSSReaderAPIKey:   "test-key",
+		WebhookSecret:     "s3cret",
+		MinMentions:       1,
+	}, false)
+	mux := server.SetupRoutes()
+
+	body := `{"event_type": "new_entries", "feed": {"feed_url": "https://links.example.com/feed.xml", "site_url": "https://links.example.com/"},
+		"entries": [{"url": "https://links.example.com/1", "content": "<a href=\"https://bob.example.net/\">Bob</a>"}]}`
+	post := func(signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/miniflux", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if signature != "" {
+			req.Header.Set("X-Miniflux-Signature", signature)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, signature := range []string{"", "not-hex", signWebhook(body, "wrong")} {
+		if w := post(signature); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for signature %q, got %d", signature, w.Code)
+		}
+	}
+
+	w := post(signWebhook(body, "s3cret"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response WebhookResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if !response.Success || response.Queued != 1 {
+		t.Errorf("Expected bob.example.net queued, got %+v", response)
+	}
+
+	// Signed webhook requests aren't rate limited like form submissions
+	for i := 0; i < 15; i++ {
+		if w := post(signWebhook(body, "s3cret")); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for repeated webhook request, got %d", w.Code)
+		}
+	}
+
+	// Failed signatures are, and once over the limit requests are refused unread
+	server.rateLimiter = NewRateLimiter(2, time.Minute)
+	for i, expected := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if w := post(signWebhook(body, "wrong")); w.Code != expected {
+			t.Errorf("Expected status %d for invalid request %d, got %d", expected, i, w.Code)
+		}
+	}
+	if w := post(signWebhook(body, "s3cret")); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for a client over the limit, got %d", w.Code)
+	}
+}
+
+// TestHandleMinifluxWebhookDisabled tests that the webhook is off without a secret
+func TestHandleMinifluxWebhookDisabled(t *testing.T) {
+	server := NewServer(config.Config{RSSReaderEndpoint: "https://test.example.com"}, false)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/miniflux", strings.NewReader(`{}`))
+	req.Header.Set("X-Miniflux-Signature", signWebhook(`{}`, ""))
+	w := httptest.NewRecorder()
+	server.SetupRoutes().ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+// TestHandleReview tests listing, subscribing to and dismissing feeds awaiting review
+func TestHandleReview(t *testing.T) {
+	server := NewServer(config.Config{
+		RSSReaderEndpoint: "https://test.example.com",
+		RSSReaderAPIKey:   "test-key",
+		WebhookSecret:     "s3cret",
+	}, false)
+	mux := server.SetupRoutes()
+
+	for _, feedURL := range []string{"https://bob.example.net/feed.xml", "https://carol.example.org/feed.xml"} {
+		server.discovery.AddReview(RSSFFS.Result{Candidate: RSSFFS.Candidate{URL: feedURL, Source: "linked from 2 new entries"}})
+	}
+
+	review := func(req *http.Request) ReviewResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var response ReviewResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return response
+	}
+
+	if response := review(httptest.NewRequest(http.MethodGet, "/webhooks/review", nil)); len(response.Feeds) != 2 {
+		t.Fatalf("Expected 2 feeds awaiting review, got %+v", response.Feeds)
+	}
+
+	form := url.Values{"url": {"https://bob.example.net/feed.xml"}, "action": {"subscribe"}, "category": {"Found"}}.Encode()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, newCSRFRequest(http.MethodPost, "/webhooks/review", form, ""))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without CSRF token, got %d", w.Code)
+	}
+
+	if response := review(newCSRFRequest(http.MethodPost, "/webhooks/review", form, "token")); !response.Success {
+		t.Errorf("Expected subscription to succeed, got %+v", response)
+	}
+	form = url.Values{"url": {"https://carol.example.org/feed.xml"}, "action": {"dismiss"}}.Encode()
+	if response := review(newCSRFRequest(http.MethodPost, "/webhooks/review", form, "token")); !response.Success {
+		t.Errorf("Expected dismissal to succeed, got %+v", response)
+	}
+	if response := review(newCSRFRequest(http.MethodPost, "/webhooks/review", form, "token")); response.Success {
+		t.Error("Expected dismissing a feed no longer awaiting review to fail")
+	}
+
+	if response := review(httptest.NewRequest(http.MethodGet, "/webhooks/review", nil)); len(response.Feeds) != 0 {
+		t.Errorf("Expected the review queue to be empty, got %+v", response.Feeds)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
@@ -47,6 +48,24 @@ import (
 //   - WebHost: The host address for the web server (default: 127.0.0.1)
 //   - WebPort: The port number for the web server (default: 8080)
 //   - SingleURLMode: Enable single URL mode for RSS discovery (default: false)
+//   - ForgeFeed: Which code forge repository feed to subscribe to (default: releases)
+//   - HNRSSBaseURL: hnrss-compatible service for Hacker News feeds (default: https://hnrss.org)
+//   - ITunesLookupBaseURL: iTunes-lookup-compatible service for podcast feeds (default: https://itunes.apple.com)
+//   - PodcastCategory: Category podcast feeds are subscribed to (default: the run's category)
+//   - Bridges: RSS-Bridge and RSSHub instances to generate feeds with, in order of preference
+//   - MaxAge: Skip feeds whose newest item is older than this, e.g. 365d
+//   - MinItems: Skip feeds with fewer items than this
+//   - Languages: Only subscribe to feeds in these languages, preferring matching hreflang alternates
+//   - ScrapeRulesFile: File the web server keeps scraping rules for generated feeds in
+//   - ScrapeCacheTTL: How long generated feeds are cached before scraping again (default: 15m)
+//   - PublicURL: Base URL the RSS reader reaches the web server at, for generated feed links
+//   - MinMentions: Fewest feed items that must link to a site for it to be checked when traversing a feed
+//   - WebhookSecret: Shared secret verifying the RSS reader's webhook requests to the web server
+//   - WebhookCategory: Category feeds found from webhook entries are subscribed to, instead of kept for review
+//   - RulesFile: YAML file of rules that accept, reject, categorise and rename discovered feeds
+//   - JournalFile: File runs record the feeds they subscribed to in, for undoing them
+//   - CreateCategory: Create missing categories instead of failing
+//   - BackupDir: Directory categories are backed up to before their feeds are cleared
 //
 // Example:
 //
@@ -81,6 +100,124 @@ type Config struct {
 	// It is loaded from the RSSFFS_SINGLE_URL_MODE environment variable.
 	// If not specified, defaults to false (traversal mode).
 	SingleURLMode bool `env:"RSSFFS_SINGLE_URL_MODE" envDefault:"false"`
+
+	// ForgeFeed specifies which feed to subscribe to for code forge repository
+	// URLs (GitHub, GitLab, Gitea, Forgejo, Codeberg and sourcehut).
+	// Valid values are "releases", "tags" and "commits".
+	// It is loaded from the RSSFFS_FORGE_FEED environment variable.
+	// If not specified, defaults to "releases".
+	ForgeFeed string `env:"RSSFFS_FORGE_FEED" envDefault:"releases"`
+
+	// HNRSSBaseURL specifies the base URL of the hnrss-compatible service that
+	// Hacker News user and listing pages are mapped to.
+	// It is loaded from the RSSFFS_HNRSS_BASE_URL environment variable.
+	// If not specified, defaults to "https://hnrss.org".
+	HNRSSBaseURL string `env:"RSSFFS_HNRSS_BASE_URL" envDefault:"https://hnrss.org"`
+
+	// ITunesLookupBaseURL specifies the base URL of the iTunes-lookup-compatible
+	// service used to find the feeds of podcasts linked from Apple Podcasts and
+	// other podcast directories. It can point at a local stand-in.
+	// It is loaded from the RSSFFS_ITUNES_LOOKUP_BASE_URL environment variable.
+	// If not specified, defaults to "https://itunes.apple.com".
+	ITunesLookupBaseURL string `env:"RSSFFS_ITUNES_LOOKUP_BASE_URL" envDefault:"https://itunes.apple.com"`
+
+	// PodcastCategory specifies the RSS reader category that podcast feeds,
+	// recognised by their use of the itunes namespace, are subscribed to.
+	// It is loaded from the RSSFFS_PODCAST_CATEGORY environment variable.
+	// If not specified, podcasts go to the same category as other feeds.
+	PodcastCategory string `env:"RSSFFS_PODCAST_CATEGORY"`
+
+	// Bridges lists self-hosted RSS-Bridge and RSSHub instances that are asked
+	// to generate a feed when a site publishes none, in order of preference.
+	// Each entry has the form "kind=baseURL" with an optional ";token=secret",
+	// where kind is "rss-bridge" or "rsshub".
+	// It is loaded from the comma-separated RSSFFS_BRIDGES environment variable.
+	// If not specified, no bridges are used.
+	Bridges []string `env:"RSSFFS_BRIDGES" envSeparator:","`
+
+	// MaxAge specifies how recently a feed must have posted to be subscribed
+	// to, as a duration such as "8760h" or in days, weeks or years, e.g. "365d".
+	// It is loaded from the RSSFFS_MAX_AGE environment variable.
+	// If not specified, feeds are subscribed to however old their newest item is.
+	MaxAge string `env:"RSSFFS_MAX_AGE"`
+
+	// MinItems specifies the fewest items a feed must have to be subscribed to.
+	// It is loaded from the RSSFFS_MIN_ITEMS environment variable.
+	// If not specified, feeds are subscribed to however few items they have.
+	MinItems int `env:"RSSFFS_MIN_ITEMS"`
+
+	// Languages lists the language tags, e.g. "en" or "pt-BR", that feeds must
+	// be in to be subscribed to, judged by the feed's language or the hreflang
+	// of the link to it. Sites offering feeds in several languages have the
+	// first matching one preferred. Feeds of unknown language are kept.
+	// It is loaded from the comma-separated RSSFFS_LANGUAGES environment variable.
+	// If not specified, feeds in any language are subscribed to.
+	Languages []string `env:"RSSFFS_LANGUAGES" envSeparator:","`
+
+	// ScrapeRulesFile specifies the JSON file the web server keeps the CSS
+	// selector rules for generated feeds in.
+	// It is loaded from the RSSFFS_SCRAPE_RULES_FILE environment variable.
+	// If not specified, rules are kept in memory and lost on restart.
+	ScrapeRulesFile string `env:"RSSFFS_SCRAPE_RULES_FILE"`
+
+	// ScrapeCacheTTL specifies how long a generated feed is cached, and may be
+	// cached by the RSS reader, before its page is scraped again.
+	// It is loaded from the RSSFFS_SCRAPE_CACHE_TTL environment variable.
+	// If not specified, defaults to 15 minutes.
+	ScrapeCacheTTL time.Duration `env:"RSSFFS_SCRAPE_CACHE_TTL" envDefault:"15m"`
+
+	// PublicURL specifies the base URL the RSS reader reaches the web server
+	// at, used when subscribing to generated feeds.
+	// It is loaded from the RSSFFS_PUBLIC_URL environment variable.
+	// If not specified, the address the web UI was requested at is used.
+	PublicURL string `env:"RSSFFS_PUBLIC_URL"`
+
+	// MinMentions specifies how many items of a feed given as the input URL
+	// must link to a site for that site's feeds to be looked for. Harvesting
+	// and webhook entries use it too, as a number of entries.
+	// It is loaded from the RSSFFS_MIN_MENTIONS environment variable.
+	// If not specified, every site linked at least once is checked, or
+	// twice for harvested and webhook entries.
+	MinMentions int `env:"RSSFFS_MIN_MENTIONS"`
+
+	// WebhookSecret specifies the secret shared with Miniflux that its webhook
+	// requests to /webhooks/miniflux are signed with.
+	// It is loaded from the RSSFFS_WEBHOOK_SECRET environment variable.
+	// If not specified, the webhook endpoint is disabled.
+	WebhookSecret string `env:"RSSFFS_WEBHOOK_SECRET"`
+
+	// WebhookCategory specifies the RSS reader category that feeds found on
+	// sites linked from webhook entries are subscribed to.
+	// It is loaded from the RSSFFS_WEBHOOK_CATEGORY environment variable.
+	// If not specified, the feeds are kept in the web UI's review queue.
+	WebhookCategory string `env:"RSSFFS_WEBHOOK_CATEGORY"`
+
+	// RulesFile specifies a YAML file of rules evaluated against every
+	// discovered feed, which can accept or reject it, choose its category and
+	// set its title. Feeds no rule gives a category go to the run's category.
+	// It is loaded from the RSSFFS_RULES_FILE environment variable.
+	// If not specified, no rules are applied.
+	RulesFile string `env:"RSSFFS_RULES_FILE"`
+
+	// JournalFile specifies the JSON file every run records the feeds it
+	// subscribed to in, so that "RSSFFS undo" can unsubscribe from them.
+	// It is loaded from the RSSFFS_JOURNAL_FILE environment variable.
+	// If not specified, defaults to RSSFFS/journal.json in the user's
+	// configuration directory (e.g. ~/.config on Linux).
+	JournalFile string `env:"RSSFFS_JOURNAL_FILE"`
+
+	// CreateCategory specifies whether categories feeds are subscribed to
+	// in are created when they don't exist yet.
+	// It is loaded from the RSSFFS_CREATE_CATEGORY environment variable.
+	// If not specified, a missing category is an error naming similar ones.
+	CreateCategory bool `env:"RSSFFS_CREATE_CATEGORY" envDefault:"false"`
+
+	// BackupDir specifies the directory the feeds in a category are backed
+	// up to, as a timestamped OPML file, before clearing them with -r.
+	// It is loaded from the RSSFFS_BACKUP_DIR environment variable.
+	// If not specified, defaults to RSSFFS/backups in the user's
+	// configuration directory (e.g. ~/.config on Linux).
+	BackupDir string `env:"RSSFFS_BACKUP_DIR"`
 }
 
 // GetEnvVars loads and returns the application configuration from environment
@@ -175,5 +312,15 @@ func GetEnvVars() Config {
 		os.Exit(1)
 	}
 
+	// Keep the run journal and backups in the user's configuration directory by default
+	if dir, err := os.UserConfigDir(); err == nil {
+		if conf.JournalFile == "" {
+			conf.JournalFile = filepath.Join(dir, "RSSFFS", "journal.json")
+		}
+		if conf.BackupDir == "" {
+			conf.BackupDir = filepath.Join(dir, "RSSFFS", "backups")
+		}
+	}
+
 	return conf
 }